@@ -23,16 +23,18 @@ superjson.registerCustom<Buffer, string>(
 	'Buffer'
 )
 
-export interface Options {
+export interface Options<R = unknown> {
 	ttl?: number | (() => number)
 	client?: Redis
 	keyPrefix?: string
+	/** Called with results fetched from the wrapped function, not with cached ones. */
+	onMiss?: (result: R) => void | Promise<void>
 }
 
 type CachedFactory = {
 	<TArgs extends any[], R>(
 		fn: (...args: TArgs) => R | Promise<R>,
-		opt?: Options
+		opt?: Options<R>
 	): (...args: TArgs) => Promise<R>
 
 	key: (args: unknown | unknown[], name?: string, prefix?: string) => string
@@ -56,7 +58,7 @@ function getKey(args: unknown | unknown[], name = 'fn', prefix?: string): string
 const factory: CachedFactory = Object.assign(
 	function cached<T extends any[], R>(
 		fn: (...args: T) => R | Promise<R>,
-		opt?: Options
+		opt?: Options<R>
 	): (...args: T) => Promise<R> {
 		const client: Redis = opt?.client ?? defaultRedis
 
@@ -73,6 +75,7 @@ const factory: CachedFactory = Object.assign(
 
 			const result = await fn(...args)
 			await client.setex(key, ttl, superjson.stringify(result))
+			await opt?.onMiss?.(result)
 			return result
 		}
 	},
@@ -0,0 +1,65 @@
+import { orm } from '~/lib/mikro-orm/index.ts'
+
+/**
+ * Personal data stores recorded in the inventory. They must match the store
+ * names the reporter worker uses.
+ */
+export const PersonalDataStore = {
+	JiraUsers: 'jira_users_cache'
+} as const
+
+export type PersonalDataStore = (typeof PersonalDataStore)[keyof typeof PersonalDataStore]
+
+export interface RegisterPersonalDataInput {
+	provider: string
+	store: PersonalDataStore
+	accountIds: string[]
+	retrievedAt?: Date
+}
+
+// Account IDs are bound as a JSON array, since Knex expands array bindings
+// into value lists.
+const registerPersonalDataQuery = `
+INSERT INTO personal_data_inventory AS inv (
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at
+)
+SELECT DISTINCT
+	?,
+	account_id,
+	ARRAY[?::text],
+	?::timestamptz
+FROM
+	jsonb_array_elements_text(?::jsonb) AS account_id
+ON CONFLICT (provider, account_id) DO UPDATE SET
+	stores = ARRAY(SELECT DISTINCT s FROM unnest(inv.stores || EXCLUDED.stores) AS s ORDER BY s),
+	data_retrieved_at = GREATEST(inv.data_retrieved_at, EXCLUDED.data_retrieved_at),
+	updated_at = now()`
+
+/**
+ * Record that a store holds personal data for the given accounts, so the
+ * reporter worker includes them in the personal data reports.
+ * Uses best-effort strategy: logs to console on DB failure.
+ */
+export async function registerPersonalData(input: RegisterPersonalDataInput): Promise<void> {
+	if (input.accountIds.length === 0) {
+		return
+	}
+
+	try {
+		await orm.em
+			.fork()
+			.getConnection()
+			.execute(registerPersonalDataQuery, [
+				input.provider,
+				input.store,
+				(input.retrievedAt ?? new Date()).toISOString(),
+				JSON.stringify(input.accountIds)
+			])
+	} catch (error) {
+		// biome-ignore lint/suspicious/noConsole: Required for inventory fallback
+		console.error(`[PersonalData] Failed to register ${input.store} accounts:`, error)
+	}
+}
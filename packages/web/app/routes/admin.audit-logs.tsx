@@ -27,6 +27,7 @@ import { auditActions, getAuditLogger, withAuditContext } from '~/lib/audit/inde
 import { requireAdmin } from '~/lib/auth/index.ts'
 import { cached } from '~/lib/cached/index.ts'
 import { AuditLog, orm } from '~/lib/mikro-orm/index.ts'
+import { PersonalDataStore, registerPersonalData } from '~/lib/personal-data/index.ts'
 
 /**
  * Convert AuditLog entity to AuditLogEntry interface for the frontend.
@@ -629,10 +630,15 @@ export const loader = withAuditContext(async function loader({
 			auth.client.getAccessibleResources.bind(auth.client),
 			cacheOpts
 		)
-		const getUsersByAccountIds = cached(
-			auth.client.getUsersByAccountIds.bind(auth.client),
-			cacheOpts
-		)
+		const getUsersByAccountIds = cached(auth.client.getUsersByAccountIds.bind(auth.client), {
+			...cacheOpts,
+			onMiss: users =>
+				registerPersonalData({
+					provider: 'atlassian',
+					store: PersonalDataStore.JiraUsers,
+					accountIds: users.map(user => user.accountId)
+				})
+		})
 
 		// First, add the current user to actors
 		const currentUser = await getMe()
@@ -6,6 +6,7 @@ import { z } from 'zod'
 import { auditActions, getAuditLogger, withAuditContext } from '~/lib/audit/index.ts'
 import { requireAuthOrRespond } from '~/lib/auth/index.ts'
 import { cached } from '~/lib/cached/index.ts'
+import { PersonalDataStore, registerPersonalData } from '~/lib/personal-data/index.ts'
 
 const schema = {
 	loader: {
@@ -48,7 +49,15 @@ export const loader = withAuditContext(async function loader({ request }: Route.
 	const getProjects = cached(auth.client.getProjects.bind(auth.client), cacheOpts)
 	const getUsersForProjectsPaginated = cached(
 		auth.client.getUsersForProjectsPaginated.bind(auth.client),
-		cacheOpts
+		{
+			...cacheOpts,
+			onMiss: users =>
+				registerPersonalData({
+					provider: 'atlassian',
+					store: PersonalDataStore.JiraUsers,
+					accountIds: users.map(user => user.accountId)
+				})
+		}
 	)
 
 	const accessibleResources = await getAccessibleResources()
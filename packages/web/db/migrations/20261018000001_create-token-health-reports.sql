@@ -0,0 +1,19 @@
+-- migrate:up
+CREATE TABLE token_health_reports (
+	id              text    PRIMARY KEY DEFAULT gen_random_uuid()::text,
+	run_id          text    NOT NULL,
+
+	total           integer NOT NULL,
+	healthy         integer NOT NULL,
+	expiring_soon   integer NOT NULL,
+	expired         integer NOT NULL,
+	scopes_drifted  integer NOT NULL,
+	profile_deleted integer NOT NULL,
+
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_token_health_reports_created_at ON token_health_reports(created_at DESC);
+
+-- migrate:down
+DROP TABLE token_health_reports;
@@ -0,0 +1,28 @@
+-- migrate:up
+CREATE TYPE token_event_type AS ENUM ('refreshed', 'rotated', 'revoked', 'deleted', 'refresh-failed');
+
+CREATE TYPE token_event_actor AS ENUM ('worker', 'web');
+
+-- Append-only history of token mutations. Secret values are never stored here.
+CREATE TABLE token_events (
+	id             text PRIMARY KEY DEFAULT gen_random_uuid()::text,
+	profile_id     text NOT NULL,
+	provider       text NOT NULL,
+	event_type     token_event_type  NOT NULL,
+	actor          token_event_actor NOT NULL,
+
+	old_expires_at timestamptz,
+	new_expires_at timestamptz,
+	scopes_added   text[] NOT NULL DEFAULT '{}',
+	scopes_removed text[] NOT NULL DEFAULT '{}',
+	error_class    text,
+
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_token_events_profile ON token_events(profile_id, provider, created_at DESC);
+
+-- migrate:down
+DROP TABLE token_events;
+DROP TYPE token_event_actor;
+DROP TYPE token_event_type;
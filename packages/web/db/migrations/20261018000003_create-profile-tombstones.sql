@@ -0,0 +1,17 @@
+-- migrate:up
+-- Hashes of hard-purged profiles, kept so that re-creation of a purged
+-- profile can be detected without retaining its identifier.
+CREATE TABLE profile_tombstones (
+	provider   text        NOT NULL,
+	id_hash    text        NOT NULL,
+	deleted_at timestamptz NOT NULL,
+	purged_at  timestamptz NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (provider, id_hash)
+);
+
+CREATE INDEX idx_profiles_deleted_at ON profiles(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- migrate:down
+DROP INDEX idx_profiles_deleted_at;
+DROP TABLE profile_tombstones;
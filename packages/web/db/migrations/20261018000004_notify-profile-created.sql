@@ -0,0 +1,21 @@
+-- migrate:up
+-- Lets the reporter worker report new profiles without waiting for the next
+-- scheduled cycle. The payload carries identifiers only.
+CREATE FUNCTION notify_profile_created() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify(
+		'profile_created',
+		json_build_object('id', NEW.id, 'provider', NEW.provider)::text
+	);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER profiles_notify_created
+	AFTER INSERT ON profiles
+	FOR EACH ROW
+	EXECUTE FUNCTION notify_profile_created();
+
+-- migrate:down
+DROP TRIGGER profiles_notify_created ON profiles;
+DROP FUNCTION notify_profile_created();
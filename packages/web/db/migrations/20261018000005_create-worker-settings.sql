@@ -0,0 +1,11 @@
+-- migrate:up
+-- Settings owned by the reporter worker that must survive restarts, such as
+-- the reporting cycle period requested by Atlassian.
+CREATE TABLE worker_settings (
+	key        text        PRIMARY KEY,
+	value      text        NOT NULL,
+	changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- migrate:down
+DROP TABLE worker_settings;
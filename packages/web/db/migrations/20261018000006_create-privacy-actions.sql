@@ -0,0 +1,42 @@
+-- migrate:up
+CREATE TYPE privacy_action_type AS ENUM ('erased', 'refreshed', 'deferred');
+
+-- Append-only ledger of the actions taken on account statuses received from
+-- Atlassian, kept for auditors beyond Temporal history retention.
+CREATE TABLE privacy_actions (
+	id           text PRIMARY KEY DEFAULT gen_random_uuid()::text,
+	account_id   text NOT NULL,
+	provider     text NOT NULL,
+	status       text NOT NULL,
+	received_at  timestamptz NOT NULL,
+	workflow_id  text NOT NULL,
+	run_id       text NOT NULL,
+
+	action       privacy_action_type NOT NULL,
+	completed_at timestamptz,
+	items        jsonb NOT NULL DEFAULT '{}',
+	error        text,
+
+	created_at timestamptz NOT NULL DEFAULT now(),
+
+	UNIQUE (run_id, provider, account_id, status)
+);
+
+CREATE INDEX idx_privacy_actions_account ON privacy_actions(provider, account_id, received_at DESC);
+CREATE INDEX idx_privacy_actions_received_at ON privacy_actions(received_at);
+
+CREATE FUNCTION prevent_privacy_action_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+	RAISE EXCEPTION 'Privacy actions are immutable - UPDATE and DELETE are not allowed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER privacy_action_immutable
+	BEFORE UPDATE OR DELETE ON privacy_actions
+	FOR EACH ROW EXECUTE FUNCTION prevent_privacy_action_modification();
+
+-- migrate:down
+DROP TABLE privacy_actions;
+DROP FUNCTION prevent_privacy_action_modification();
+DROP TYPE privacy_action_type;
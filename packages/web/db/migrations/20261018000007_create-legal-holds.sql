@@ -0,0 +1,24 @@
+-- migrate:up
+-- Holds that keep a closed account's data from being erased, e.g. during a
+-- dispute. An erasure requested while held is marked as deferred and runs
+-- once the hold is released or expires.
+CREATE TABLE legal_holds (
+	id          text PRIMARY KEY DEFAULT gen_random_uuid()::text,
+	account_id  text        NOT NULL,
+	provider    text        NOT NULL,
+	reason      text        NOT NULL,
+	placed_by   text,
+	placed_at   timestamptz NOT NULL DEFAULT now(),
+	expires_at  timestamptz,
+	released_at timestamptz,
+
+	erasure_deferred_at timestamptz,
+	erasure_resolved_at timestamptz
+);
+
+CREATE UNIQUE INDEX idx_legal_holds_unreleased ON legal_holds(provider, account_id) WHERE released_at IS NULL;
+CREATE INDEX idx_legal_holds_pending_erasure ON legal_holds(erasure_deferred_at)
+	WHERE erasure_deferred_at IS NOT NULL AND erasure_resolved_at IS NULL;
+
+-- migrate:down
+DROP TABLE legal_holds;
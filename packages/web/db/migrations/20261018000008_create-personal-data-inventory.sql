@@ -0,0 +1,84 @@
+-- migrate:up
+-- Every account whose personal data the app stores, with the stores that hold
+-- it. Atlassian requires each of these accounts to be reported, not only the
+-- ones with a profile, so the reporter worker reads this table.
+--
+-- Profiles register themselves through the trigger below; other stores (e.g.
+-- cached Jira users) are registered by whichever side writes them.
+CREATE TABLE personal_data_inventory (
+	provider          text        NOT NULL,
+	account_id        text        NOT NULL,
+	stores            text[]      NOT NULL DEFAULT '{}',
+	-- Latest time data for the account was retrieved, across all stores.
+	data_retrieved_at timestamptz NOT NULL,
+	reported_at       timestamptz,
+
+	created_at timestamptz NOT NULL DEFAULT now(),
+	updated_at timestamptz NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (provider, account_id)
+);
+
+CREATE INDEX idx_personal_data_inventory_reporting ON personal_data_inventory(provider, data_retrieved_at DESC, account_id);
+
+CREATE FUNCTION sync_profile_personal_data() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP <> 'DELETE' AND NEW.deleted_at IS NULL THEN
+		INSERT INTO personal_data_inventory AS inv (
+			provider,
+			account_id,
+			stores,
+			data_retrieved_at,
+			reported_at
+		) VALUES (
+			NEW.provider, NEW.id, ARRAY['profiles'], NEW.updated_at, NEW.reported_at
+		)
+		ON CONFLICT (provider, account_id) DO UPDATE SET
+			stores = ARRAY(SELECT DISTINCT s FROM unnest(inv.stores || EXCLUDED.stores) AS s ORDER BY s),
+			data_retrieved_at = GREATEST(inv.data_retrieved_at, EXCLUDED.data_retrieved_at),
+			updated_at = now();
+	ELSIF TG_OP <> 'INSERT' THEN
+		UPDATE personal_data_inventory SET
+			stores = array_remove(stores, 'profiles'),
+			updated_at = now()
+		WHERE
+			provider = OLD.provider
+			AND account_id = OLD.id;
+
+		DELETE FROM personal_data_inventory
+		WHERE
+			provider = OLD.provider
+			AND account_id = OLD.id
+			AND stores = '{}';
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER profiles_sync_personal_data
+	AFTER INSERT OR DELETE OR UPDATE OF updated_at, deleted_at ON profiles
+	FOR EACH ROW
+	EXECUTE FUNCTION sync_profile_personal_data();
+
+INSERT INTO personal_data_inventory (
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at,
+	reported_at
+)
+SELECT
+	provider,
+	id,
+	ARRAY['profiles'],
+	updated_at,
+	reported_at
+FROM
+	profiles
+WHERE
+	deleted_at IS NULL;
+
+-- migrate:down
+DROP TRIGGER profiles_sync_personal_data ON profiles;
+DROP FUNCTION sync_profile_personal_data();
+DROP TABLE personal_data_inventory;
@@ -0,0 +1,16 @@
+-- migrate:up
+-- Keyed hashes of accounts erased after Atlassian reported them closed. The
+-- web app and reporter worker check them before persisting personal data so
+-- that a closed account's data does not flow back in, e.g. from a cached Jira
+-- user list. Hashes are HMAC-SHA256 of "provider:account_id" under the
+-- ACCOUNT_TOMBSTONE_KEY secret shared by both.
+CREATE TABLE account_tombstones (
+	provider  text        NOT NULL,
+	id_hash   text        NOT NULL,
+	closed_at timestamptz NOT NULL,
+
+	PRIMARY KEY (provider, id_hash)
+);
+
+-- migrate:down
+DROP TABLE account_tombstones;
@@ -0,0 +1,60 @@
+-- migrate:up
+-- Records token_events for token mutations made outside the reporter worker,
+-- e.g. the web app rotating a token on sign-in or refresh. The reporter worker
+-- connects with application_name 'hourly-reporter' and records its own events,
+-- which carry details a trigger cannot see (error class, revocations), so its
+-- mutations are skipped here. Every other connection is recorded as 'web'.
+-- Secret values are never copied.
+CREATE FUNCTION record_token_event() RETURNS trigger AS $$
+BEGIN
+	IF current_setting('application_name', true) = 'hourly-reporter' THEN
+		RETURN NULL;
+	END IF;
+
+	IF TG_OP = 'DELETE' THEN
+		INSERT INTO token_events (
+			profile_id,
+			provider,
+			event_type,
+			actor,
+			old_expires_at,
+			scopes_removed
+		) VALUES (
+			OLD.profile_id, OLD.provider, 'deleted', 'web', OLD.expires_at, OLD.scopes
+		);
+	ELSE
+		INSERT INTO token_events (
+			profile_id,
+			provider,
+			event_type,
+			actor,
+			old_expires_at,
+			new_expires_at,
+			scopes_added,
+			scopes_removed
+		) VALUES (
+			NEW.profile_id,
+			NEW.provider,
+			CASE
+				WHEN OLD.refresh_token IS DISTINCT FROM NEW.refresh_token THEN 'rotated'
+				ELSE 'refreshed'
+			END::token_event_type,
+			'web',
+			OLD.expires_at,
+			NEW.expires_at,
+			ARRAY(SELECT s FROM unnest(NEW.scopes) AS s WHERE s <> ALL(OLD.scopes)),
+			ARRAY(SELECT s FROM unnest(OLD.scopes) AS s WHERE s <> ALL(NEW.scopes))
+		);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tokens_record_event
+	AFTER UPDATE OF access_token, refresh_token, expires_at, scopes OR DELETE ON tokens
+	FOR EACH ROW
+	EXECUTE FUNCTION record_token_event();
+
+-- migrate:down
+DROP TRIGGER tokens_record_event ON tokens;
+DROP FUNCTION record_token_event();
@@ -16,6 +16,14 @@ const (
 	defaultOAuthTimeout = 15 * time.Second
 )
 
+// RevocationUnsupported explains why an Atlassian grant cannot be revoked
+// when an account is erased: OAuth 2.0 (3LO) offers no token revocation
+// endpoint. Deleting the stored tokens is all the app can do; the grant
+// itself lasts until the user removes the app from their connected apps or
+// the refresh token expires unused.
+const RevocationUnsupported = "Atlassian OAuth 2.0 (3LO) has no token revocation endpoint; " +
+	"the grant lasts until the user removes the app from their connected apps or its refresh token expires unused"
+
 // RefreshAccessTokenInput contains parameters required to refresh an access token.
 type RefreshAccessTokenInput struct {
 	ClientID     string
@@ -33,6 +41,30 @@ type RefreshAccessTokenOutput struct {
 	Scopes       []string
 }
 
+// RefreshTokenError is returned when the token endpoint rejects a refresh.
+type RefreshTokenError struct {
+	StatusCode int
+	// Code is the OAuth error code (e.g. "invalid_grant"), when provided.
+	Code    string
+	Message string
+}
+
+func (e *RefreshTokenError) Error() string {
+	return fmt.Sprintf("refresh token failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// ErrorClass returns the OAuth error code, or the HTTP status when none was provided.
+func (e *RefreshTokenError) ErrorClass() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return fmt.Sprintf("http_%d", e.StatusCode)
+}
+
+type refreshErrorResponse struct {
+	Error string `json:"error"`
+}
+
 type refreshAccessTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -95,8 +127,14 @@ func RefreshAccessToken(ctx context.Context, input *RefreshAccessTokenInput) (*R
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		message := strings.TrimSpace(string(data))
-		return nil, fmt.Errorf("refresh token failed with status %d: %s", resp.StatusCode, message)
+		var parsed refreshErrorResponse
+		_ = json.Unmarshal(data, &parsed)
+
+		return nil, &RefreshTokenError{
+			StatusCode: resp.StatusCode,
+			Code:       parsed.Error,
+			Message:    strings.TrimSpace(string(data)),
+		}
 	}
 
 	var parsed refreshAccessTokenResponse
@@ -0,0 +1,225 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultRefreshSkew     = 5 * time.Minute
+	defaultExchangeTimeout = 20 * time.Second
+)
+
+var (
+	// ErrTokenNotFound is returned when no token is stored for the profile.
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrNotRefreshable is returned when the stored token has no refresh token.
+	ErrNotRefreshable = errors.New("token has no refresh token")
+	// ErrUnsupportedProvider is returned when no refresher is configured for the provider.
+	ErrUnsupportedProvider = errors.New("token refresh not supported for provider")
+)
+
+// RefreshResult contains the values returned by a provider's token endpoint.
+// Empty RefreshToken or Scopes keep the stored values.
+type RefreshResult struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    *time.Time
+	Scopes       []string
+}
+
+// RefreshFunc exchanges the stored refresh token for new token values.
+type RefreshFunc func(ctx context.Context, token *store.Token) (*RefreshResult, error)
+
+// Broker hands out valid access tokens and is the single place where refresh
+// exchanges happen. Each exchange runs under the token store's lock on the
+// token, so refreshes for the same profile and provider are serialized across
+// worker replicas as well as within one.
+type Broker struct {
+	tokens          store.TokenStore
+	refreshers      map[string]RefreshFunc
+	refreshSkew     time.Duration
+	exchangeTimeout time.Duration
+}
+
+// Options configures the broker.
+type Options struct {
+	// Tokens is the token store backing the broker.
+	Tokens store.TokenStore
+	// Refreshers perform the refresh exchange, keyed by provider.
+	Refreshers map[string]RefreshFunc
+	// RefreshSkew refreshes tokens this long before they expire (default: 5m).
+	RefreshSkew time.Duration
+	// ExchangeTimeout bounds each call to a refresher, which runs while the
+	// token is locked (default: 20s).
+	ExchangeTimeout time.Duration
+}
+
+// New creates a token broker.
+func New(opts Options) (*Broker, error) {
+	if opts.Tokens == nil {
+		return nil, fmt.Errorf("token store is required")
+	}
+
+	skew := opts.RefreshSkew
+	if skew <= 0 {
+		skew = defaultRefreshSkew
+	}
+
+	exchangeTimeout := opts.ExchangeTimeout
+	if exchangeTimeout <= 0 {
+		exchangeTimeout = defaultExchangeTimeout
+	}
+
+	return &Broker{
+		tokens:          opts.Tokens,
+		refreshers:      opts.Refreshers,
+		refreshSkew:     skew,
+		exchangeTimeout: exchangeTimeout,
+	}, nil
+}
+
+// AccessToken returns a currently valid token, refreshing it first when it
+// expires within the refresh skew.
+func (b *Broker) AccessToken(ctx context.Context, profileID, provider string) (*store.Token, error) {
+	token, err := b.getToken(ctx, profileID, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.needsRefresh(token) {
+		return token, nil
+	}
+
+	// Another caller may have refreshed while we waited for the lock.
+	return b.refresh(ctx, profileID, provider, b.needsRefresh)
+}
+
+// Refresh unconditionally exchanges the stored refresh token for a new access token.
+func (b *Broker) Refresh(ctx context.Context, profileID, provider string) (*store.Token, error) {
+	return b.refresh(ctx, profileID, provider, nil)
+}
+
+func (b *Broker) getToken(ctx context.Context, profileID, provider string) (*store.Token, error) {
+	token, err := b.tokens.GetToken(ctx, &store.GetTokenInput{
+		ProfileID: profileID,
+		Provider:  provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil || token.AccessToken == "" {
+		return nil, ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (b *Broker) needsRefresh(token *store.Token) bool {
+	return token.ExpiresAt != nil && token.ExpiresAt.Before(time.Now().UTC().Add(b.refreshSkew))
+}
+
+// refresh locks the stored token and exchanges it, unless needed reports that
+// the locked token no longer needs a refresh. A failed exchange is recorded
+// once the lock is released, since the store may need another connection.
+func (b *Broker) refresh(ctx context.Context, profileID, provider string, needed func(*store.Token) bool) (*store.Token, error) {
+	var failed *store.TokenEvent
+	token, err := b.tokens.RefreshToken(ctx, &store.RefreshTokenInput{
+		ProfileID: profileID,
+		Provider:  provider,
+		Exchange: func(ctx context.Context, token *store.Token) (*store.UpdateTokenInput, error) {
+			if token.AccessToken == "" {
+				return nil, ErrTokenNotFound
+			}
+			if needed != nil && !needed(token) {
+				return nil, nil
+			}
+			update, err := b.exchange(ctx, token)
+			if err != nil && !errors.Is(err, ErrUnsupportedProvider) && !errors.Is(err, ErrNotRefreshable) {
+				failed = &store.TokenEvent{
+					ProfileID:    token.ProfileID,
+					Provider:     token.Provider,
+					Type:         store.TokenEventRefreshFailed,
+					Actor:        store.TokenActorWorker,
+					OldExpiresAt: token.ExpiresAt,
+					ErrorClass:   ErrorClass(err),
+				}
+			}
+			return update, err
+		},
+	})
+	if failed != nil {
+		if recordErr := b.tokens.RecordTokenEvent(ctx, failed); recordErr != nil {
+			err = errors.Join(err, fmt.Errorf("record token event: %w", recordErr))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil {
+		return nil, ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+// exchange calls the provider's refresher and returns the values to store.
+func (b *Broker) exchange(ctx context.Context, token *store.Token) (*store.UpdateTokenInput, error) {
+	refresher, ok := b.refreshers[token.Provider]
+	if !ok || refresher == nil {
+		return nil, ErrUnsupportedProvider
+	}
+
+	if token.RefreshToken == "" {
+		return nil, ErrNotRefreshable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.exchangeTimeout)
+	defer cancel()
+
+	result, err := refresher(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := &store.UpdateTokenInput{
+		ProfileID:    token.ProfileID,
+		Provider:     token.Provider,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		ExpiresAt:    result.ExpiresAt,
+		Scopes:       result.Scopes,
+		Actor:        store.TokenActorWorker,
+	}
+
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+
+	if len(refreshed.Scopes) == 0 {
+		refreshed.Scopes = token.Scopes
+	}
+
+	return refreshed, nil
+}
+
+// ErrorClass maps a refresh failure to a coarse class suitable for the token
+// event log. Errors may implement ErrorClass() string to provide their own class.
+func ErrorClass(err error) string {
+	var classified interface{ ErrorClass() string }
+	if errors.As(err, &classified) {
+		return classified.ErrorClass()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return "timeout"
+	}
+
+	return "request_failed"
+}
@@ -0,0 +1,229 @@
+package broker
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const unixSocketPrefix = "unix://"
+
+// Server exposes the broker over an internal HTTP/JSON API.
+//
+//	GET  /v1/tokens/{provider}/{profileId}          returns a valid access token
+//	POST /v1/tokens/{provider}/{profileId}/refresh  forces a refresh
+//
+// Requests must carry "Authorization: Bearer <secret>".
+type Server struct {
+	broker   *Broker
+	address  string
+	secret   string
+	server   *http.Server
+	listener net.Listener
+}
+
+// ServerOptions configures the broker API server.
+type ServerOptions struct {
+	// Address is a loopback TCP address (e.g. "127.0.0.1:8089") or a unix
+	// socket path prefixed with "unix://". The socket's directory is created
+	// with mode 0700 when missing and must not be accessible to other users.
+	Address string
+	// Secret is the shared secret clients present as a bearer token.
+	Secret string
+}
+
+// TokenResponse is the JSON body returned for a token.
+type TokenResponse struct {
+	ProfileID   string     `json:"profileId"`
+	Provider    string     `json:"provider"`
+	AccessToken string     `json:"accessToken"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Scopes      []string   `json:"scopes,omitempty"`
+}
+
+// ErrorResponse is the JSON body returned on failure.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer creates a broker API server. Only loopback TCP addresses and unix
+// sockets are accepted.
+func NewServer(broker *Broker, opts ServerOptions) (*Server, error) {
+	if broker == nil {
+		return nil, fmt.Errorf("broker is required")
+	}
+
+	if opts.Secret == "" {
+		return nil, fmt.Errorf("broker secret is required")
+	}
+
+	if !strings.HasPrefix(opts.Address, unixSocketPrefix) {
+		host, _, err := net.SplitHostPort(opts.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker address %q: %w", opts.Address, err)
+		}
+		if host != "localhost" {
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsLoopback() {
+				return nil, fmt.Errorf("broker address %q is not a loopback address", opts.Address)
+			}
+		}
+	}
+
+	s := &Server{
+		broker:  broker,
+		address: opts.Address,
+		secret:  opts.Secret,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/tokens/{provider}/{profileId}", s.handleGetToken)
+	mux.HandleFunc("POST /v1/tokens/{provider}/{profileId}/refresh", s.handleRefreshToken)
+
+	s.server = &http.Server{
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	return s, nil
+}
+
+// Start begins serving in the background.
+func (s *Server) Start() error {
+	var (
+		listener net.Listener
+		err      error
+	)
+
+	if path, ok := strings.CutPrefix(s.address, unixSocketPrefix); ok {
+		// The socket is reachable before it can be chmodded, so it is only
+		// created in a directory other users cannot enter.
+		if err := privateDir(filepath.Dir(path)); err != nil {
+			return err
+		}
+		// Remove a stale socket left behind by a previous run.
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove stale socket: %w", err)
+		}
+		listener, err = net.Listen("unix", path)
+		if err == nil {
+			err = os.Chmod(path, 0o600)
+		}
+	} else {
+		listener, err = net.Listen("tcp", s.address)
+	}
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.address, err)
+	}
+
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Token broker server stopped:", err)
+		}
+	}()
+
+	return nil
+}
+
+// privateDir creates dir with mode 0700 if it does not exist and checks that
+// an existing one is not accessible to group or others.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create socket directory: %w", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("stat socket directory: %w", err)
+	}
+
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("socket directory %s has mode %#o; it must not be accessible to group or others", dir, perm)
+	}
+
+	return nil
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(s.secret)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleGetToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.broker.AccessToken(r.Context(), r.PathValue("profileId"), r.PathValue("provider"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TokenResponse{
+		ProfileID:   token.ProfileID,
+		Provider:    token.Provider,
+		AccessToken: token.AccessToken,
+		ExpiresAt:   token.ExpiresAt,
+		Scopes:      token.Scopes,
+	})
+}
+
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.broker.Refresh(r.Context(), r.PathValue("profileId"), r.PathValue("provider"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TokenResponse{
+		ProfileID:   token.ProfileID,
+		Provider:    token.Provider,
+		AccessToken: token.AccessToken,
+		ExpiresAt:   token.ExpiresAt,
+		Scopes:      token.Scopes,
+	})
+}
+
+// writeError maps err to a response. Upstream and store failures may carry
+// provider response bodies, so they are logged rather than returned.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNotRefreshable), errors.Is(err, ErrUnsupportedProvider):
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		log.Println("Token broker request failed:", err)
+		writeJSON(w, http.StatusBadGateway, ErrorResponse{Error: "token request failed"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
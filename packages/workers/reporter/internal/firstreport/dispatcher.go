@@ -0,0 +1,171 @@
+// Package firstreport starts reporting runs for newly connected profiles so
+// they do not wait for the next scheduled privacy compliance cycle.
+package firstreport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/temporal/workflows"
+)
+
+const (
+	defaultDebounce = 30 * time.Second
+	defaultMaxDelay = 5 * time.Minute
+	defaultMaxBatch = 1000
+)
+
+// Options configures a Dispatcher.
+type Options struct {
+	Client    client.Client
+	TaskQueue string
+	// Provider selects which profiles are reported (default: atlassian).
+	Provider string
+	// Debounce is how long to wait after the last event before starting a run (default: 30s).
+	Debounce time.Duration
+	// MaxDelay bounds how long the first event of a batch may wait (default: 5m).
+	MaxDelay time.Duration
+	// MaxBatch starts a run as soon as this many accounts are pending (default: 1000).
+	MaxBatch int
+}
+
+// Dispatcher batches profile_created events and starts a PrivacyCompliance
+// run restricted to the new account IDs.
+type Dispatcher struct {
+	client    client.Client
+	taskQueue string
+	provider  string
+	debounce  time.Duration
+	maxDelay  time.Duration
+	maxBatch  int
+}
+
+func New(opts Options) (*Dispatcher, error) {
+	if opts.Client == nil {
+		return nil, errors.New("temporal client is required")
+	}
+	if opts.TaskQueue == "" {
+		return nil, errors.New("task queue is required")
+	}
+
+	d := &Dispatcher{
+		client:    opts.Client,
+		taskQueue: opts.TaskQueue,
+		provider:  opts.Provider,
+		debounce:  opts.Debounce,
+		maxDelay:  opts.MaxDelay,
+		maxBatch:  opts.MaxBatch,
+	}
+
+	if d.provider == "" {
+		d.provider = store.ProviderAtlassian
+	}
+	if d.debounce <= 0 {
+		d.debounce = defaultDebounce
+	}
+	if d.maxDelay <= 0 {
+		d.maxDelay = defaultMaxDelay
+	}
+	if d.maxBatch <= 0 {
+		d.maxBatch = defaultMaxBatch
+	}
+
+	return d, nil
+}
+
+// Run consumes events until the channel is closed or ctx is done. Pending
+// accounts are flushed when the channel closes.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan store.ProfileCreated) {
+	pending := map[string]struct{}{}
+	var firstAt time.Time
+
+	timer := time.NewTimer(d.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(pending) == 0 {
+			return
+		}
+
+		ids := make([]string, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		clear(pending)
+		firstAt = time.Time{}
+
+		d.start(ctx, ids)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				flush()
+				return
+			}
+			if event.Provider != d.provider || event.ID == "" {
+				continue
+			}
+
+			pending[event.ID] = struct{}{}
+			if firstAt.IsZero() {
+				firstAt = time.Now()
+			}
+
+			if len(pending) >= d.maxBatch || time.Since(firstAt) >= d.maxDelay {
+				flush()
+				continue
+			}
+
+			timer.Reset(min(d.debounce, d.maxDelay-time.Since(firstAt)))
+
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// start launches a run for ids. The workflow id is derived from the sorted
+// ids, so a replayed batch is rejected by Temporal instead of reported twice.
+func (d *Dispatcher) start(ctx context.Context, ids []string) {
+	slices.Sort(ids)
+
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\n")))
+	workflowID := "privacy-compliance-new-" + hex.EncodeToString(sum[:8])
+
+	_, err := d.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:                    workflowID,
+		TaskQueue:             d.taskQueue,
+		WorkflowIDReusePolicy: enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	}, workflows.PrivacyCompliance, workflows.PrivacyComplianceInput{
+		AccountIDs: ids,
+	})
+
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	switch {
+	case err == nil:
+		log.Println("Started first report for new accounts", workflowID, len(ids))
+	case errors.As(err, &alreadyStarted):
+		log.Println("First report already started for new accounts", workflowID)
+	default:
+		// The scheduled cycle still picks these accounts up.
+		log.Println("Unable to start first report for new accounts", workflowID, err)
+	}
+}
@@ -0,0 +1,238 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the GitLab instance used when no base URL is configured.
+	DefaultBaseURL = "https://gitlab.com"
+
+	tokenPath           = "/oauth/token"
+	revokeTokenPath     = "/oauth/revoke"
+	defaultOAuthTimeout = 15 * time.Second
+)
+
+// Token type hints accepted by the revocation endpoint (RFC 7009).
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// RefreshAccessTokenInput contains parameters required to refresh an access token.
+type RefreshAccessTokenInput struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	// CallbackURL is the redirect URI the grant was issued for, which GitLab
+	// requires on refresh.
+	CallbackURL string
+	HTTPClient  *http.Client
+}
+
+// RefreshAccessTokenOutput contains refreshed tokens and expiry metadata.
+type RefreshAccessTokenOutput struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    *time.Time
+	Scopes       []string
+}
+
+// RefreshTokenError is returned when the token endpoint rejects a refresh.
+type RefreshTokenError struct {
+	StatusCode int
+	// Code is the OAuth error code (e.g. "invalid_grant"), when provided.
+	Code    string
+	Message string
+}
+
+func (e *RefreshTokenError) Error() string {
+	return fmt.Sprintf("refresh token failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// ErrorClass returns the OAuth error code, or the HTTP status when none was provided.
+func (e *RefreshTokenError) ErrorClass() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return fmt.Sprintf("http_%d", e.StatusCode)
+}
+
+type refreshErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type refreshAccessTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// RefreshAccessToken exchanges a refresh token for a new access token.
+// POST {baseURL}/oauth/token
+//
+// GitLab rotates the refresh token on every exchange.
+func RefreshAccessToken(ctx context.Context, input *RefreshAccessTokenInput) (*RefreshAccessTokenOutput, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	if input.ClientID == "" || input.ClientSecret == "" {
+		return nil, fmt.Errorf("client credentials are required")
+	}
+
+	if input.RefreshToken == "" {
+		return nil, fmt.Errorf("refresh token is required")
+	}
+
+	baseURL := strings.TrimRight(input.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	httpClient := input.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultOAuthTimeout}
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", input.ClientID)
+	form.Set("client_secret", input.ClientSecret)
+	form.Set("refresh_token", input.RefreshToken)
+
+	if input.CallbackURL != "" {
+		form.Set("redirect_uri", input.CallbackURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+tokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("refresh token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var parsed refreshErrorResponse
+		_ = json.Unmarshal(data, &parsed)
+
+		return nil, &RefreshTokenError{
+			StatusCode: resp.StatusCode,
+			Code:       parsed.Error,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	var parsed refreshAccessTokenResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if parsed.AccessToken == "" {
+		return nil, fmt.Errorf("refresh token response missing access_token")
+	}
+
+	var expiresAt *time.Time
+	if parsed.ExpiresIn > 0 {
+		expiry := time.Now().UTC().Add(time.Duration(parsed.ExpiresIn) * time.Second)
+		expiresAt = &expiry
+	}
+
+	return &RefreshAccessTokenOutput{
+		AccessToken:  parsed.AccessToken,
+		RefreshToken: parsed.RefreshToken,
+		ExpiresAt:    expiresAt,
+		Scopes:       strings.Fields(parsed.Scope),
+	}, nil
+}
+
+// RevokeTokenInput contains parameters required to revoke a token.
+type RevokeTokenInput struct {
+	BaseURL       string
+	ClientID      string
+	ClientSecret  string
+	Token         string
+	TokenTypeHint string
+	HTTPClient    *http.Client
+}
+
+// RevokeToken revokes an access or refresh token at the GitLab instance.
+// POST {baseURL}/oauth/revoke
+//
+// GitLab answers 200 for unknown or already revoked tokens, so a nil error means
+// the token is no longer usable.
+func RevokeToken(ctx context.Context, input *RevokeTokenInput) error {
+	if input == nil {
+		return fmt.Errorf("input is required")
+	}
+
+	if input.ClientID == "" || input.ClientSecret == "" {
+		return fmt.Errorf("client credentials are required")
+	}
+
+	if input.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+
+	baseURL := strings.TrimRight(input.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	httpClient := input.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultOAuthTimeout}
+	}
+
+	form := url.Values{}
+	form.Set("client_id", input.ClientID)
+	form.Set("client_secret", input.ClientSecret)
+	form.Set("token", input.Token)
+
+	if input.TokenTypeHint != "" {
+		form.Set("token_type_hint", input.TokenTypeHint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+revokeTokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("revoke token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		message := strings.TrimSpace(string(data))
+		return fmt.Errorf("revoke token failed with status %d: %s", resp.StatusCode, message)
+	}
+
+	return nil
+}
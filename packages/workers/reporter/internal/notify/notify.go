@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// Message is a notification delivered to operators.
+type Message struct {
+	Subject string         `json:"subject"`
+	Text    string         `json:"text"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// Notifier delivers operator notifications.
+type Notifier interface {
+	Notify(ctx context.Context, message *Message) error
+}
+
+// Webhook posts notifications as JSON to an HTTP endpoint.
+type Webhook struct {
+	httpClient *http.Client
+	url        string
+}
+
+// WebhookOptions configures the webhook notifier.
+type WebhookOptions struct {
+	// URL is the endpoint receiving notification payloads.
+	URL string
+	// HTTPClient allows injecting a custom client.
+	HTTPClient *http.Client
+}
+
+// NewWebhook creates a webhook notifier.
+func NewWebhook(opts WebhookOptions) (*Webhook, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	return &Webhook{
+		httpClient: httpClient,
+		url:        opts.URL,
+	}, nil
+}
+
+// Notify posts the message to the webhook.
+func (w *Webhook) Notify(ctx context.Context, message *Message) error {
+	if message == nil {
+		return fmt.Errorf("message is required")
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("webhook failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return nil
+}
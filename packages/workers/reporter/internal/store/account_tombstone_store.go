@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+)
+
+// AccountTombstoneKey identifies a closed account.
+type AccountTombstoneKey struct {
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+}
+
+// FilterTombstonedInput contains the accounts to check for tombstones.
+type FilterTombstonedInput struct {
+	Provider   string   `json:"provider"`
+	AccountIDs []string `json:"accountIds"`
+}
+
+// AccountTombstoneStore reads the tombstones DeleteUserData leaves for closed
+// accounts. Only a keyed hash of the account ID is stored.
+type AccountTombstoneStore interface {
+	// IsTombstoned reports whether the account was closed and erased. The
+	// web app and worker check it before persisting personal data.
+	IsTombstoned(ctx context.Context, input *AccountTombstoneKey) (bool, error)
+
+	// FilterTombstoned returns the given accounts that are tombstoned.
+	FilterTombstoned(ctx context.Context, input *FilterTombstonedInput) ([]string, error)
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+)
+
+// Audit log enum values shared with the web app's audit_logs table.
+const (
+	AuditActionDataModification = "data-modification"
+	AuditActionAdministration   = "administration"
+
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+
+	AuditSeverityInfo    = "info"
+	AuditSeverityWarning = "warning"
+
+	AuditTargetToken   = "token"
+	AuditTargetProfile = "profile"
+)
+
+// AuditLogEntry is an entry in the shared audit log written by the worker.
+type AuditLogEntry struct {
+	ActionType         string         `json:"actionType"`
+	ActionDescription  string         `json:"actionDescription"`
+	Severity           string         `json:"severity,omitempty"`
+	TargetResourceType string         `json:"targetResourceType"`
+	TargetResourceID   string         `json:"targetResourceId,omitempty"`
+	Outcome            string         `json:"outcome"`
+	CorrelationID      string         `json:"correlationId"`
+	RequestID          string         `json:"requestId"`
+	RequestPath        string         `json:"requestPath"`
+	RequestMethod      string         `json:"requestMethod"`
+	Metadata           map[string]any `json:"metadata,omitempty"`
+}
+
+// AuditStore appends entries to the audit log.
+type AuditStore interface {
+	// RecordAuditLog appends an entry. Entries are immutable once written.
+	RecordAuditLog(ctx context.Context, entry *AuditLogEntry) error
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AccountsCursor is the keyset position of the last account on a page of
+// GetAccountsToReport. Pages are ordered by updated_at DESC, id ASC.
+type AccountsCursor struct {
+	UpdatedAt time.Time `json:"u"`
+	AccountID string    `json:"i"`
+}
+
+// Encode returns the opaque form of the cursor handed to callers.
+func (c AccountsCursor) Encode() string {
+	raw, _ := json.Marshal(AccountsCursor{
+		UpdatedAt: c.UpdatedAt.UTC(),
+		AccountID: c.AccountID,
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeAccountsCursor parses a cursor produced by Encode. An empty string
+// yields nil, meaning the first page.
+func DecodeAccountsCursor(cursor string) (*AccountsCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accounts cursor: %w", err)
+	}
+
+	var c AccountsCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("invalid accounts cursor: %w", err)
+	}
+
+	if c.AccountID == "" || c.UpdatedAt.IsZero() {
+		return nil, fmt.Errorf("invalid accounts cursor")
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type AccountTombstoneStore struct {
+	state *state
+}
+
+func (s *Store) AccountTombstones() store.AccountTombstoneStore {
+	return s.tombstones
+}
+
+func (s *AccountTombstoneStore) IsTombstoned(ctx context.Context, input *store.AccountTombstoneKey) (bool, error) {
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return false, fmt.Errorf("account id and provider are required")
+	}
+
+	tombstoned, err := s.FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   input.Provider,
+		AccountIDs: []string{input.AccountID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(tombstoned) > 0, nil
+}
+
+func (s *AccountTombstoneStore) FilterTombstoned(ctx context.Context, input *store.FilterTombstonedInput) ([]string, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	var tombstoned []string
+	for _, id := range input.AccountIDs {
+		key := tombstoneKey{provider: input.Provider, idHash: s.state.tombstoneHasher.Hash(input.Provider, id)}
+		if _, ok := s.state.accountTombstones[key]; ok && !slices.Contains(tombstoned, id) {
+			tombstoned = append(tombstoned, id)
+		}
+	}
+
+	return tombstoned, nil
+}
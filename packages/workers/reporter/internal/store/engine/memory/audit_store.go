@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type AuditStore struct {
+	state *state
+}
+
+func (s *AuditStore) RecordAuditLog(ctx context.Context, entry *store.AuditLogEntry) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if entry == nil {
+		return fmt.Errorf("entry is required")
+	}
+
+	recorded := *entry
+	if recorded.Severity == "" {
+		recorded.Severity = store.AuditSeverityInfo
+	}
+	recorded.Metadata = maps.Clone(entry.Metadata)
+
+	s.state.auditLogs = append(s.state.auditLogs, recorded)
+
+	return nil
+}
+
+// AuditLogs returns a copy of all recorded audit log entries.
+func (s *Store) AuditLogs() []store.AuditLogEntry {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	return append([]store.AuditLogEntry(nil), s.state.auditLogs...)
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type InventoryStore struct {
+	state *state
+}
+
+func (s *Store) Inventory() store.InventoryStore {
+	return s.inventory
+}
+
+func (s *InventoryStore) RegisterPersonalData(ctx context.Context, input *store.RegisterPersonalDataInput) error {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" || input.RetrievedAt.IsZero() {
+		return fmt.Errorf("provider, store, and retrieved at are required")
+	}
+
+	for _, id := range input.AccountIDs {
+		s.state.registerPersonalData(input.Provider, id, input.Store, input.RetrievedAt.UTC(), nil, now)
+	}
+
+	return nil
+}
+
+func (s *InventoryStore) UnregisterPersonalData(ctx context.Context, input *store.UnregisterPersonalDataInput) (*store.UnregisterPersonalDataOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" {
+		return nil, fmt.Errorf("provider and store are required")
+	}
+
+	var output store.UnregisterPersonalDataOutput
+	for _, id := range input.AccountIDs {
+		if s.state.unregisterPersonalData(input.Provider, id, input.Store, now) {
+			output.Removed++
+		}
+	}
+
+	return &output, nil
+}
+
+func (s *InventoryStore) GetPersonalData(ctx context.Context, input *store.PersonalDataKey) (*store.PersonalDataEntry, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	entry, ok := s.state.inventory[ProfileKey{ID: input.AccountID, Provider: input.Provider}]
+	if !ok {
+		return nil, nil
+	}
+
+	cp := *entry
+	cp.Stores = slices.Clone(entry.Stores)
+	cp.ReportedAt = cloneTime(entry.ReportedAt)
+	return &cp, nil
+}
+
+// registerPersonalData adds a store to the account's inventory entry. The
+// reported time only applies to a new entry. Callers must hold the state lock.
+func (st *state) registerPersonalData(provider, accountID, name string, retrievedAt time.Time, reportedAt *time.Time, now time.Time) {
+	key := ProfileKey{ID: accountID, Provider: provider}
+
+	entry, ok := st.inventory[key]
+	if !ok {
+		st.inventory[key] = &store.PersonalDataEntry{
+			AccountID:       accountID,
+			Provider:        provider,
+			Stores:          []string{name},
+			DataRetrievedAt: retrievedAt,
+			ReportedAt:      cloneTime(reportedAt),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		return
+	}
+
+	if !slices.Contains(entry.Stores, name) {
+		entry.Stores = append(entry.Stores, name)
+		slices.Sort(entry.Stores)
+	}
+	if retrievedAt.After(entry.DataRetrievedAt) {
+		entry.DataRetrievedAt = retrievedAt
+	}
+	entry.UpdatedAt = now
+}
+
+// unregisterPersonalData removes a store from the account's inventory entry
+// and reports whether the entry was dropped for having no stores left.
+// Callers must hold the state lock.
+func (st *state) unregisterPersonalData(provider, accountID, name string, now time.Time) bool {
+	key := ProfileKey{ID: accountID, Provider: provider}
+
+	entry, ok := st.inventory[key]
+	if !ok || !slices.Contains(entry.Stores, name) {
+		return false
+	}
+
+	entry.Stores = slices.DeleteFunc(entry.Stores, func(s string) bool { return s == name })
+	entry.UpdatedAt = now
+
+	if len(entry.Stores) == 0 {
+		delete(st.inventory, key)
+		return true
+	}
+	return false
+}
+
+// syncProfilePersonalData mirrors the profiles_sync_personal_data trigger: a
+// live profile is registered, a deleted one is unregistered. Callers must
+// hold the state lock.
+func (st *state) syncProfilePersonalData(p *Profile, now time.Time) {
+	if p.DeletedAt != nil {
+		st.unregisterPersonalData(p.Provider, p.ID, store.PersonalDataProfiles, now)
+		return
+	}
+	st.registerPersonalData(p.Provider, p.ID, store.PersonalDataProfiles, p.UpdatedAt, p.ReportedAt, now)
+}
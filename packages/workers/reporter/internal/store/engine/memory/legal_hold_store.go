@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultPendingErasuresPage = 100
+)
+
+type LegalHoldStore struct {
+	state *state
+}
+
+func (s *Store) LegalHolds() store.LegalHoldStore {
+	return s.legalHolds
+}
+
+func (s *LegalHoldStore) PlaceLegalHold(ctx context.Context, input *store.PlaceLegalHoldInput) (*store.LegalHold, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" || input.Reason == "" {
+		return nil, fmt.Errorf("account id, provider, and reason are required")
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("legal hold expiry must be in the future")
+	}
+
+	hold := s.state.unreleasedHold(input.Provider, input.AccountID)
+	if hold != nil && !hold.Active(now) {
+		// An expired hold is closed off so that the new hold gets its own
+		// row while the expired one keeps any erasure it deferred.
+		hold.ReleasedAt = cloneTime(hold.ExpiresAt)
+		hold = nil
+	}
+
+	if hold == nil {
+		hold = &store.LegalHold{
+			ID:        s.state.nextID(),
+			AccountID: input.AccountID,
+			Provider:  input.Provider,
+			PlacedAt:  now,
+		}
+		s.state.legalHolds = append(s.state.legalHolds, hold)
+	}
+
+	hold.Reason = input.Reason
+	hold.PlacedBy = input.PlacedBy
+	hold.ExpiresAt = cloneTime(input.ExpiresAt)
+
+	return cloneLegalHold(hold), nil
+}
+
+func (s *LegalHoldStore) ReleaseLegalHold(ctx context.Context, input *store.LegalHoldKey) (*store.ReleaseLegalHoldOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	output := &store.ReleaseLegalHoldOutput{}
+
+	if hold := s.state.unreleasedHold(input.Provider, input.AccountID); hold != nil {
+		hold.ReleasedAt = timeRef(now)
+		output.Released = true
+	}
+
+	for _, hold := range s.state.legalHolds {
+		if hold.Provider == input.Provider && hold.AccountID == input.AccountID &&
+			hold.ErasureDeferredAt != nil && hold.ErasureResolvedAt == nil {
+			output.ErasurePending = true
+		}
+	}
+
+	return output, nil
+}
+
+func (s *LegalHoldStore) GetLegalHold(ctx context.Context, input *store.LegalHoldKey) (*store.LegalHold, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	hold := s.state.unreleasedHold(input.Provider, input.AccountID)
+	if hold == nil || !hold.Active(time.Now().UTC()) {
+		return nil, nil
+	}
+
+	return cloneLegalHold(hold), nil
+}
+
+func (s *LegalHoldStore) DeferErasure(ctx context.Context, input *store.LegalHoldKey) (*store.LegalHold, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	return s.state.deferErasure(input.Provider, input.AccountID, time.Now().UTC()), nil
+}
+
+func (s *LegalHoldStore) ListPendingErasures(ctx context.Context, input *store.ListPendingErasuresInput) ([]store.PendingErasure, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	limit := defaultPendingErasuresPage
+	var provider string
+	var after *store.PendingErasure
+	if input != nil {
+		if input.Limit > 0 {
+			limit = input.Limit
+		}
+		provider = input.Provider
+		after = input.After
+	}
+
+	pending := map[ProfileKey]time.Time{}
+	for _, hold := range s.state.legalHolds {
+		if hold.ErasureDeferredAt == nil || hold.ErasureResolvedAt != nil {
+			continue
+		}
+		if provider != "" && hold.Provider != provider {
+			continue
+		}
+		if active := s.state.unreleasedHold(hold.Provider, hold.AccountID); active != nil && active.Active(now) {
+			continue
+		}
+
+		key := ProfileKey{ID: hold.AccountID, Provider: hold.Provider}
+		if deferredAt, ok := pending[key]; !ok || hold.ErasureDeferredAt.Before(deferredAt) {
+			pending[key] = *hold.ErasureDeferredAt
+		}
+	}
+
+	erasures := make([]store.PendingErasure, 0, len(pending))
+	for key, deferredAt := range pending {
+		erasures = append(erasures, store.PendingErasure{
+			AccountID:  key.ID,
+			Provider:   key.Provider,
+			DeferredAt: deferredAt,
+		})
+	}
+
+	slices.SortFunc(erasures, comparePendingErasures)
+
+	if after != nil {
+		erasures = slices.DeleteFunc(erasures, func(e store.PendingErasure) bool {
+			return comparePendingErasures(e, *after) <= 0
+		})
+	}
+
+	if len(erasures) > limit {
+		erasures = erasures[:limit]
+	}
+
+	return erasures, nil
+}
+
+// comparePendingErasures orders erasures as ListPendingErasures returns them.
+func comparePendingErasures(a, b store.PendingErasure) int {
+	return cmp.Or(
+		a.DeferredAt.Compare(b.DeferredAt),
+		cmp.Compare(a.Provider, b.Provider),
+		cmp.Compare(a.AccountID, b.AccountID),
+	)
+}
+
+// unreleasedHold returns the account's unreleased hold, which may have
+// expired. The caller must hold the lock.
+func (st *state) unreleasedHold(provider, accountID string) *store.LegalHold {
+	for _, hold := range st.legalHolds {
+		if hold.Provider == provider && hold.AccountID == accountID && hold.ReleasedAt == nil {
+			return hold
+		}
+	}
+	return nil
+}
+
+// deferErasure marks the account's active hold as deferring an erasure and
+// returns a copy of it, or nil when the account is not held. The caller must
+// hold the write lock.
+func (st *state) deferErasure(provider, accountID string, now time.Time) *store.LegalHold {
+	hold := st.unreleasedHold(provider, accountID)
+	if hold == nil || !hold.Active(now) {
+		return nil
+	}
+
+	if hold.ErasureDeferredAt == nil {
+		hold.ErasureDeferredAt = timeRef(now)
+	}
+
+	return cloneLegalHold(hold)
+}
+
+// resolveDeferredErasures marks the account's deferred erasures as done. The
+// caller must hold the write lock.
+func (st *state) resolveDeferredErasures(provider, accountID string, now time.Time) {
+	for _, hold := range st.legalHolds {
+		if hold.Provider == provider && hold.AccountID == accountID &&
+			hold.ErasureDeferredAt != nil && hold.ErasureResolvedAt == nil {
+			hold.ErasureResolvedAt = timeRef(now)
+		}
+	}
+}
+
+func cloneLegalHold(hold *store.LegalHold) *store.LegalHold {
+	cp := *hold
+	cp.ExpiresAt = cloneTime(hold.ExpiresAt)
+	cp.ReleasedAt = cloneTime(hold.ReleasedAt)
+	cp.ErasureDeferredAt = cloneTime(hold.ErasureDeferredAt)
+	cp.ErasureResolvedAt = cloneTime(hold.ErasureResolvedAt)
+	return &cp
+}
@@ -0,0 +1,279 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// Store is an in-memory implementation of store.Store with the same semantics
+// as the Postgres engine. It is intended for tests and local development.
+type Store struct {
+	state *state
+
+	userData       *UserDataStore
+	tokens         *TokenStore
+	audit          *AuditStore
+	settings       *SettingsStore
+	privacyActions *PrivacyActionStore
+	legalHolds     *LegalHoldStore
+	inventory      *InventoryStore
+	tombstones     *AccountTombstoneStore
+}
+
+// Profile is a row of the profiles table.
+type Profile struct {
+	ID         string
+	Provider   string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReportedAt *time.Time
+	DeletedAt  *time.Time
+}
+
+// Session is a row of the sessions table together with the profiles linked to it.
+type Session struct {
+	ID        string
+	Profiles  []ProfileKey
+	Data      map[string]any
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ProfileKey identifies a profile.
+type ProfileKey struct {
+	ID       string
+	Provider string
+}
+
+// Tombstone is a row of the profile_tombstones table.
+type Tombstone struct {
+	Provider  string
+	IDHash    string
+	DeletedAt time.Time
+	PurgedAt  time.Time
+}
+
+type tombstoneKey struct {
+	provider string
+	idHash   string
+}
+
+type tokenRow struct {
+	store.Token
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type state struct {
+	mu     sync.RWMutex
+	opened bool
+	seq    int
+
+	profiles       map[ProfileKey]*Profile
+	tokens         map[ProfileKey]*tokenRow
+	sessions       map[string]*Session
+	tombstones     map[tombstoneKey]Tombstone
+	settings       map[string]setting
+	tokenEvents    []store.TokenEvent
+	privacyActions []store.PrivacyAction
+	legalHolds     []*store.LegalHold
+	inventory      map[ProfileKey]*store.PersonalDataEntry
+	healthReports  []store.TokenHealthReport
+	auditLogs      []store.AuditLogEntry
+
+	// accountTombstones maps closed account hashes to their closure time.
+	accountTombstones map[tombstoneKey]time.Time
+	tombstoneHasher   *store.TombstoneHasher
+
+	policies store.ProviderPolicies
+}
+
+type Options struct {
+	// Policies sets how each provider's accounts are kept current
+	// (default: store.DefaultProviderPolicies).
+	Policies store.ProviderPolicies
+}
+
+// New returns an empty store. Account tombstones are hashed with a random key,
+// so they are only meaningful to this store.
+func New(opts Options) (*Store, error) {
+	policies := opts.Policies
+	if policies == nil {
+		policies = store.DefaultProviderPolicies()
+	}
+	if err := policies.Validate(); err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, store.MinTombstoneKeyLength)
+	rand.Read(key)
+	hasher, err := store.NewTombstoneHasher(hex.EncodeToString(key))
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{
+		profiles:          map[ProfileKey]*Profile{},
+		tokens:            map[ProfileKey]*tokenRow{},
+		sessions:          map[string]*Session{},
+		tombstones:        map[tombstoneKey]Tombstone{},
+		settings:          map[string]setting{},
+		inventory:         map[ProfileKey]*store.PersonalDataEntry{},
+		accountTombstones: map[tombstoneKey]time.Time{},
+		tombstoneHasher:   hasher,
+		policies:          policies,
+	}
+
+	return &Store{
+		state:          st,
+		userData:       &UserDataStore{state: st},
+		tokens:         &TokenStore{state: st},
+		audit:          &AuditStore{state: st},
+		settings:       &SettingsStore{state: st},
+		privacyActions: &PrivacyActionStore{state: st},
+		legalHolds:     &LegalHoldStore{state: st},
+		inventory:      &InventoryStore{state: st},
+		tombstones:     &AccountTombstoneStore{state: st},
+	}, nil
+}
+
+func (s *Store) Open(ctx context.Context) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.opened = true
+	return nil
+}
+
+func (s *Store) Close(ctx context.Context) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.opened = false
+	return nil
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	return s.state.checkOpened()
+}
+
+func (s *Store) UserData() store.UserDataStore {
+	return s.userData
+}
+
+func (s *Store) Tokens() store.TokenStore {
+	return s.tokens
+}
+
+func (s *Store) Audit() store.AuditStore {
+	return s.audit
+}
+
+// PutProfile inserts or replaces a profile. Zero timestamps default to now.
+func (s *Store) PutProfile(p Profile) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	now := time.Now().UTC()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = now
+	}
+
+	s.state.profiles[ProfileKey{ID: p.ID, Provider: p.Provider}] = &p
+	s.state.syncProfilePersonalData(&p, now)
+}
+
+// PutToken inserts or replaces a token. The profile must exist, mirroring the
+// foreign key on the tokens table.
+func (s *Store) PutToken(token store.Token) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	key := ProfileKey{ID: token.ProfileID, Provider: token.Provider}
+	if _, ok := s.state.profiles[key]; !ok {
+		return fmt.Errorf("profile %s/%s does not exist", token.Provider, token.ProfileID)
+	}
+
+	now := time.Now().UTC()
+	token.Scopes = append([]string(nil), token.Scopes...)
+	s.state.tokens[key] = &tokenRow{Token: token, CreatedAt: now, UpdatedAt: now}
+
+	return nil
+}
+
+// PutSession inserts or replaces a session and its profile links.
+func (s *Store) PutSession(session Session) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	for _, key := range session.Profiles {
+		if _, ok := s.state.profiles[key]; !ok {
+			return fmt.Errorf("profile %s/%s does not exist", key.Provider, key.ID)
+		}
+	}
+
+	now := time.Now().UTC()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	if session.UpdatedAt.IsZero() {
+		session.UpdatedAt = now
+	}
+	session.Profiles = append([]ProfileKey(nil), session.Profiles...)
+
+	s.state.sessions[session.ID] = &session
+
+	return nil
+}
+
+// Session returns a copy of the session with the given id.
+func (s *Store) Session(id string) (Session, bool) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	session, ok := s.state.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+
+	cp := *session
+	cp.Profiles = append([]ProfileKey(nil), session.Profiles...)
+	return cp, true
+}
+
+func (st *state) nextID() string {
+	st.seq++
+	return fmt.Sprintf("%016d", st.seq)
+}
+
+func (st *state) checkOpened() error {
+	if !st.opened {
+		return fmt.Errorf("store not opened")
+	}
+	return nil
+}
+
+func timeRef(t time.Time) *time.Time {
+	return &t
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	value := *t
+	return &value
+}
@@ -0,0 +1,66 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/store/engine/memory"
+	"hourly/workers/reporter/internal/store/storetest"
+)
+
+type fixtures struct {
+	st *memory.Store
+}
+
+func (f fixtures) CreateProfile(t *testing.T, p storetest.Profile) {
+	f.st.PutProfile(memory.Profile{
+		ID:         p.ID,
+		Provider:   p.Provider,
+		UpdatedAt:  p.UpdatedAt,
+		ReportedAt: p.ReportedAt,
+		DeletedAt:  p.DeletedAt,
+	})
+}
+
+func (f fixtures) CreateToken(t *testing.T, token store.Token) {
+	if err := f.st.PutToken(token); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+}
+
+func (f fixtures) CreateSession(t *testing.T, s storetest.Session) {
+	session := memory.Session{ID: s.ID, Data: s.Data, UpdatedAt: s.UpdatedAt}
+	for _, p := range s.Profiles {
+		session.Profiles = append(session.Profiles, memory.ProfileKey{ID: p.ID, Provider: p.Provider})
+	}
+	if err := f.st.PutSession(session); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+}
+
+func (f fixtures) LoadSession(t *testing.T, id string) *storetest.Session {
+	session, ok := f.st.Session(id)
+	if !ok {
+		return nil
+	}
+
+	loaded := &storetest.Session{ID: session.ID, Data: session.Data, UpdatedAt: session.UpdatedAt}
+	for _, k := range session.Profiles {
+		loaded.Profiles = append(loaded.Profiles, storetest.Profile{ID: k.ID, Provider: k.Provider})
+	}
+	return loaded
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, storetest.Fixtures) {
+		st, err := memory.New(memory.Options{Policies: storetest.Policies()})
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		if err := st.Open(context.Background()); err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return st, fixtures{st: st}
+	})
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultPrivacyActionsPage = 500
+)
+
+type PrivacyActionStore struct {
+	state *state
+}
+
+func (s *Store) PrivacyActions() store.PrivacyActionStore {
+	return s.privacyActions
+}
+
+func (s *PrivacyActionStore) RecordPrivacyAction(ctx context.Context, action *store.PrivacyAction) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if action == nil || action.AccountID == "" || action.Provider == "" || action.Status == "" || action.RunID == "" || action.Action == "" {
+		return fmt.Errorf("account id, provider, status, run id, and action are required")
+	}
+
+	// Mirror the unique (run_id, provider, account_id, status) constraint.
+	for _, recorded := range s.state.privacyActions {
+		if recorded.RunID == action.RunID && recorded.Provider == action.Provider &&
+			recorded.AccountID == action.AccountID && recorded.Status == action.Status {
+			return nil
+		}
+	}
+
+	recorded := *action
+	recorded.ID = s.state.nextID()
+	recorded.ReceivedAt = action.ReceivedAt.UTC()
+	recorded.CompletedAt = cloneTime(action.CompletedAt)
+	recorded.Items = maps.Clone(action.Items)
+	if recorded.Items == nil {
+		recorded.Items = map[string]int{}
+	}
+	recorded.CreatedAt = time.Now().UTC()
+
+	s.state.privacyActions = append(s.state.privacyActions, recorded)
+
+	return nil
+}
+
+func (s *PrivacyActionStore) GetAccountPrivacyActions(ctx context.Context, input *store.GetAccountPrivacyActionsInput) ([]store.PrivacyAction, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var actions []store.PrivacyAction
+	for _, action := range s.state.privacyActions {
+		if action.AccountID == input.AccountID && action.Provider == input.Provider {
+			actions = append(actions, clonePrivacyAction(action))
+		}
+	}
+
+	slices.SortFunc(actions, func(a, b store.PrivacyAction) int {
+		return cmp.Or(b.ReceivedAt.Compare(a.ReceivedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	if len(actions) > limit {
+		actions = actions[:limit]
+	}
+
+	return actions, nil
+}
+
+func (s *PrivacyActionStore) ListPrivacyActions(ctx context.Context, input *store.ListPrivacyActionsInput) (*store.ListPrivacyActionsOutput, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.ReceivedFrom.IsZero() || input.ReceivedTo.IsZero() {
+		return nil, fmt.Errorf("received from and received to are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var actions []store.PrivacyAction
+	for _, action := range s.state.privacyActions {
+		if !action.ReceivedAt.Before(input.ReceivedFrom) && action.ReceivedAt.Before(input.ReceivedTo) {
+			actions = append(actions, clonePrivacyAction(action))
+		}
+	}
+
+	slices.SortFunc(actions, func(a, b store.PrivacyAction) int {
+		return cmp.Or(a.ReceivedAt.Compare(b.ReceivedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	offset := min(max(input.Offset, 0), len(actions))
+	actions = actions[offset:]
+
+	hasMore := len(actions) > limit
+	if hasMore {
+		actions = actions[:limit]
+	}
+
+	return &store.ListPrivacyActionsOutput{
+		Actions: slices.Clip(actions),
+		HasMore: hasMore,
+	}, nil
+}
+
+func clonePrivacyAction(action store.PrivacyAction) store.PrivacyAction {
+	action.CompletedAt = cloneTime(action.CompletedAt)
+	action.Items = maps.Clone(action.Items)
+	return action
+}
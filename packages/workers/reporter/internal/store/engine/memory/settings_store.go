@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/store"
+)
+
+type SettingsStore struct {
+	state *state
+}
+
+// setting is a row of the worker_settings table.
+type setting struct {
+	value     string
+	changedAt time.Time
+}
+
+func (s *Store) Settings() store.SettingsStore {
+	return s.settings
+}
+
+func (s *SettingsStore) GetCyclePeriod(ctx context.Context) (*store.CyclePeriod, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	return s.state.cyclePeriod(store.ProviderAtlassian, atlassian.DefaultCyclePeriodDays)
+}
+
+func (s *SettingsStore) SetCyclePeriod(ctx context.Context, input *store.SetCyclePeriodInput) (*store.SetCyclePeriodOutput, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.Days <= 0 {
+		return nil, fmt.Errorf("cycle period days must be positive")
+	}
+
+	previous, err := s.state.cyclePeriod(store.ProviderAtlassian, atlassian.DefaultCyclePeriodDays)
+	if err != nil {
+		return nil, err
+	}
+
+	value := strconv.Itoa(input.Days)
+	output := &store.SetCyclePeriodOutput{PreviousDays: previous.Days}
+
+	if current, ok := s.state.settings[store.SettingCyclePeriodDays]; !ok || current.value != value {
+		s.state.settings[store.SettingCyclePeriodDays] = setting{value: value, changedAt: time.Now().UTC()}
+		output.Changed = true
+	}
+
+	return output, nil
+}
+
+// cyclePeriod reads the provider's stored cycle period, falling back to
+// defaultDays. The caller must hold the lock.
+func (st *state) cyclePeriod(provider string, defaultDays int) (*store.CyclePeriod, error) {
+	row, ok := st.settings[store.CyclePeriodSetting(provider)]
+	if !ok {
+		return &store.CyclePeriod{Days: defaultDays}, nil
+	}
+
+	days, err := strconv.Atoi(row.value)
+	if err != nil || days <= 0 {
+		return nil, fmt.Errorf("invalid stored cycle period %q", row.value)
+	}
+
+	changedAt := row.changedAt
+	return &store.CyclePeriod{Days: days, ChangedAt: &changedAt}, nil
+}
@@ -0,0 +1,493 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type TokenStore struct {
+	state *state
+
+	// refreshMu serializes RefreshToken without holding the state lock while
+	// the exchange runs.
+	refreshMu sync.Mutex
+}
+
+const (
+	defaultTokensPage       = 500
+	defaultTokenHistoryPage = 100
+)
+
+func (s *TokenStore) GetToken(ctx context.Context, input *store.GetTokenInput) (*store.Token, error) {
+	return s.fetchToken(input, false)
+}
+
+func (s *TokenStore) GetRefreshableToken(ctx context.Context, input *store.GetTokenInput) (*store.Token, error) {
+	return s.fetchToken(input, true)
+}
+
+func (s *TokenStore) fetchToken(input *store.GetTokenInput, refreshable bool) (*store.Token, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	key := ProfileKey{ID: input.ProfileID, Provider: input.Provider}
+
+	p, ok := s.state.profiles[key]
+	if !ok || p.DeletedAt != nil {
+		return nil, nil
+	}
+
+	row, ok := s.state.tokens[key]
+	if !ok {
+		return nil, nil
+	}
+
+	if refreshable && row.RefreshToken == "" {
+		return nil, nil
+	}
+
+	return &store.Token{
+		ProfileID:    input.ProfileID,
+		Provider:     input.Provider,
+		AccessToken:  row.AccessToken,
+		RefreshToken: row.RefreshToken,
+		ExpiresAt:    cloneTime(row.ExpiresAt),
+		Scopes:       slices.Clone(row.Scopes),
+	}, nil
+}
+
+func (s *TokenStore) ListLinkedTokens(ctx context.Context, input *store.GetTokenInput) ([]store.Token, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	own := ProfileKey{ID: input.ProfileID, Provider: input.Provider}
+	linked := map[ProfileKey]bool{own: true}
+	for _, session := range s.state.sessions {
+		if slices.Contains(session.Profiles, own) {
+			for _, key := range session.Profiles {
+				linked[key] = true
+			}
+		}
+	}
+
+	tokens := []store.Token{}
+	for key := range linked {
+		p, ok := s.state.profiles[key]
+		if !ok || p.DeletedAt != nil {
+			continue
+		}
+
+		row, ok := s.state.tokens[key]
+		if !ok {
+			continue
+		}
+
+		tokens = append(tokens, store.Token{
+			ProfileID:    key.ID,
+			Provider:     key.Provider,
+			AccessToken:  row.AccessToken,
+			RefreshToken: row.RefreshToken,
+			ExpiresAt:    cloneTime(row.ExpiresAt),
+			Scopes:       slices.Clone(row.Scopes),
+		})
+	}
+
+	slices.SortFunc(tokens, func(a, b store.Token) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.ProfileID, b.ProfileID))
+	})
+
+	return tokens, nil
+}
+
+func (s *TokenStore) UpdateToken(ctx context.Context, input *store.UpdateTokenInput) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if input == nil {
+		return fmt.Errorf("input is required")
+	}
+
+	if input.ProfileID == "" || input.Provider == "" || input.AccessToken == "" {
+		return fmt.Errorf("profile id, provider, and access token are required")
+	}
+
+	row, ok := s.state.tokens[ProfileKey{ID: input.ProfileID, Provider: input.Provider}]
+	if !ok {
+		return fmt.Errorf("token not found for profile %s and provider %s", input.ProfileID, input.Provider)
+	}
+
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	var expires *time.Time
+	if input.ExpiresAt != nil {
+		expires = timeRef(input.ExpiresAt.UTC())
+	}
+
+	eventType := store.TokenEventRefreshed
+	if row.RefreshToken != input.RefreshToken {
+		eventType = store.TokenEventRotated
+	}
+
+	added, removed := store.DiffScopes(row.Scopes, input.Scopes)
+	now := time.Now().UTC()
+
+	s.state.tokenEvents = append(s.state.tokenEvents, store.TokenEvent{
+		ID:            s.state.nextID(),
+		ProfileID:     input.ProfileID,
+		Provider:      input.Provider,
+		Type:          eventType,
+		Actor:         actor,
+		OldExpiresAt:  cloneTime(row.ExpiresAt),
+		NewExpiresAt:  cloneTime(expires),
+		ScopesAdded:   added,
+		ScopesRemoved: removed,
+		CreatedAt:     now,
+	})
+
+	row.AccessToken = input.AccessToken
+	row.RefreshToken = input.RefreshToken
+	row.ExpiresAt = expires
+	row.Scopes = slices.Clone(input.Scopes)
+	row.UpdatedAt = now
+
+	return nil
+}
+
+func (s *TokenStore) RefreshToken(ctx context.Context, input *store.RefreshTokenInput) (*store.Token, error) {
+	if input == nil || input.ProfileID == "" || input.Provider == "" || input.Exchange == nil {
+		return nil, fmt.Errorf("profile id, provider, and exchange are required")
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	token, err := s.GetToken(ctx, &store.GetTokenInput{ProfileID: input.ProfileID, Provider: input.Provider})
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	update, err := input.Exchange(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if update == nil {
+		return token, nil
+	}
+
+	update.ProfileID = input.ProfileID
+	update.Provider = input.Provider
+	if err := s.UpdateToken(ctx, update); err != nil {
+		return nil, err
+	}
+
+	return &store.Token{
+		ProfileID:    update.ProfileID,
+		Provider:     update.Provider,
+		AccessToken:  update.AccessToken,
+		RefreshToken: update.RefreshToken,
+		ExpiresAt:    update.ExpiresAt,
+		Scopes:       update.Scopes,
+	}, nil
+}
+
+func (s *TokenStore) RecordTokenEvent(ctx context.Context, event *store.TokenEvent) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if event == nil || event.ProfileID == "" || event.Provider == "" || event.Type == "" {
+		return fmt.Errorf("profile id, provider, and event type are required")
+	}
+
+	recorded := *event
+	recorded.ID = s.state.nextID()
+	recorded.CreatedAt = time.Now().UTC()
+	recorded.OldExpiresAt = cloneTime(event.OldExpiresAt)
+	recorded.NewExpiresAt = cloneTime(event.NewExpiresAt)
+	recorded.ScopesAdded = slices.Clone(event.ScopesAdded)
+	recorded.ScopesRemoved = slices.Clone(event.ScopesRemoved)
+
+	if recorded.Actor == "" {
+		recorded.Actor = store.TokenActorWorker
+	}
+
+	s.state.tokenEvents = append(s.state.tokenEvents, recorded)
+
+	return nil
+}
+
+func (s *TokenStore) GetTokenHistory(ctx context.Context, input *store.GetTokenHistoryInput) ([]store.TokenEvent, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	limit := defaultTokenHistoryPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var events []store.TokenEvent
+	for _, event := range s.state.tokenEvents {
+		if event.ProfileID == input.ProfileID && event.Provider == input.Provider {
+			events = append(events, event)
+		}
+	}
+
+	// Events are appended in order, so newest first is the reverse.
+	slices.Reverse(events)
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+func (s *TokenStore) ListTokens(ctx context.Context, input *store.ListTokensInput) (*store.ListTokensOutput, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	limit := defaultTokensPage
+	var after *store.TokenCursor
+
+	if input != nil {
+		if input.Limit > 0 {
+			limit = input.Limit
+		}
+		after = input.After
+	}
+
+	rows := make([]*tokenRow, 0, len(s.state.tokens))
+	for _, row := range s.state.tokens {
+		if after != nil && cmp.Or(cmp.Compare(row.Provider, after.Provider), cmp.Compare(row.ProfileID, after.ProfileID)) <= 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	slices.SortFunc(rows, func(a, b *tokenRow) int {
+		if c := cmp.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProfileID, b.ProfileID)
+	})
+
+	end := min(limit, len(rows))
+
+	tokens := make([]store.TokenInfo, 0, end)
+	for _, row := range rows[:end] {
+		info := store.TokenInfo{
+			ProfileID:       row.ProfileID,
+			Provider:        row.Provider,
+			HasRefreshToken: row.RefreshToken != "",
+			ExpiresAt:       cloneTime(row.ExpiresAt),
+			Scopes:          slices.Clone(row.Scopes),
+			UpdatedAt:       row.UpdatedAt,
+		}
+		if p, ok := s.state.profiles[ProfileKey{ID: row.ProfileID, Provider: row.Provider}]; ok {
+			info.ProfileDeletedAt = cloneTime(p.DeletedAt)
+		}
+		tokens = append(tokens, info)
+	}
+
+	return &store.ListTokensOutput{
+		Tokens:  tokens,
+		HasMore: end < len(rows),
+	}, nil
+}
+
+func (s *TokenStore) ListDormantTokens(ctx context.Context, input *store.ListDormantTokensInput) ([]store.DormantToken, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.InactiveSince.IsZero() {
+		return nil, fmt.Errorf("inactive since is required")
+	}
+
+	limit := defaultTokensPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var tokens []store.DormantToken
+	for key, row := range s.state.tokens {
+		p, ok := s.state.profiles[key]
+		if !ok || p.DeletedAt != nil || slices.Contains(input.ExcludeProfileIDs, key.ID) {
+			continue
+		}
+
+		var lastActive time.Time
+		for _, session := range s.state.sessions {
+			if slices.Contains(session.Profiles, key) && session.UpdatedAt.After(lastActive) {
+				lastActive = session.UpdatedAt
+			}
+		}
+		if lastActive.IsZero() {
+			lastActive = row.UpdatedAt
+		}
+
+		if !lastActive.Before(input.InactiveSince) {
+			continue
+		}
+
+		tokens = append(tokens, store.DormantToken{
+			ProfileID:    key.ID,
+			Provider:     key.Provider,
+			LastActiveAt: lastActive,
+		})
+	}
+
+	slices.SortFunc(tokens, compareDormantTokens)
+
+	if input.After != nil {
+		tokens = slices.DeleteFunc(tokens, func(token store.DormantToken) bool {
+			return compareDormantTokens(token, *input.After) <= 0
+		})
+	}
+
+	if len(tokens) > limit {
+		tokens = tokens[:limit]
+	}
+
+	return tokens, nil
+}
+
+// compareDormantTokens orders dormant tokens least recently active first,
+// then by provider and profile id.
+func compareDormantTokens(a, b store.DormantToken) int {
+	if c := a.LastActiveAt.Compare(b.LastActiveAt); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Provider, b.Provider); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ProfileID, b.ProfileID)
+}
+
+func (s *TokenStore) DeleteToken(ctx context.Context, input *store.DeleteTokenInput) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return fmt.Errorf("profile id and provider are required")
+	}
+
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	key := ProfileKey{ID: input.ProfileID, Provider: input.Provider}
+	if row, ok := s.state.tokens[key]; ok {
+		s.state.recordDeletedToken(row, actor, time.Now().UTC())
+		delete(s.state.tokens, key)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) CreateTokenHealthReport(ctx context.Context, report *store.TokenHealthReport) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return err
+	}
+
+	if report == nil {
+		return fmt.Errorf("report is required")
+	}
+
+	recorded := *report
+	if recorded.CreatedAt.IsZero() {
+		recorded.CreatedAt = time.Now().UTC()
+	}
+
+	s.state.healthReports = append(s.state.healthReports, recorded)
+
+	return nil
+}
+
+// TokenHealthReports returns a copy of all persisted token health reports.
+func (s *Store) TokenHealthReports() []store.TokenHealthReport {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	return slices.Clone(s.state.healthReports)
+}
+
+// recordDeletedToken appends a deleted event for the token; callers must hold the lock.
+func (st *state) recordDeletedToken(row *tokenRow, actor string, now time.Time) {
+	st.tokenEvents = append(st.tokenEvents, store.TokenEvent{
+		ID:            st.nextID(),
+		ProfileID:     row.ProfileID,
+		Provider:      row.Provider,
+		Type:          store.TokenEventDeleted,
+		Actor:         actor,
+		OldExpiresAt:  cloneTime(row.ExpiresAt),
+		ScopesRemoved: slices.Clone(row.Scopes),
+		CreatedAt:     now,
+	})
+}
+
+// pseudonymizeTokenEvents moves the account's token history under its keyed
+// tombstone hash, so that it no longer names the account.
+func (st *state) pseudonymizeTokenEvents(provider, accountID string) {
+	idHash := st.tombstoneHasher.Hash(provider, accountID)
+	for i := range st.tokenEvents {
+		if e := &st.tokenEvents[i]; e.Provider == provider && e.ProfileID == accountID {
+			e.ProfileID = idHash
+		}
+	}
+}
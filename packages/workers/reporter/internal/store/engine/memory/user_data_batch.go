@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+func (s *UserDataStore) DeleteUserDataBatch(ctx context.Context, input *store.DeleteUserDataBatchInput) (*store.DeleteUserDataBatchOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return &store.DeleteUserDataBatchOutput{Results: []store.DeleteUserDataResult{}}, nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+
+	holdIDs := map[string]string{}
+	items := map[ProfileKey]*store.ErasedItems{}
+	for _, id := range accountIDs {
+		if id == "" {
+			continue
+		}
+		if hold := s.state.deferErasure(input.Provider, id, now); hold != nil {
+			holdIDs[id] = hold.ID
+			continue
+		}
+		items[ProfileKey{ID: id, Provider: input.Provider}] = &store.ErasedItems{}
+	}
+
+	for key, erased := range items {
+		if token, ok := s.state.tokens[key]; ok {
+			s.state.recordDeletedToken(token, store.TokenActorWorker, now)
+			delete(s.state.tokens, key)
+			erased.Tokens++
+		}
+		s.state.pseudonymizeTokenEvents(key.Provider, key.ID)
+	}
+
+	// Like the SQL engines, unlink every erased account before deleting or
+	// scrubbing sessions, so a shared session counts for each of its accounts.
+	for id, session := range s.state.sessions {
+		var unlinked []ProfileKey
+		session.Profiles = slices.DeleteFunc(session.Profiles, func(k ProfileKey) bool {
+			if _, ok := items[k]; ok {
+				unlinked = append(unlinked, k)
+				return true
+			}
+			return false
+		})
+		if len(unlinked) == 0 {
+			continue
+		}
+		for _, key := range unlinked {
+			items[key].SessionLinks++
+		}
+
+		if len(session.Profiles) == 0 {
+			delete(s.state.sessions, id)
+			for _, key := range unlinked {
+				items[key].Sessions++
+			}
+			continue
+		}
+
+		if scrubSessionData(session.Data, input.Provider) {
+			session.UpdatedAt = now
+			for _, key := range unlinked {
+				items[key].SessionsScrubbed++
+			}
+		}
+	}
+
+	erased := make(map[string]*store.ErasedItems, len(items))
+	for key, counts := range items {
+		if p, ok := s.state.profiles[key]; ok {
+			p.ReportedAt = timeRef(now)
+			p.DeletedAt = timeRef(now)
+			p.UpdatedAt = now
+			counts.Profiles++
+			s.state.syncProfilePersonalData(p, now)
+		}
+
+		s.state.resolveDeferredErasures(key.Provider, key.ID, now)
+
+		tombstone := tombstoneKey{provider: key.Provider, idHash: s.state.tombstoneHasher.Hash(key.Provider, key.ID)}
+		if _, ok := s.state.accountTombstones[tombstone]; !ok {
+			s.state.accountTombstones[tombstone] = now
+		}
+
+		erased[key.ID] = counts
+	}
+
+	return store.NewDeleteUserDataBatchOutput(accountIDs, now, holdIDs, erased), nil
+}
+
+func (s *UserDataStore) RefreshUserDataBatch(ctx context.Context, input *store.RefreshUserDataBatchInput) (*store.RefreshUserDataBatchOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return &store.RefreshUserDataBatchOutput{Results: []store.RefreshUserDataResult{}}, nil
+	}
+
+	policy, err := s.state.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+
+	var refreshed []string
+	if policy.Refresh == store.RefreshTouch {
+		for _, id := range accountIDs {
+			p, ok := s.state.profiles[ProfileKey{ID: id, Provider: input.Provider}]
+			if ok && p.DeletedAt == nil {
+				p.UpdatedAt = now
+				refreshed = append(refreshed, id)
+				s.state.syncProfilePersonalData(p, now)
+			}
+		}
+	}
+
+	return store.NewRefreshUserDataBatchOutput(accountIDs, now, refreshed), nil
+}
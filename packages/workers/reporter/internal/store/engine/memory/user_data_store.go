@@ -0,0 +1,371 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"hourly/workers/reporter/internal/domain"
+	"hourly/workers/reporter/internal/store"
+)
+
+type UserDataStore struct {
+	state *state
+}
+
+const (
+	defaultAccountsPage = 1000
+	defaultPurgeBatch   = 100
+)
+
+func (s *UserDataStore) GetAccountsToReport(ctx context.Context, input *store.GetAccountsToReportInput) (*store.GetAccountsToReportOutput, error) {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	policy, err := s.state.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := defaultAccountsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	after, err := store.DecodeAccountsCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	period, err := s.state.cyclePeriod(input.Provider, policy.CyclePeriodDays)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().UTC().Add(-period.Duration())
+
+	var candidates []domain.Account
+	for _, e := range s.state.inventory {
+		if e.Provider != input.Provider {
+			continue
+		}
+		if input.AccountIDs != nil && !slices.Contains(input.AccountIDs, e.AccountID) {
+			continue
+		}
+		if e.ReportedAt != nil && e.ReportedAt.After(cutoff) {
+			continue
+		}
+		candidates = append(candidates, domain.Account{AccountID: e.AccountID, UpdatedAt: e.DataRetrievedAt})
+	}
+
+	accounts, hasMore, total := pageAccounts(candidates, after, limit)
+
+	output := &store.GetAccountsToReportOutput{
+		Accounts: accounts,
+		HasMore:  hasMore,
+	}
+
+	if input.IncludeCount {
+		output.TotalCount = total
+	}
+
+	if hasMore {
+		last := accounts[len(accounts)-1]
+		output.NextCursor = store.AccountsCursor{UpdatedAt: last.UpdatedAt, AccountID: last.AccountID}.Encode()
+	}
+
+	return output, nil
+}
+
+// pageAccounts orders the candidates by updated_at DESC, id and returns up to
+// limit of them that sort after the cursor. total counts every candidate
+// regardless of the cursor.
+func pageAccounts(candidates []domain.Account, after *store.AccountsCursor, limit int) (accounts []domain.Account, hasMore bool, total int) {
+	slices.SortFunc(candidates, func(a, b domain.Account) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.AccountID, b.AccountID)
+	})
+
+	total = len(candidates)
+
+	if after != nil {
+		// Skip everything at or before the cursor in page order.
+		start, _ := slices.BinarySearchFunc(candidates, after, func(a domain.Account, cursor *store.AccountsCursor) int {
+			if c := cursor.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+				return c
+			}
+			if a.AccountID <= cursor.AccountID {
+				return -1
+			}
+			return 1
+		})
+		candidates = candidates[start:]
+	}
+
+	hasMore = len(candidates) > limit
+	if hasMore {
+		candidates = candidates[:limit]
+	}
+
+	return candidates, hasMore, total
+}
+
+func (s *UserDataStore) UpdateLastReported(ctx context.Context, input *store.UpdateLastReportedInput) (*store.UpdateLastReportedOutput, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return store.NewUpdateLastReportedOutput(nil, nil), nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	reportedAt := input.ReportedAt.UTC()
+
+	var updated []string
+	for _, id := range input.AccountIDs {
+		if e, ok := s.state.inventory[ProfileKey{ID: id, Provider: input.Provider}]; ok {
+			e.ReportedAt = timeRef(reportedAt)
+			updated = append(updated, id)
+		}
+
+		p, ok := s.state.profiles[ProfileKey{ID: id, Provider: input.Provider}]
+		if !ok || p.DeletedAt != nil {
+			continue
+		}
+		p.ReportedAt = timeRef(reportedAt)
+	}
+
+	return store.NewUpdateLastReportedOutput(input.AccountIDs, updated), nil
+}
+
+func (s *UserDataStore) DeleteUserData(ctx context.Context, input *store.DeleteUserDataInput) (*store.DeleteUserDataOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" {
+		return &store.DeleteUserDataOutput{
+			DeletedAt:    now.Format(time.RFC3339),
+			ItemsDeleted: 0,
+		}, nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	if hold := s.state.deferErasure(input.Provider, input.AccountID, now); hold != nil {
+		return &store.DeleteUserDataOutput{
+			Deferred: true,
+			HoldID:   hold.ID,
+		}, nil
+	}
+
+	key := ProfileKey{ID: input.AccountID, Provider: input.Provider}
+
+	var items store.ErasedItems
+
+	if token, ok := s.state.tokens[key]; ok {
+		s.state.recordDeletedToken(token, store.TokenActorWorker, now)
+		delete(s.state.tokens, key)
+		items.Tokens++
+	}
+	s.state.pseudonymizeTokenEvents(input.Provider, input.AccountID)
+
+	for id, session := range s.state.sessions {
+		linked := len(session.Profiles)
+		session.Profiles = slices.DeleteFunc(session.Profiles, func(k ProfileKey) bool { return k == key })
+		if len(session.Profiles) == linked {
+			continue
+		}
+		items.SessionLinks += linked - len(session.Profiles)
+
+		if len(session.Profiles) == 0 {
+			delete(s.state.sessions, id)
+			items.Sessions++
+			continue
+		}
+
+		if scrubSessionData(session.Data, key.Provider) {
+			session.UpdatedAt = now
+			items.SessionsScrubbed++
+		}
+	}
+
+	if p, ok := s.state.profiles[key]; ok {
+		p.ReportedAt = timeRef(now)
+		p.DeletedAt = timeRef(now)
+		p.UpdatedAt = now
+		items.Profiles++
+		s.state.syncProfilePersonalData(p, now)
+	}
+
+	s.state.resolveDeferredErasures(input.Provider, input.AccountID, now)
+
+	tombstone := tombstoneKey{provider: input.Provider, idHash: s.state.tombstoneHasher.Hash(input.Provider, input.AccountID)}
+	if _, ok := s.state.accountTombstones[tombstone]; !ok {
+		s.state.accountTombstones[tombstone] = now
+	}
+
+	return &store.DeleteUserDataOutput{
+		DeletedAt:    now.Format(time.RFC3339),
+		ItemsDeleted: items.Total(),
+		Items:        items,
+	}, nil
+}
+
+// scrubSessionData removes data.user.oauth[provider] and reports whether it was present.
+func scrubSessionData(data map[string]any, provider string) bool {
+	user, _ := data["user"].(map[string]any)
+	oauth, _ := user["oauth"].(map[string]any)
+	if _, ok := oauth[provider]; !ok {
+		return false
+	}
+	delete(oauth, provider)
+	return true
+}
+
+func (s *UserDataStore) RefreshUserData(ctx context.Context, input *store.RefreshUserDataInput) (*store.RefreshUserDataOutput, error) {
+	now := time.Now().UTC()
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.AccountID == "" {
+		return &store.RefreshUserDataOutput{
+			RefreshedAt:  now.Format(time.RFC3339),
+			ItemsUpdated: 0,
+		}, nil
+	}
+
+	policy, err := s.state.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemsUpdated int
+
+	p, ok := s.state.profiles[ProfileKey{ID: input.AccountID, Provider: input.Provider}]
+	if ok && p.DeletedAt == nil && policy.Refresh == store.RefreshTouch {
+		p.UpdatedAt = now
+		itemsUpdated++
+		s.state.syncProfilePersonalData(p, now)
+	}
+
+	return &store.RefreshUserDataOutput{
+		RefreshedAt:  now.Format(time.RFC3339),
+		ItemsUpdated: itemsUpdated,
+	}, nil
+}
+
+func (s *UserDataStore) PurgeDeletedProfiles(ctx context.Context, input *store.PurgeDeletedProfilesInput) (*store.PurgeDeletedProfilesOutput, error) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if err := s.state.checkOpened(); err != nil {
+		return nil, err
+	}
+
+	if input == nil || input.DeletedBefore.IsZero() {
+		return nil, fmt.Errorf("deleted before is required")
+	}
+
+	limit := defaultPurgeBatch
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var candidates []*Profile
+	for _, p := range s.state.profiles {
+		if input.Provider != "" && p.Provider != input.Provider {
+			continue
+		}
+		if p.DeletedAt != nil && p.DeletedAt.Before(input.DeletedBefore) {
+			candidates = append(candidates, p)
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b *Profile) int {
+		if c := a.DeletedAt.Compare(*b.DeletedAt); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	hasMore := len(candidates) > limit
+	if hasMore {
+		candidates = candidates[:limit]
+	}
+
+	now := time.Now().UTC()
+	for _, p := range candidates {
+		key := ProfileKey{ID: p.ID, Provider: p.Provider}
+		idHash := s.state.tombstoneHasher.Hash(p.Provider, p.ID)
+
+		s.state.tombstones[tombstoneKey{provider: p.Provider, idHash: idHash}] = Tombstone{
+			Provider:  p.Provider,
+			IDHash:    idHash,
+			DeletedAt: *p.DeletedAt,
+			PurgedAt:  now,
+		}
+		s.state.tokenEvents = slices.DeleteFunc(s.state.tokenEvents, func(e store.TokenEvent) bool {
+			return e.Provider == p.Provider && (e.ProfileID == p.ID || e.ProfileID == idHash)
+		})
+
+		// Mirror ON DELETE CASCADE on tokens and profiles_on_sessions.
+		delete(s.state.tokens, key)
+		for _, session := range s.state.sessions {
+			session.Profiles = slices.DeleteFunc(session.Profiles, func(k ProfileKey) bool { return k == key })
+		}
+
+		delete(s.state.profiles, key)
+		s.state.unregisterPersonalData(p.Provider, p.ID, store.PersonalDataProfiles, now)
+	}
+
+	return &store.PurgeDeletedProfilesOutput{
+		Purged:  len(candidates),
+		HasMore: hasMore,
+	}, nil
+}
+
+// Tombstones returns a copy of the profile tombstones.
+func (s *Store) Tombstones() []Tombstone {
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+
+	tombstones := make([]Tombstone, 0, len(s.state.tombstones))
+	for _, t := range s.state.tombstones {
+		tombstones = append(tombstones, t)
+	}
+	return tombstones
+}
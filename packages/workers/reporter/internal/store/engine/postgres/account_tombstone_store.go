@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type AccountTombstoneStore struct {
+	db     *sqlx.DB
+	hasher *store.TombstoneHasher
+}
+
+// insertAccountTombstoneQuery keeps the first closure of an account.
+const insertAccountTombstoneQuery = `
+INSERT INTO account_tombstones (
+	provider,
+	id_hash,
+	closed_at
+) VALUES (
+	$1, $2, $3
+)
+ON CONFLICT (provider, id_hash) DO NOTHING`
+
+const selectAccountTombstonesQuery = `
+SELECT
+	id_hash
+FROM
+	account_tombstones
+WHERE
+	provider = $1
+	AND id_hash = ANY($2)`
+
+func (s *Store) AccountTombstones() store.AccountTombstoneStore {
+	return s.tombstones
+}
+
+func (s *AccountTombstoneStore) IsTombstoned(ctx context.Context, input *store.AccountTombstoneKey) (bool, error) {
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return false, fmt.Errorf("account id and provider are required")
+	}
+
+	tombstoned, err := s.FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   input.Provider,
+		AccountIDs: []string{input.AccountID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(tombstoned) > 0, nil
+}
+
+func (s *AccountTombstoneStore) FilterTombstoned(ctx context.Context, input *store.FilterTombstonedInput) ([]string, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	if s.hasher == nil {
+		return nil, store.ErrTombstonesDisabled
+	}
+
+	if len(input.AccountIDs) == 0 {
+		return nil, nil
+	}
+
+	hashes := make([]string, len(input.AccountIDs))
+	for i, id := range input.AccountIDs {
+		hashes[i] = s.hasher.Hash(input.Provider, id)
+	}
+
+	var found []string
+	if err := s.db.SelectContext(ctx, &found, selectAccountTombstonesQuery, input.Provider, pq.Array(hashes)); err != nil {
+		return nil, fmt.Errorf("check account tombstones: %w", err)
+	}
+
+	return store.TombstonedAccounts(input.AccountIDs, hashes, found), nil
+}
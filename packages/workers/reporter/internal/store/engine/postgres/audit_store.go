@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type AuditStore struct {
+	db *sqlx.DB
+}
+
+const insertAuditLogQuery = `
+INSERT INTO audit_logs (
+	id,
+	action_type,
+	action_description,
+	severity,
+	target_resource_type,
+	target_resource_id,
+	outcome,
+	correlation_id,
+	request_id,
+	request_path,
+	request_method,
+	metadata
+) VALUES (
+	gen_random_uuid()::text,
+	$1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11
+)`
+
+func (s *Store) Audit() store.AuditStore {
+	return s.audit
+}
+
+func (s *AuditStore) RecordAuditLog(ctx context.Context, entry *store.AuditLogEntry) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if entry == nil {
+		return fmt.Errorf("entry is required")
+	}
+
+	severity := entry.Severity
+	if severity == "" {
+		severity = store.AuditSeverityInfo
+	}
+
+	var metadata sql.NullString
+	if len(entry.Metadata) > 0 {
+		data, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return fmt.Errorf("marshal audit metadata: %w", err)
+		}
+		metadata = sql.NullString{String: string(data), Valid: true}
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertAuditLogQuery,
+		entry.ActionType,
+		entry.ActionDescription,
+		severity,
+		entry.TargetResourceType,
+		entry.TargetResourceID,
+		entry.Outcome,
+		entry.CorrelationID,
+		entry.RequestID,
+		entry.RequestPath,
+		entry.RequestMethod,
+		metadata,
+	); err != nil {
+		return fmt.Errorf("insert audit log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package postgres_test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// testDSNEnv names the variable holding the DSN of a database in which the
+// tests may create and drop schemas. The tests are skipped when it is unset.
+const testDSNEnv = "REPORTER_TEST_DATABASE_URL"
+
+// webMigrationsDir is the web app's dbmate migrations directory, which owns
+// the schema the worker runs against.
+const webMigrationsDir = "../../../../../../web/db/migrations"
+
+const (
+	migrateUpMarker   = "-- migrate:up"
+	migrateDownMarker = "-- migrate:down"
+)
+
+// preexistingSchema holds objects that deployed databases already had when a
+// released migration was written but that no migration creates, keyed by the
+// version of the migration that expects them. They are created just before
+// that migration so that it applies to a fresh schema unchanged.
+var preexistingSchema = map[string]string{
+	// The soft-delete migration replaces this index, which predates the
+	// migrations directory.
+	"20260101142000": `CREATE INDEX idx_profiles_provider_reported_at_updated_at_active
+  ON profiles (provider, reported_at, updated_at DESC)`,
+}
+
+// migration is the up section of a web app migration.
+type migration struct {
+	version string
+	name    string
+	up      string
+}
+
+// testDSN returns the test database DSN or skips the test.
+func testDSN(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	return dsn
+}
+
+// webMigrations reads the web app migrations in version order.
+func webMigrations(t *testing.T) []migration {
+	t.Helper()
+
+	names, err := filepath.Glob(filepath.Join(webMigrationsDir, "*.sql"))
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	if len(names) == 0 {
+		t.Fatalf("no migrations found in %s", webMigrationsDir)
+	}
+	sort.Strings(names)
+
+	migrations := make([]migration, 0, len(names))
+	for _, path := range names {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read migration: %v", err)
+		}
+
+		_, rest, ok := strings.Cut(string(data), migrateUpMarker)
+		if !ok {
+			t.Fatalf("migration %s: missing %q section", path, migrateUpMarker)
+		}
+		up, _, _ := strings.Cut(rest, migrateDownMarker)
+
+		name := filepath.Base(path)
+		version, _, _ := strings.Cut(name, "_")
+		migrations = append(migrations, migration{version: version, name: name, up: up})
+	}
+
+	return migrations
+}
+
+// newSchema creates a throwaway schema, dropped when the test ends, and
+// returns a DSN whose search_path points at it.
+func newSchema(t *testing.T, dsn string) (schema, schemaDSN string) {
+	t.Helper()
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema = "reporter_test_" + hex.EncodeToString(suffix)
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, name := range []string{schema, auditSchema(schema)} {
+			if _, err := admin.Exec(`DROP SCHEMA IF EXISTS ` + name + ` CASCADE`); err != nil {
+				t.Errorf("drop schema %s: %v", name, err)
+			}
+		}
+	})
+
+	schemaDSN, err = withSearchPath(dsn, schema)
+	if err != nil {
+		t.Fatalf("schema dsn: %v", err)
+	}
+	return schema, schemaDSN
+}
+
+// auditSchema names the schema that stands in for "audit", which the audit
+// log migration creates for its partitions.
+func auditSchema(schema string) string {
+	return schema + "_audit"
+}
+
+// withSearchPath sets search_path on a URL or key/value DSN.
+func withSearchPath(dsn, schema string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return dsn + " search_path=" + schema, nil
+}
+
+// migrateSchema applies the given migrations to the schema and records them
+// in schema_migrations the way dbmate does. Migrations that name the public
+// or audit schemas explicitly are pointed at the throwaway ones instead.
+func migrateSchema(t *testing.T, schemaDSN, schema string, migrations []migration) {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := sqlx.Connect("postgres", schemaDSN)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(128) PRIMARY KEY)`); err != nil {
+		t.Fatalf("create schema_migrations: %v", err)
+	}
+
+	rewrite := strings.NewReplacer(
+		"public.", schema+".",
+		"CREATE SCHEMA audit", "CREATE SCHEMA "+auditSchema(schema),
+		"DROP SCHEMA audit", "DROP SCHEMA "+auditSchema(schema),
+		"audit.", auditSchema(schema)+".",
+	)
+
+	for _, m := range migrations {
+		if stmt, ok := preexistingSchema[m.version]; ok {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				t.Fatalf("migration %s: create preexisting schema: %v", m.name, err)
+			}
+		}
+		if err := applyMigration(ctx, db, m.version, rewrite.Replace(m.up)); err != nil {
+			t.Fatalf("migration %s: %v", m.name, err)
+		}
+	}
+}
+
+func applyMigration(ctx context.Context, db *sqlx.DB, version, up string) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, up); err != nil {
+		return fmt.Errorf("apply: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return fmt.Errorf("record: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type InventoryStore struct {
+	db *sqlx.DB
+}
+
+const registerPersonalDataQuery = `
+INSERT INTO personal_data_inventory AS inv (
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at
+)
+SELECT DISTINCT
+	$1,
+	account_id,
+	ARRAY[$2::text],
+	$3::timestamptz
+FROM
+	unnest($4::text[]) AS account_id
+ON CONFLICT (provider, account_id) DO UPDATE SET
+	stores = ARRAY(SELECT DISTINCT s FROM unnest(inv.stores || EXCLUDED.stores) AS s ORDER BY s),
+	data_retrieved_at = GREATEST(inv.data_retrieved_at, EXCLUDED.data_retrieved_at),
+	updated_at = now()`
+
+const unregisterPersonalDataQuery = `
+UPDATE personal_data_inventory SET
+	stores = array_remove(stores, $2),
+	updated_at = now()
+WHERE
+	provider = $1
+	AND account_id = ANY($3)
+	AND $2 = ANY(stores)`
+
+const deleteEmptyPersonalDataQuery = `
+DELETE FROM
+	personal_data_inventory
+WHERE
+	provider = $1
+	AND account_id = ANY($2)
+	AND stores = '{}'`
+
+const selectPersonalDataQuery = `
+SELECT
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at,
+	reported_at,
+	created_at,
+	updated_at
+FROM
+	personal_data_inventory
+WHERE
+	provider = $1
+	AND account_id = $2`
+
+func (s *Store) Inventory() store.InventoryStore {
+	return s.inventory
+}
+
+func (s *InventoryStore) RegisterPersonalData(ctx context.Context, input *store.RegisterPersonalDataInput) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" || input.RetrievedAt.IsZero() {
+		return fmt.Errorf("provider, store, and retrieved at are required")
+	}
+
+	if len(input.AccountIDs) == 0 {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		registerPersonalDataQuery,
+		input.Provider,
+		input.Store,
+		input.RetrievedAt.UTC(),
+		pq.Array(input.AccountIDs),
+	); err != nil {
+		return fmt.Errorf("register %s personal data: %w", input.Store, err)
+	}
+
+	return nil
+}
+
+func (s *InventoryStore) UnregisterPersonalData(ctx context.Context, input *store.UnregisterPersonalDataInput) (*store.UnregisterPersonalDataOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" {
+		return nil, fmt.Errorf("provider and store are required")
+	}
+
+	if len(input.AccountIDs) == 0 {
+		return &store.UnregisterPersonalDataOutput{}, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, unregisterPersonalDataQuery, input.Provider, input.Store, pq.Array(input.AccountIDs)); err != nil {
+		return nil, fmt.Errorf("unregister %s personal data: %w", input.Store, err)
+	}
+
+	result, err := tx.ExecContext(ctx, deleteEmptyPersonalDataQuery, input.Provider, pq.Array(input.AccountIDs))
+	if err != nil {
+		return nil, fmt.Errorf("remove empty inventory entries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit unregister: %w", err)
+	}
+
+	return &store.UnregisterPersonalDataOutput{Removed: rowsAffected(result)}, nil
+}
+
+func (s *InventoryStore) GetPersonalData(ctx context.Context, input *store.PersonalDataKey) (*store.PersonalDataEntry, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	var row struct {
+		Provider        string         `db:"provider"`
+		AccountID       string         `db:"account_id"`
+		Stores          pq.StringArray `db:"stores"`
+		DataRetrievedAt time.Time      `db:"data_retrieved_at"`
+		ReportedAt      sql.NullTime   `db:"reported_at"`
+		CreatedAt       time.Time      `db:"created_at"`
+		UpdatedAt       time.Time      `db:"updated_at"`
+	}
+	if err := s.db.GetContext(ctx, &row, selectPersonalDataQuery, input.Provider, input.AccountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get personal data of account %s: %w", input.AccountID, err)
+	}
+
+	stores := []string(row.Stores)
+	slices.Sort(stores)
+
+	return &store.PersonalDataEntry{
+		AccountID:       row.AccountID,
+		Provider:        row.Provider,
+		Stores:          stores,
+		DataRetrievedAt: row.DataRetrievedAt,
+		ReportedAt:      timePtr(row.ReportedAt),
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+	}, nil
+}
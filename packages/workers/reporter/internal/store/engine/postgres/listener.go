@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const profileCreatedChannel = "profile_created"
+
+const (
+	defaultMinReconnectInterval = time.Second
+	defaultMaxReconnectInterval = time.Minute
+	defaultCatchUpSlack         = time.Minute
+
+	// listenerPingInterval bounds how long a silently dead connection goes unnoticed.
+	listenerPingInterval = 90 * time.Second
+)
+
+const selectProfilesCreatedSinceQuery = `
+SELECT
+	id,
+	provider
+FROM
+	profiles
+WHERE
+	created_at >= $1
+	AND deleted_at IS NULL
+ORDER BY
+	created_at`
+
+// ListenOptions configures ListenProfileCreated.
+type ListenOptions struct {
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+	// CatchUpSlack widens the replay window after a reconnect to cover clock
+	// skew between the worker and the database (default: 1m).
+	CatchUpSlack time.Duration
+	// OnError is called with connection and decoding errors; the listener
+	// keeps running after each of them.
+	OnError func(error)
+}
+
+// ListenProfileCreated subscribes to the profile_created notifications raised
+// by the profiles insert trigger. Notifications sent while the connection is
+// down are lost, so after a reconnect the profiles created since the drop are
+// replayed from the table; consumers must tolerate duplicates. The returned
+// channel is closed once ctx is done.
+func (s *Store) ListenProfileCreated(ctx context.Context, opts ListenOptions) (<-chan store.ProfileCreated, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if opts.MinReconnectInterval <= 0 {
+		opts.MinReconnectInterval = defaultMinReconnectInterval
+	}
+	if opts.MaxReconnectInterval <= 0 {
+		opts.MaxReconnectInterval = defaultMaxReconnectInterval
+	}
+	if opts.CatchUpSlack <= 0 {
+		opts.CatchUpSlack = defaultCatchUpSlack
+	}
+
+	report := func(err error) {
+		if err != nil && opts.OnError != nil {
+			opts.OnError(err)
+		}
+	}
+
+	var (
+		mu             sync.Mutex
+		disconnectedAt time.Time
+		// disconnects counts drops, so that a catch-up can tell whether the
+		// connection dropped again while it ran.
+		disconnects int
+	)
+
+	listener := pq.NewListener(s.dsn, opts.MinReconnectInterval, opts.MaxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			mu.Lock()
+			if disconnectedAt.IsZero() {
+				disconnectedAt = time.Now()
+			}
+			disconnects++
+			mu.Unlock()
+			report(fmt.Errorf("profile listener disconnected: %w", err))
+		case pq.ListenerEventConnectionAttemptFailed:
+			report(fmt.Errorf("profile listener reconnect failed: %w", err))
+		}
+	})
+
+	if err := listener.Listen(profileCreatedChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listen %s: %w", profileCreatedChannel, err)
+	}
+
+	events := make(chan store.ProfileCreated, 64)
+
+	send := func(event store.ProfileCreated) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var (
+		retry      <-chan time.Time
+		retryDelay = opts.MinReconnectInterval
+	)
+
+	// catchUp replays the profiles created since the connection dropped. The
+	// drop is only forgotten once the replay succeeded; a failed replay is
+	// retried with backoff. It returns false once ctx is done.
+	catchUp := func() bool {
+		mu.Lock()
+		since, generation := disconnectedAt, disconnects
+		mu.Unlock()
+
+		if since.IsZero() {
+			return true
+		}
+
+		created, err := s.profilesCreatedSince(ctx, since.Add(-opts.CatchUpSlack))
+		if err != nil {
+			report(err)
+			retry = time.After(retryDelay)
+			retryDelay = min(2*retryDelay, opts.MaxReconnectInterval)
+			return true
+		}
+		retry, retryDelay = nil, opts.MinReconnectInterval
+
+		// A drop during the replay keeps the earlier timestamp, so the next
+		// reconnect replays from it again.
+		mu.Lock()
+		if disconnects == generation {
+			disconnectedAt = time.Time{}
+		}
+		mu.Unlock()
+
+		for _, event := range created {
+			if !send(event) {
+				return false
+			}
+		}
+		return true
+	}
+
+	go func() {
+		defer close(events)
+		defer listener.Close()
+
+		ping := time.NewTicker(listenerPingInterval)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ping.C:
+				go listener.Ping()
+
+			case <-retry:
+				if !catchUp() {
+					return
+				}
+
+			case n := <-listener.Notify:
+				if n == nil {
+					// pq signals a re-established connection with a nil notification.
+					if !catchUp() {
+						return
+					}
+					continue
+				}
+
+				var event store.ProfileCreated
+				if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+					report(fmt.Errorf("decode %s payload: %w", profileCreatedChannel, err))
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (s *Store) profilesCreatedSince(ctx context.Context, since time.Time) ([]store.ProfileCreated, error) {
+	var rows []store.ProfileCreated
+	if err := s.db.SelectContext(ctx, &rows, selectProfilesCreatedSinceQuery, since.UTC()); err != nil {
+		return nil, fmt.Errorf("catch up created profiles: %w", err)
+	}
+	return rows, nil
+}
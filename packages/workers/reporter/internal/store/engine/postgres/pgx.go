@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// Driver selects the client library the engine talks to Postgres with.
+type Driver string
+
+const (
+	// DriverPQ uses lib/pq through database/sql.
+	DriverPQ Driver = "pq"
+	// DriverPGX uses a pgx connection pool. Bulk writes bypass database/sql
+	// and go through pgx directly.
+	DriverPGX Driver = "pgx"
+)
+
+const (
+	defaultSlowQueryThreshold = 500 * time.Millisecond
+
+	// reportedCopyThreshold is the number of account IDs above which
+	// reported_at updates copy the IDs into a temporary table instead of
+	// sending them as query parameters.
+	reportedCopyThreshold = 10000
+)
+
+const (
+	batchUpdateReportedAtQuery = `
+UPDATE
+	profiles p
+SET
+	reported_at = $1
+FROM
+	unnest($3::text[]) AS batch(account_id)
+WHERE
+	p.provider = $2
+	AND p.id = batch.account_id
+	AND p.deleted_at IS NULL`
+
+	batchUpdateInventoryReportedAtQuery = `
+UPDATE
+	personal_data_inventory i
+SET
+	reported_at = $1
+FROM
+	unnest($3::text[]) AS batch(account_id)
+WHERE
+	i.provider = $2
+	AND i.account_id = batch.account_id
+RETURNING
+	i.account_id`
+
+	createReportedAccountsQuery = `
+CREATE TEMPORARY TABLE reported_accounts (
+	account_id text PRIMARY KEY
+) ON COMMIT DROP`
+
+	copyUpdateReportedAtQuery = `
+UPDATE
+	profiles p
+SET
+	reported_at = $1
+FROM
+	reported_accounts r
+WHERE
+	p.provider = $2
+	AND p.id = r.account_id
+	AND p.deleted_at IS NULL`
+
+	copyUpdateInventoryReportedAtQuery = `
+UPDATE
+	personal_data_inventory i
+SET
+	reported_at = $1
+FROM
+	reported_accounts r
+WHERE
+	i.provider = $2
+	AND i.account_id = r.account_id
+RETURNING
+	i.account_id`
+)
+
+// connectPGX opens a pgx pool for dsn and wraps it for the sqlx queries. The
+// pool settings replace the database/sql ones, which would otherwise hold a
+// second set of idle connections on top of the pool's.
+// MaxIdleConnections has no pgxpool equivalent and is ignored.
+func (s *Store) connectPGX(ctx context.Context, dsn string) (*sqlx.DB, *pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse connection string: %w", err)
+	}
+
+	if s.maxOpenConnections > 0 {
+		config.MaxConns = int32(s.maxOpenConnections)
+	}
+	if s.connMaxLifetime > 0 {
+		config.MaxConnLifetime = s.connMaxLifetime
+	}
+	if s.connMaxIdleTime > 0 {
+		config.MaxConnIdleTime = s.connMaxIdleTime
+	}
+	if s.healthCheckPeriod > 0 {
+		config.HealthCheckPeriod = s.healthCheckPeriod
+	}
+	config.ConnConfig.Tracer = &slowQueryTracer{threshold: s.slowQueryThreshold}
+	config.ConnConfig.RuntimeParams["application_name"] = ApplicationName
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	return sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx"), pool, nil
+}
+
+// updateLastReportedPGX updates reported_at in one transaction and returns
+// the accounts whose inventory entry was marked. Small updates are queued as
+// a single batch of UPDATE ... FROM unnest() statements, one pair per chunk of
+// IDs; large ones copy the IDs into a temporary table and update from it.
+func updateLastReportedPGX(ctx context.Context, pool *pgxpool.Pool, provider string, accountIDs []string, reportedAt time.Time) ([]string, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var updated []string
+	if len(accountIDs) > reportedCopyThreshold {
+		updated, err = copyReportedAt(ctx, tx, provider, accountIDs, reportedAt)
+	} else {
+		updated, err = batchReportedAt(ctx, tx, provider, accountIDs, reportedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit reported_at: %w", err)
+	}
+
+	return updated, nil
+}
+
+func batchReportedAt(ctx context.Context, tx pgx.Tx, provider string, accountIDs []string, reportedAt time.Time) ([]string, error) {
+	batch := &pgx.Batch{}
+	chunks := 0
+	for chunk := range slices.Chunk(accountIDs, reportedChunkSize) {
+		batch.Queue(batchUpdateInventoryReportedAtQuery, reportedAt, provider, chunk)
+		batch.Queue(batchUpdateReportedAtQuery, reportedAt, provider, chunk)
+		chunks++
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	var updated []string
+	for range chunks {
+		rows, err := results.Query()
+		if err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+		marked, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+		updated = append(updated, marked...)
+
+		if _, err := results.Exec(); err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		return nil, fmt.Errorf("update reported_at: %w", err)
+	}
+
+	return updated, nil
+}
+
+func copyReportedAt(ctx context.Context, tx pgx.Tx, provider string, accountIDs []string, reportedAt time.Time) ([]string, error) {
+	if _, err := tx.Exec(ctx, createReportedAccountsQuery); err != nil {
+		return nil, fmt.Errorf("create reported accounts table: %w", err)
+	}
+
+	// The primary key rejects duplicates, which COPY cannot skip.
+	ids := slices.Clone(accountIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	if _, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"reported_accounts"},
+		[]string{"account_id"},
+		pgx.CopyFromSlice(len(ids), func(i int) ([]any, error) {
+			return []any{ids[i]}, nil
+		}),
+	); err != nil {
+		return nil, fmt.Errorf("copy reported accounts: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, copyUpdateInventoryReportedAtQuery, reportedAt, provider)
+	if err != nil {
+		return nil, fmt.Errorf("update reported_at: %w", err)
+	}
+	updated, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("update reported_at: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, copyUpdateReportedAtQuery, reportedAt, provider); err != nil {
+		return nil, fmt.Errorf("update reported_at: %w", err)
+	}
+
+	return updated, nil
+}
+
+type slowQueryStart struct {
+	sql     string
+	startAt time.Time
+}
+
+type slowQueryStartKey struct{}
+
+// slowQueryTracer logs queries and batches that take longer than threshold.
+// Only the statement is logged; arguments may hold personal data.
+type slowQueryTracer struct {
+	threshold time.Duration
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryStartKey{}, slowQueryStart{sql: data.SQL, startAt: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(slowQueryStartKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	t.log(start, data.Err)
+}
+
+func (t *slowQueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, slowQueryStartKey{}, slowQueryStart{
+		sql:     fmt.Sprintf("batch of %d queries", data.Batch.Len()),
+		startAt: time.Now(),
+	})
+}
+
+func (t *slowQueryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (t *slowQueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	start, ok := ctx.Value(slowQueryStartKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	t.log(start, data.Err)
+}
+
+func (t *slowQueryTracer) log(start slowQueryStart, err error) {
+	threshold := t.threshold
+	if threshold <= 0 {
+		threshold = defaultSlowQueryThreshold
+	}
+
+	elapsed := time.Since(start.startAt)
+	if elapsed < threshold {
+		return
+	}
+
+	sql := strings.Join(strings.Fields(start.sql), " ")
+	if err != nil {
+		log.Println("Slow postgres query failed", elapsed.Round(time.Millisecond), sql, err)
+		return
+	}
+	log.Println("Slow postgres query", elapsed.Round(time.Millisecond), sql)
+}
@@ -2,29 +2,111 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"hourly/workers/reporter/internal/store"
 )
 
+// ApplicationName is the application_name of every worker connection. The
+// web app's tokens_record_event trigger leaves token mutations made under it
+// to the worker, which records its own token events.
+const ApplicationName = "hourly-reporter"
+
+const (
+	defaultConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+)
+
 type Store struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	replica *sqlx.DB
 
+	// pool and replicaPool back db and replica with DriverPGX.
+	pool        *pgxpool.Pool
+	replicaPool *pgxpool.Pool
+
+	driver             Driver
 	dsn                string
+	replicaDSN         string
+	maxReplicaLag      time.Duration
 	maxIdleConnections int
 	maxOpenConnections int
+	connMaxLifetime    time.Duration
+	connMaxIdleTime    time.Duration
+	connectAttempts    int
+	connectBackoff     time.Duration
+	slowQueryThreshold time.Duration
+	healthCheckPeriod  time.Duration
+
+	allowSchemaMismatch bool
+	schemaMismatch      error
 
-	userData *UserDataStore
-	tokens   *TokenStore
+	tombstoneHasher *store.TombstoneHasher
+	policies        store.ProviderPolicies
+
+	userData       *UserDataStore
+	tokens         *TokenStore
+	audit          *AuditStore
+	settings       *SettingsStore
+	privacyActions *PrivacyActionStore
+	legalHolds     *LegalHoldStore
+	inventory      *InventoryStore
+	tombstones     *AccountTombstoneStore
 }
 
 type Options struct {
-	Connection         string
+	Connection string
+	// Driver is the client library to connect with (default: DriverPQ).
+	Driver Driver
+
 	MaxIdleConnections int
 	MaxOpenConnections int
+	// ConnMaxLifetime closes connections after this age; zero keeps them.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle for this long; zero keeps them.
+	ConnMaxIdleTime time.Duration
+
+	// ConnectAttempts is how many times Open tries to connect before giving
+	// up (default: 1).
+	ConnectAttempts int
+	// ConnectBackoff is the delay before the first retry. It doubles on each
+	// attempt up to maxConnectBackoff (default: 1s).
+	ConnectBackoff time.Duration
+
+	// SlowQueryThreshold is the duration above which DriverPGX logs a query
+	// (default: 500ms).
+	SlowQueryThreshold time.Duration
+	// HealthCheckPeriod is how often DriverPGX checks idle connections
+	// (default: pgxpool's, 1m).
+	HealthCheckPeriod time.Duration
+
+	// ReplicaConnection is an optional read replica DSN. Lag-tolerant reads
+	// such as account listing go there; writes and reads that must observe
+	// them stay on the primary.
+	ReplicaConnection string
+	// MaxReplicaLag is the replay lag above which reads fall back to the
+	// primary (default: 30s).
+	MaxReplicaLag time.Duration
+
+	// AllowSchemaMismatch lets Open succeed when the schema check fails; the
+	// mismatch is then available from SchemaMismatch.
+	AllowSchemaMismatch bool
+
+	// TombstoneKey is the secret account tombstones are hashed with. It must
+	// match the web app's. Without it, tombstone lookups fail with
+	// store.ErrTombstonesDisabled and accounts are erased without tombstones.
+	TombstoneKey string
+
+	// Policies sets how each provider's accounts are kept current
+	// (default: store.DefaultProviderPolicies).
+	Policies store.ProviderPolicies
 }
 
 func New(opts Options) (*Store, error) {
@@ -32,11 +114,56 @@ func New(opts Options) (*Store, error) {
 		return nil, fmt.Errorf("postgres connection string is required")
 	}
 
+	driver := opts.Driver
+	switch driver {
+	case "":
+		driver = DriverPQ
+	case DriverPQ, DriverPGX:
+	default:
+		return nil, fmt.Errorf("unknown postgres driver: %s", driver)
+	}
+
+	var hasher *store.TombstoneHasher
+	if opts.TombstoneKey != "" {
+		h, err := store.NewTombstoneHasher(opts.TombstoneKey)
+		if err != nil {
+			return nil, err
+		}
+		hasher = h
+	}
+
+	policies := opts.Policies
+	if policies == nil {
+		policies = store.DefaultProviderPolicies()
+	}
+	if err := policies.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &Store{
-		dsn:                opts.Connection,
-		maxIdleConnections: opts.MaxIdleConnections,
-		maxOpenConnections: opts.MaxOpenConnections,
-		userData:           &UserDataStore{},
+		driver:              driver,
+		dsn:                 opts.Connection,
+		replicaDSN:          opts.ReplicaConnection,
+		maxReplicaLag:       opts.MaxReplicaLag,
+		maxIdleConnections:  opts.MaxIdleConnections,
+		maxOpenConnections:  opts.MaxOpenConnections,
+		connMaxLifetime:     opts.ConnMaxLifetime,
+		connMaxIdleTime:     opts.ConnMaxIdleTime,
+		connectAttempts:     opts.ConnectAttempts,
+		connectBackoff:      opts.ConnectBackoff,
+		slowQueryThreshold:  opts.SlowQueryThreshold,
+		healthCheckPeriod:   opts.HealthCheckPeriod,
+		allowSchemaMismatch: opts.AllowSchemaMismatch,
+		tombstoneHasher:     hasher,
+		policies:            policies,
+		userData:            &UserDataStore{},
+		tokens:              &TokenStore{},
+		audit:               &AuditStore{},
+		settings:            &SettingsStore{},
+		privacyActions:      &PrivacyActionStore{},
+		legalHolds:          &LegalHoldStore{},
+		inventory:           &InventoryStore{},
+		tombstones:          &AccountTombstoneStore{},
 	}, nil
 }
 
@@ -45,23 +172,138 @@ func (s *Store) Open(ctx context.Context) error {
 		return nil
 	}
 
-	db, err := sqlx.ConnectContext(ctx, "postgres", s.dsn)
+	db, pool, err := s.connect(ctx, s.dsn)
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres: %w", err)
 	}
 
+	if err := checkSchema(ctx, db); err != nil {
+		var mismatch *SchemaMismatchError
+		if !errors.As(err, &mismatch) || !s.allowSchemaMismatch {
+			closeDB(db, pool)
+			return err
+		}
+		s.schemaMismatch = mismatch
+	}
+
+	var (
+		replica     *sqlx.DB
+		replicaPool *pgxpool.Pool
+	)
+	if s.replicaDSN != "" {
+		replica, replicaPool, err = s.connect(ctx, s.replicaDSN)
+		if err != nil {
+			closeDB(db, pool)
+			return fmt.Errorf("failed to connect to postgres replica: %w", err)
+		}
+	}
+
+	reads := newReadRouter(db, replica, s.maxReplicaLag)
+
+	s.db = db
+	s.replica = replica
+	s.pool = pool
+	s.replicaPool = replicaPool
+	s.userData = &UserDataStore{db: db, pool: pool, reads: reads, hasher: s.tombstoneHasher, policies: s.policies}
+	s.tokens = &TokenStore{db: db, reads: reads}
+	s.audit = &AuditStore{db: db}
+	s.settings = &SettingsStore{db: db}
+	s.privacyActions = &PrivacyActionStore{db: db, reads: reads}
+	s.legalHolds = &LegalHoldStore{db: db}
+	s.inventory = &InventoryStore{db: db}
+	s.tombstones = &AccountTombstoneStore{db: db, hasher: s.tombstoneHasher}
+
+	return nil
+}
+
+// connect opens and pings a pool for dsn, retrying with exponential backoff
+// up to connectAttempts times, and applies the pool settings. The pgx pool is
+// nil unless the driver is DriverPGX.
+func (s *Store) connect(ctx context.Context, dsn string) (*sqlx.DB, *pgxpool.Pool, error) {
+	attempts := max(s.connectAttempts, 1)
+
+	backoff := s.connectBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	var (
+		db   *sqlx.DB
+		pool *pgxpool.Pool
+		err  error
+	)
+
+	if s.driver != DriverPGX {
+		if dsn, err = withApplicationName(dsn); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		if s.driver == DriverPGX {
+			db, pool, err = s.connectPGX(ctx, dsn)
+		} else {
+			db, err = sqlx.ConnectContext(ctx, "postgres", dsn)
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			return nil, nil, fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, errors.Join(err, ctx.Err())
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	if pool != nil {
+		return db, pool, nil
+	}
+
 	if s.maxIdleConnections > 0 {
 		db.SetMaxIdleConns(s.maxIdleConnections)
 	}
 	if s.maxOpenConnections > 0 {
 		db.SetMaxOpenConns(s.maxOpenConnections)
 	}
+	if s.connMaxLifetime > 0 {
+		db.SetConnMaxLifetime(s.connMaxLifetime)
+	}
+	if s.connMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(s.connMaxIdleTime)
+	}
 
-	s.db = db
-	s.userData = &UserDataStore{db: db}
-	s.tokens = &TokenStore{db: db}
+	return db, nil, nil
+}
 
-	return nil
+// closeDB closes db and the pgx pool behind it, if any. Closing a database
+// opened from a pool leaves the pool open.
+func closeDB(db *sqlx.DB, pool *pgxpool.Pool) error {
+	err := db.Close()
+	if pool != nil {
+		pool.Close()
+	}
+	return err
+}
+
+// Ping checks the primary. An unreachable replica is not an error, since
+// reads fall back to the primary.
+func (s *Store) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	return s.db.PingContext(ctx)
+}
+
+// SchemaMismatch returns the schema check failure tolerated by
+// AllowSchemaMismatch, or nil when the schema is compatible.
+func (s *Store) SchemaMismatch() error {
+	return s.schemaMismatch
 }
 
 func (s *Store) Close(ctx context.Context) error {
@@ -72,15 +314,28 @@ func (s *Store) Close(ctx context.Context) error {
 	ch := make(chan error, 1)
 
 	go func() {
-		ch <- s.db.Close()
+		var replicaErr error
+		if s.replica != nil {
+			replicaErr = closeDB(s.replica, s.replicaPool)
+		}
+		ch <- errors.Join(closeDB(s.db, s.pool), replicaErr)
 	}()
 
 	select {
 	case err := <-ch:
 		if err == nil {
 			s.db = nil
+			s.replica = nil
+			s.pool = nil
+			s.replicaPool = nil
 			s.userData = &UserDataStore{}
 			s.tokens = &TokenStore{}
+			s.audit = &AuditStore{}
+			s.settings = &SettingsStore{}
+			s.privacyActions = &PrivacyActionStore{}
+			s.legalHolds = &LegalHoldStore{}
+			s.inventory = &InventoryStore{}
+			s.tombstones = &AccountTombstoneStore{}
 		}
 		return err
 
@@ -92,3 +347,16 @@ func (s *Store) Close(ctx context.Context) error {
 func (s *Store) Tokens() store.TokenStore {
 	return s.tokens
 }
+
+// withApplicationName returns a lib/pq DSN equivalent to dsn with
+// application_name set to ApplicationName, overriding any name dsn sets.
+func withApplicationName(dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		opts, err := pq.ParseURL(dsn)
+		if err != nil {
+			return "", fmt.Errorf("parse connection string: %w", err)
+		}
+		dsn = opts
+	}
+	return strings.TrimSpace(dsn + " application_name=" + ApplicationName), nil
+}
@@ -0,0 +1,296 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/store/engine/postgres"
+	"hourly/workers/reporter/internal/store/storetest"
+)
+
+type fixtures struct {
+	db *sqlx.DB
+}
+
+func (f fixtures) CreateProfile(t *testing.T, p storetest.Profile) {
+	updatedAt := p.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+
+	if _, err := f.db.Exec(
+		`INSERT INTO profiles (id, provider, updated_at, reported_at, deleted_at) VALUES ($1, $2, $3, $4, $5)`,
+		p.ID, p.Provider, updatedAt, p.ReportedAt, p.DeletedAt,
+	); err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+}
+
+func (f fixtures) CreateToken(t *testing.T, token store.Token) {
+	var refresh *string
+	if token.RefreshToken != "" {
+		refresh = &token.RefreshToken
+	}
+
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	if _, err := f.db.Exec(
+		`INSERT INTO tokens (profile_id, provider, access_token, refresh_token, expires_at, scopes) VALUES ($1, $2, $3, $4, $5, $6)`,
+		token.ProfileID, token.Provider, token.AccessToken, refresh, token.ExpiresAt, pq.StringArray(scopes),
+	); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+}
+
+func (f fixtures) CreateSession(t *testing.T, s storetest.Session) {
+	data, err := json.Marshal(sessionData(s.Data))
+	if err != nil {
+		t.Fatalf("encode session data: %v", err)
+	}
+
+	if _, err := f.db.Exec(
+		`INSERT INTO sessions (id, data, updated_at) VALUES ($1, $2::jsonb, $3)`,
+		s.ID, string(data), s.UpdatedAt,
+	); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	for _, p := range s.Profiles {
+		if _, err := f.db.Exec(
+			`INSERT INTO profiles_on_sessions (profile_id, profile_provider, session_id, connection_type) VALUES ($1, $2, $3, 'data-source')`,
+			p.ID, p.Provider, s.ID,
+		); err != nil {
+			t.Fatalf("link session: %v", err)
+		}
+	}
+}
+
+func (f fixtures) LoadSession(t *testing.T, id string) *storetest.Session {
+	var data string
+	if err := f.db.Get(&data, `SELECT data FROM sessions WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		t.Fatalf("load session: %v", err)
+	}
+
+	session := &storetest.Session{ID: id}
+	if err := json.Unmarshal([]byte(data), &session.Data); err != nil {
+		t.Fatalf("decode session data: %v", err)
+	}
+
+	var links []struct {
+		ProfileID string `db:"profile_id"`
+		Provider  string `db:"profile_provider"`
+	}
+	if err := f.db.Select(&links, `SELECT profile_id, profile_provider FROM profiles_on_sessions WHERE session_id = $1 ORDER BY profile_provider, profile_id`, id); err != nil {
+		t.Fatalf("load session links: %v", err)
+	}
+	for _, link := range links {
+		session.Profiles = append(session.Profiles, storetest.Profile{ID: link.ProfileID, Provider: link.Provider})
+	}
+
+	return session
+}
+
+func sessionData(data map[string]any) map[string]any {
+	if data == nil {
+		return map[string]any{}
+	}
+	return data
+}
+
+func TestConformance(t *testing.T) {
+	for _, driver := range []postgres.Driver{postgres.DriverPQ, postgres.DriverPGX} {
+		t.Run(string(driver), func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) (store.Store, storetest.Fixtures) {
+				st, db := openStore(t, driver)
+				return st, fixtures{db: db}
+			})
+		})
+	}
+}
+
+// TestUpdateLastReportedCopy covers the pgx path that copies account IDs
+// into a temporary table, which the conformance suite's batches are too
+// small to reach.
+func TestUpdateLastReportedCopy(t *testing.T) {
+	ctx := context.Background()
+	st, db := openStore(t, postgres.DriverPGX)
+	f := fixtures{db: db}
+
+	f.CreateProfile(t, storetest.Profile{ID: "copy-1", Provider: store.ProviderAtlassian})
+	f.CreateProfile(t, storetest.Profile{ID: "copy-2", Provider: store.ProviderAtlassian})
+	f.CreateProfile(t, storetest.Profile{ID: "copy-3", Provider: store.ProviderAtlassian})
+
+	ids := []string{"copy-1", "copy-2", "copy-1"}
+	for i := range 10000 {
+		ids = append(ids, fmt.Sprintf("missing-%d", i))
+	}
+
+	reportedAt := time.Now().UTC().Truncate(time.Microsecond)
+	out, err := st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: ids,
+		ReportedAt: reportedAt,
+	})
+	if err != nil {
+		t.Fatalf("UpdateLastReported: %v", err)
+	}
+	if want := []string{"copy-1", "copy-2"}; !slices.Equal(out.Updated, want) {
+		t.Fatalf("updated = %v, want %v", out.Updated, want)
+	}
+	if len(out.Missed) != 10000 {
+		t.Fatalf("missed %d accounts, want 10000", len(out.Missed))
+	}
+
+	for _, table := range []struct{ name, query string }{
+		{"profiles", `SELECT id FROM profiles WHERE reported_at = $1 ORDER BY id`},
+		{"personal_data_inventory", `SELECT account_id FROM personal_data_inventory WHERE reported_at = $1 ORDER BY account_id`},
+	} {
+		var reported []string
+		if err := db.Select(&reported, table.query, reportedAt); err != nil {
+			t.Fatalf("load %s: %v", table.name, err)
+		}
+		if want := []string{"copy-1", "copy-2"}; !slices.Equal(reported, want) {
+			t.Fatalf("reported %s = %v, want %v", table.name, reported, want)
+		}
+	}
+}
+
+// TestWebTokenEvents covers the tokens trigger, which records mutations made
+// outside the worker, such as the web app rotating a token, and leaves the
+// worker's own mutations to the store.
+func TestWebTokenEvents(t *testing.T) {
+	ctx := context.Background()
+	st, db := openStore(t, postgres.DriverPQ)
+	f := fixtures{db: db}
+
+	f.CreateProfile(t, storetest.Profile{ID: "web", Provider: store.ProviderAtlassian})
+	f.CreateToken(t, store.Token{ProfileID: "web", Provider: store.ProviderAtlassian, AccessToken: "a", RefreshToken: "r1", Scopes: []string{"read"}})
+
+	// The fixtures connection has no application_name, as the web app's.
+	if _, err := db.Exec(
+		`UPDATE tokens SET access_token = 'b', refresh_token = 'r2', scopes = '{read,write}' WHERE profile_id = 'web'`,
+	); err != nil {
+		t.Fatalf("rotate token: %v", err)
+	}
+
+	if err := st.Tokens().UpdateToken(ctx, &store.UpdateTokenInput{
+		ProfileID:    "web",
+		Provider:     store.ProviderAtlassian,
+		AccessToken:  "c",
+		RefreshToken: "r2",
+		Scopes:       []string{"read", "write"},
+	}); err != nil {
+		t.Fatalf("UpdateToken: %v", err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM tokens WHERE profile_id = 'web'`); err != nil {
+		t.Fatalf("delete token: %v", err)
+	}
+
+	history, err := st.Tokens().GetTokenHistory(ctx, &store.GetTokenHistoryInput{ProfileID: "web", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetTokenHistory: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("history = %+v, want web rotation, worker refresh and web deletion", history)
+	}
+
+	// History is newest first.
+	deleted, refreshed, rotated := history[0], history[1], history[2]
+	if rotated.Type != store.TokenEventRotated || rotated.Actor != store.TokenActorWeb || !slices.Equal(rotated.ScopesAdded, []string{"write"}) {
+		t.Fatalf("web rotation = %+v", rotated)
+	}
+	if refreshed.Type != store.TokenEventRefreshed || refreshed.Actor != store.TokenActorWorker {
+		t.Fatalf("worker refresh = %+v", refreshed)
+	}
+	if deleted.Type != store.TokenEventDeleted || deleted.Actor != store.TokenActorWeb || !slices.Equal(deleted.ScopesRemoved, []string{"read", "write"}) {
+		t.Fatalf("web deletion = %+v", deleted)
+	}
+}
+
+// openStore opens a store with the given driver on a freshly migrated schema,
+// along with a plain connection to it for fixtures.
+func openStore(t *testing.T, driver postgres.Driver) (*postgres.Store, *sqlx.DB) {
+	t.Helper()
+	ctx := context.Background()
+
+	dsn := testDSN(t)
+	schema, schemaDSN := newSchema(t, dsn)
+	migrateSchema(t, schemaDSN, schema, webMigrations(t))
+
+	db, err := sqlx.Connect("postgres", schemaDSN)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	st, err := postgres.New(postgres.Options{
+		Connection:   schemaDSN,
+		Driver:       driver,
+		TombstoneKey: storetest.TombstoneKey,
+		Policies:     storetest.Policies(),
+	})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := st.Open(ctx); err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close(ctx) })
+
+	return st, db
+}
+
+func TestSchemaCheck(t *testing.T) {
+	dsn := testDSN(t)
+	migrations := webMigrations(t)
+	ctx := context.Background()
+
+	schema, schemaDSN := newSchema(t, dsn)
+	migrateSchema(t, schemaDSN, schema, migrations[:len(migrations)-1])
+
+	missing := migrations[len(migrations)-1].version
+
+	st, err := postgres.New(postgres.Options{Connection: schemaDSN, TombstoneKey: storetest.TombstoneKey})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	err = st.Open(ctx)
+	var mismatch *postgres.SchemaMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Open = %v, want schema mismatch", err)
+	}
+	if !slices.Contains(mismatch.MissingMigrations, missing) {
+		t.Fatalf("missing migrations = %v, want %s", mismatch.MissingMigrations, missing)
+	}
+
+	tolerant, err := postgres.New(postgres.Options{Connection: schemaDSN, TombstoneKey: storetest.TombstoneKey, AllowSchemaMismatch: true})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := tolerant.Open(ctx); err != nil {
+		t.Fatalf("Open with AllowSchemaMismatch: %v", err)
+	}
+	t.Cleanup(func() { tolerant.Close(ctx) })
+
+	if tolerant.SchemaMismatch() == nil {
+		t.Fatal("SchemaMismatch = nil, want the tolerated mismatch")
+	}
+}
@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultPrivacyActionsPage = 500
+)
+
+type PrivacyActionStore struct {
+	db    *sqlx.DB
+	reads *readRouter
+}
+
+const insertPrivacyActionQuery = `
+INSERT INTO privacy_actions (
+	account_id,
+	provider,
+	status,
+	received_at,
+	workflow_id,
+	run_id,
+	action,
+	completed_at,
+	items,
+	error
+) VALUES (
+	$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (run_id, provider, account_id, status) DO NOTHING`
+
+const privacyActionColumns = `
+	id,
+	account_id,
+	provider,
+	status,
+	received_at,
+	workflow_id,
+	run_id,
+	action,
+	completed_at,
+	items,
+	error,
+	created_at`
+
+const selectAccountPrivacyActionsQuery = `
+SELECT` + privacyActionColumns + `
+FROM
+	privacy_actions
+WHERE
+	account_id = $1
+	AND provider = $2
+ORDER BY
+	received_at DESC,
+	id
+LIMIT $3`
+
+const listPrivacyActionsQuery = `
+SELECT` + privacyActionColumns + `
+FROM
+	privacy_actions
+WHERE
+	received_at >= $1
+	AND received_at < $2
+ORDER BY
+	received_at,
+	id
+LIMIT $3 OFFSET $4`
+
+type privacyActionRow struct {
+	ID          string         `db:"id"`
+	AccountID   string         `db:"account_id"`
+	Provider    string         `db:"provider"`
+	Status      string         `db:"status"`
+	ReceivedAt  time.Time      `db:"received_at"`
+	WorkflowID  string         `db:"workflow_id"`
+	RunID       string         `db:"run_id"`
+	Action      string         `db:"action"`
+	CompletedAt sql.NullTime   `db:"completed_at"`
+	Items       string         `db:"items"`
+	Error       sql.NullString `db:"error"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+func (s *Store) PrivacyActions() store.PrivacyActionStore {
+	return s.privacyActions
+}
+
+func (s *PrivacyActionStore) RecordPrivacyAction(ctx context.Context, action *store.PrivacyAction) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if action == nil || action.AccountID == "" || action.Provider == "" || action.Status == "" || action.RunID == "" || action.Action == "" {
+		return fmt.Errorf("account id, provider, status, run id, and action are required")
+	}
+
+	items := action.Items
+	if items == nil {
+		items = map[string]int{}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("marshal privacy action items: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertPrivacyActionQuery,
+		action.AccountID,
+		action.Provider,
+		action.Status,
+		action.ReceivedAt,
+		action.WorkflowID,
+		action.RunID,
+		action.Action,
+		nullTime(action.CompletedAt),
+		string(data),
+		sql.NullString{String: action.Error, Valid: action.Error != ""},
+	); err != nil {
+		return fmt.Errorf("record privacy action: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PrivacyActionStore) GetAccountPrivacyActions(ctx context.Context, input *store.GetAccountPrivacyActionsInput) ([]store.PrivacyAction, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []privacyActionRow
+	if err := s.reads.reader(ctx).SelectContext(ctx, &rows, selectAccountPrivacyActionsQuery, input.AccountID, input.Provider, limit); err != nil {
+		return nil, fmt.Errorf("get account privacy actions: %w", err)
+	}
+
+	return privacyActions(rows)
+}
+
+func (s *PrivacyActionStore) ListPrivacyActions(ctx context.Context, input *store.ListPrivacyActionsInput) (*store.ListPrivacyActionsOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ReceivedFrom.IsZero() || input.ReceivedTo.IsZero() {
+		return nil, fmt.Errorf("received from and received to are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []privacyActionRow
+
+	// Fetch one extra row to learn whether another page exists without counting.
+	if err := s.reads.reader(ctx).SelectContext(ctx, &rows, listPrivacyActionsQuery, input.ReceivedFrom, input.ReceivedTo, limit+1, max(input.Offset, 0)); err != nil {
+		return nil, fmt.Errorf("list privacy actions: %w", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	actions, err := privacyActions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.ListPrivacyActionsOutput{
+		Actions: actions,
+		HasMore: hasMore,
+	}, nil
+}
+
+func privacyActions(rows []privacyActionRow) ([]store.PrivacyAction, error) {
+	actions := make([]store.PrivacyAction, 0, len(rows))
+	for _, row := range rows {
+		var items map[string]int
+		if err := json.Unmarshal([]byte(row.Items), &items); err != nil {
+			return nil, fmt.Errorf("decode privacy action items: %w", err)
+		}
+
+		actions = append(actions, store.PrivacyAction{
+			ID:          row.ID,
+			AccountID:   row.AccountID,
+			Provider:    row.Provider,
+			Status:      row.Status,
+			ReceivedAt:  row.ReceivedAt,
+			WorkflowID:  row.WorkflowID,
+			RunID:       row.RunID,
+			Action:      store.PrivacyActionType(row.Action),
+			CompletedAt: timePtr(row.CompletedAt),
+			Items:       items,
+			Error:       row.Error.String,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultMaxReplicaLag = 30 * time.Second
+
+	// replicaCheckInterval is how long a lag measurement is trusted before the
+	// replica is probed again.
+	replicaCheckInterval = 5 * time.Second
+
+	// replicaProbeTimeout bounds a lag probe, so that an unresponsive replica
+	// only delays the read that happens to probe it.
+	replicaProbeTimeout = 2 * time.Second
+)
+
+// replicaLagQuery reports replay lag in seconds. An idle primary produces no
+// new WAL, so a replica that has replayed everything it received counts as
+// current regardless of the last replay timestamp.
+const replicaLagQuery = `
+SELECT
+	CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// readRouter sends read-only queries to a replica while its lag stays under
+// maxLag and falls back to the primary otherwise.
+type readRouter struct {
+	primary *sqlx.DB
+	replica *sqlx.DB
+	maxLag  time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	current   bool
+	probing   bool
+}
+
+func newReadRouter(primary, replica *sqlx.DB, maxLag time.Duration) *readRouter {
+	if maxLag <= 0 {
+		maxLag = defaultMaxReplicaLag
+	}
+
+	return &readRouter{
+		primary: primary,
+		replica: replica,
+		maxLag:  maxLag,
+	}
+}
+
+// reader returns the connection to use for a read that tolerates replica lag.
+func (r *readRouter) reader(ctx context.Context) *sqlx.DB {
+	if r.replica == nil {
+		return r.primary
+	}
+
+	r.mu.Lock()
+	current := r.current
+	stale := time.Since(r.checkedAt) >= replicaCheckInterval && !r.probing
+	if stale {
+		r.probing = true
+	}
+	r.mu.Unlock()
+
+	// One caller probes, without holding the lock; concurrent reads keep
+	// using the previous measurement until it completes.
+	if stale {
+		current = r.probe(ctx)
+
+		r.mu.Lock()
+		r.current = current
+		r.checkedAt = time.Now()
+		r.probing = false
+		r.mu.Unlock()
+	}
+
+	if current {
+		return r.replica
+	}
+	return r.primary
+}
+
+func (r *readRouter) probe(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, replicaProbeTimeout)
+	defer cancel()
+
+	var lagSeconds float64
+	if err := r.replica.GetContext(ctx, &lagSeconds, replicaLagQuery); err != nil {
+		return false
+	}
+	return time.Duration(lagSeconds*float64(time.Second)) <= r.maxLag
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// requiredMigrations lists the web app migrations (packages/web/db/migrations)
+// whose schema the worker's queries depend on.
+var requiredMigrations = []string{
+	"20251015000001", // create-profiles
+	"20251015000002", // create-sessions
+	"20251015000003", // create-tokens
+	"20251015000004", // create-profiles_on_sessions
+	"20260101131023", // add-reported_at
+	"20260101140000", // add-reporting-indices
+	"20260101142000", // add-profile-soft-delete
+	"20260121000001", // create-audit-logs
+	"20261018000001", // create-token-health-reports
+	"20261018000002", // create-token-events
+	"20261018000003", // create-profile-tombstones
+	"20261018000004", // notify-profile-created
+	"20261018000005", // create-worker-settings
+	"20261018000006", // create-privacy-actions
+	"20261018000007", // create-legal-holds
+	"20261018000008", // create-personal-data-inventory
+	"20261018000009", // create-account-tombstones
+	"20261018000010", // record-token-events
+}
+
+// requiredColumns lists every column the worker reads or writes, per table.
+var requiredColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"profiles", []string{"id", "provider", "reported_at", "deleted_at", "created_at", "updated_at"}},
+	{"sessions", []string{"id", "data", "updated_at"}},
+	{"tokens", []string{"profile_id", "provider", "access_token", "refresh_token", "expires_at", "scopes", "updated_at"}},
+	{"profiles_on_sessions", []string{"profile_id", "profile_provider", "session_id"}},
+	{"audit_logs", []string{"id", "action_type", "action_description", "severity", "target_resource_type", "target_resource_id", "outcome", "correlation_id", "request_id", "request_path", "request_method", "metadata"}},
+	{"token_health_reports", []string{"run_id", "total", "healthy", "expiring_soon", "expired", "scopes_drifted", "profile_deleted", "created_at"}},
+	{"token_events", []string{"id", "profile_id", "provider", "event_type", "actor", "old_expires_at", "new_expires_at", "scopes_added", "scopes_removed", "error_class", "created_at"}},
+	{"profile_tombstones", []string{"provider", "id_hash", "deleted_at", "purged_at"}},
+	{"worker_settings", []string{"key", "value", "changed_at"}},
+	{"privacy_actions", []string{"id", "account_id", "provider", "status", "received_at", "workflow_id", "run_id", "action", "completed_at", "items", "error", "created_at"}},
+	{"legal_holds", []string{"id", "account_id", "provider", "reason", "placed_by", "placed_at", "expires_at", "released_at", "erasure_deferred_at", "erasure_resolved_at"}},
+	{"personal_data_inventory", []string{"provider", "account_id", "stores", "data_retrieved_at", "reported_at", "created_at", "updated_at"}},
+	{"account_tombstones", []string{"provider", "id_hash", "closed_at"}},
+}
+
+const selectAppliedMigrationsQuery = `
+SELECT
+	version
+FROM
+	schema_migrations
+WHERE
+	version = ANY($1)`
+
+const selectColumnsQuery = `
+SELECT
+	table_name || '.' || column_name
+FROM
+	information_schema.columns
+WHERE
+	table_schema = current_schema()
+	AND table_name = ANY($1)`
+
+const schemaMigrationsExistsQuery = `
+SELECT
+	to_regclass('schema_migrations') IS NOT NULL`
+
+// SchemaMismatchError describes how the database schema falls short of what
+// the worker requires.
+type SchemaMismatchError struct {
+	MissingMigrations []string
+	MissingColumns    []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	var b strings.Builder
+	b.WriteString("database schema is not compatible with this worker; apply the web app migrations first")
+	if len(e.MissingMigrations) > 0 {
+		b.WriteString("\n  missing migrations: ")
+		b.WriteString(strings.Join(e.MissingMigrations, ", "))
+	}
+	if len(e.MissingColumns) > 0 {
+		b.WriteString("\n  missing columns: ")
+		b.WriteString(strings.Join(e.MissingColumns, ", "))
+	}
+	return b.String()
+}
+
+// checkSchema compares dbmate's schema_migrations and information_schema with
+// the declared requirements. It returns a *SchemaMismatchError on a mismatch.
+func checkSchema(ctx context.Context, db *sqlx.DB) error {
+	mismatch := &SchemaMismatchError{}
+
+	var hasMigrations bool
+	if err := db.GetContext(ctx, &hasMigrations, schemaMigrationsExistsQuery); err != nil {
+		return fmt.Errorf("check schema_migrations: %w", err)
+	}
+
+	if hasMigrations {
+		var applied []string
+		if err := db.SelectContext(ctx, &applied, selectAppliedMigrationsQuery, pq.Array(requiredMigrations)); err != nil {
+			return fmt.Errorf("list applied migrations: %w", err)
+		}
+		for _, version := range requiredMigrations {
+			if !slices.Contains(applied, version) {
+				mismatch.MissingMigrations = append(mismatch.MissingMigrations, version)
+			}
+		}
+	} else {
+		mismatch.MissingMigrations = slices.Clone(requiredMigrations)
+	}
+
+	tables := make([]string, 0, len(requiredColumns))
+	for _, t := range requiredColumns {
+		tables = append(tables, t.table)
+	}
+
+	var present []string
+	if err := db.SelectContext(ctx, &present, selectColumnsQuery, pq.Array(tables)); err != nil {
+		return fmt.Errorf("list columns: %w", err)
+	}
+
+	for _, t := range requiredColumns {
+		for _, column := range t.columns {
+			name := t.table + "." + column
+			if !slices.Contains(present, name) {
+				mismatch.MissingColumns = append(mismatch.MissingColumns, name)
+			}
+		}
+	}
+
+	if len(mismatch.MissingMigrations) > 0 || len(mismatch.MissingColumns) > 0 {
+		return mismatch
+	}
+
+	return nil
+}
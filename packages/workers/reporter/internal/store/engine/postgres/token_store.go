@@ -13,7 +13,8 @@ import (
 )
 
 type TokenStore struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	reads *readRouter
 }
 
 const getTokenQuery = `
@@ -30,6 +31,37 @@ WHERE
 	AND t.provider = $2
 	AND p.deleted_at IS NULL`
 
+const listLinkedTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	t.access_token,
+	t.refresh_token,
+	t.expires_at,
+	t.scopes
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	p.deleted_at IS NULL
+	AND (
+		(t.profile_id = $1 AND t.provider = $2)
+		OR (t.profile_id, t.provider) IN (
+			SELECT
+				l.profile_id,
+				l.profile_provider
+			FROM
+				profiles_on_sessions own
+				JOIN profiles_on_sessions l ON l.session_id = own.session_id
+			WHERE
+				own.profile_id = $1
+				AND own.profile_provider = $2
+		)
+	)
+ORDER BY
+	t.provider,
+	t.profile_id`
+
 const getRefreshableTokenQuery = `
 SELECT
 	t.access_token,
@@ -59,6 +91,166 @@ WHERE
 	profile_id = $5
 	AND provider = $6`
 
+const lockTokenQuery = `
+SELECT
+	refresh_token,
+	expires_at,
+	scopes
+FROM
+	tokens
+WHERE
+	profile_id = $1
+	AND provider = $2
+FOR UPDATE`
+
+const lockActiveTokenQuery = `
+SELECT
+	t.access_token,
+	t.refresh_token,
+	t.expires_at,
+	t.scopes
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	t.profile_id = $1
+	AND t.provider = $2
+	AND p.deleted_at IS NULL
+FOR UPDATE OF t`
+
+const insertTokenEventQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	new_expires_at,
+	scopes_added,
+	scopes_removed,
+	error_class
+) VALUES (
+	$1, $2, $3, $4, $5, $6, $7, $8, $9
+)`
+
+const selectTokenHistoryQuery = `
+SELECT
+	id,
+	event_type,
+	actor,
+	old_expires_at,
+	new_expires_at,
+	scopes_added,
+	scopes_removed,
+	error_class,
+	created_at
+FROM
+	token_events
+WHERE
+	profile_id = $1
+	AND provider = $2
+ORDER BY
+	created_at DESC,
+	id
+LIMIT $3`
+
+const listDormantTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	COALESCE(MAX(s.updated_at), t.updated_at) AS last_active_at
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+	LEFT JOIN profiles_on_sessions pos ON pos.profile_id = t.profile_id AND pos.profile_provider = t.provider
+	LEFT JOIN sessions s ON s.id = pos.session_id
+WHERE
+	p.deleted_at IS NULL
+	AND NOT (t.profile_id = ANY($2))
+GROUP BY
+	t.profile_id,
+	t.provider,
+	t.updated_at
+HAVING
+	COALESCE(MAX(s.updated_at), t.updated_at) < $1
+	AND (
+		$4::timestamptz IS NULL
+		OR (COALESCE(MAX(s.updated_at), t.updated_at), t.provider, t.profile_id) > ($4, $5, $6)
+	)
+ORDER BY
+	last_active_at,
+	t.provider,
+	t.profile_id
+LIMIT $3`
+
+const recordDeletedTokenQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	scopes_removed
+)
+SELECT
+	profile_id,
+	provider,
+	'deleted',
+	$3,
+	expires_at,
+	scopes
+FROM
+	tokens
+WHERE
+	profile_id = $1
+	AND provider = $2`
+
+const deleteTokenQuery = `
+DELETE FROM
+	tokens
+WHERE
+	profile_id = $1
+	AND provider = $2`
+
+const listTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	t.refresh_token IS NOT NULL AND t.refresh_token <> '' AS has_refresh_token,
+	t.expires_at,
+	t.scopes,
+	t.updated_at,
+	p.deleted_at AS profile_deleted_at
+FROM
+	tokens t
+	LEFT JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	$2::text IS NULL
+	OR (t.provider, t.profile_id) > ($2, $3)
+ORDER BY
+	t.provider,
+	t.profile_id
+LIMIT $1`
+
+const insertTokenHealthReportQuery = `
+INSERT INTO token_health_reports (
+	run_id,
+	total,
+	healthy,
+	expiring_soon,
+	expired,
+	scopes_drifted,
+	profile_deleted,
+	created_at
+) VALUES (
+	$1, $2, $3, $4, $5, $6, $7, $8
+)`
+
+const (
+	defaultTokensPage       = 500
+	defaultTokenHistoryPage = 100
+)
+
 func (s *TokenStore) GetToken(ctx context.Context, input *store.GetTokenInput) (*store.Token, error) {
 	return s.fetchToken(ctx, getTokenQuery, input)
 }
@@ -106,6 +298,48 @@ func (s *TokenStore) fetchToken(ctx context.Context, query string, input *store.
 	}, nil
 }
 
+func (s *TokenStore) ListLinkedTokens(ctx context.Context, input *store.GetTokenInput) ([]store.Token, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	var rows []struct {
+		ProfileID    string         `db:"profile_id"`
+		Provider     string         `db:"provider"`
+		AccessToken  string         `db:"access_token"`
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullTime   `db:"expires_at"`
+		Scopes       pq.StringArray `db:"scopes"`
+	}
+
+	if err := s.db.SelectContext(ctx, &rows, listLinkedTokensQuery, input.ProfileID, input.Provider); err != nil {
+		return nil, fmt.Errorf("list linked tokens: %w", err)
+	}
+
+	tokens := make([]store.Token, 0, len(rows))
+	for _, row := range rows {
+		var expires *time.Time
+		if row.ExpiresAt.Valid {
+			expires = &row.ExpiresAt.Time
+		}
+
+		tokens = append(tokens, store.Token{
+			ProfileID:    row.ProfileID,
+			Provider:     row.Provider,
+			AccessToken:  row.AccessToken,
+			RefreshToken: row.RefreshToken.String,
+			ExpiresAt:    expires,
+			Scopes:       row.Scopes,
+		})
+	}
+
+	return tokens, nil
+}
+
 func (s *TokenStore) UpdateToken(ctx context.Context, input *store.UpdateTokenInput) error {
 	if s.db == nil {
 		return fmt.Errorf("store not opened")
@@ -119,13 +353,123 @@ func (s *TokenStore) UpdateToken(ctx context.Context, input *store.UpdateTokenIn
 		return fmt.Errorf("profile id, provider, and access token are required")
 	}
 
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateToken(ctx, tx, input); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit token update: %w", err)
+	}
+
+	return nil
+}
+
+// RefreshToken holds the token row lock while the exchange runs, so that
+// other replicas refreshing the token, and the web app writing it on sign-in,
+// wait until the exchanged values are stored.
+func (s *TokenStore) RefreshToken(ctx context.Context, input *store.RefreshTokenInput) (*store.Token, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" || input.Exchange == nil {
+		return nil, fmt.Errorf("profile id, provider, and exchange are required")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var row struct {
+		AccessToken  string         `db:"access_token"`
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullTime   `db:"expires_at"`
+		Scopes       pq.StringArray `db:"scopes"`
+	}
+
+	if err := tx.GetContext(ctx, &row, lockActiveTokenQuery, input.ProfileID, input.Provider); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("lock token: %w", err)
+	}
+
+	token := &store.Token{
+		ProfileID:    input.ProfileID,
+		Provider:     input.Provider,
+		AccessToken:  row.AccessToken,
+		RefreshToken: row.RefreshToken.String,
+		ExpiresAt:    timePtr(row.ExpiresAt),
+		Scopes:       row.Scopes,
+	}
+
+	update, err := input.Exchange(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if update == nil {
+		return token, nil
+	}
+
+	update.ProfileID = input.ProfileID
+	update.Provider = input.Provider
+	if update.AccessToken == "" {
+		return nil, fmt.Errorf("access token is required")
+	}
+
+	if err := updateToken(ctx, tx, update); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit token update: %w", err)
+	}
+
+	return &store.Token{
+		ProfileID:    update.ProfileID,
+		Provider:     update.Provider,
+		AccessToken:  update.AccessToken,
+		RefreshToken: update.RefreshToken,
+		ExpiresAt:    update.ExpiresAt,
+		Scopes:       update.Scopes,
+	}, nil
+}
+
+// updateToken replaces the token values and records the token event within tx.
+func updateToken(ctx context.Context, tx *sqlx.Tx, input *store.UpdateTokenInput) error {
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
 	refresh := sql.NullString{String: input.RefreshToken, Valid: input.RefreshToken != ""}
 	var expires sql.NullTime
 	if input.ExpiresAt != nil {
 		expires = sql.NullTime{Time: input.ExpiresAt.UTC(), Valid: true}
 	}
 
-	result, err := s.db.ExecContext(
+	var prev struct {
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullTime   `db:"expires_at"`
+		Scopes       pq.StringArray `db:"scopes"`
+	}
+
+	if err := tx.GetContext(ctx, &prev, lockTokenQuery, input.ProfileID, input.Provider); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("token not found for profile %s and provider %s", input.ProfileID, input.Provider)
+		}
+		return fmt.Errorf("lock token: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
 		ctx,
 		updateTokenQuery,
 		input.AccessToken,
@@ -134,14 +478,314 @@ func (s *TokenStore) UpdateToken(ctx context.Context, input *store.UpdateTokenIn
 		pq.StringArray(input.Scopes),
 		input.ProfileID,
 		input.Provider,
+	); err != nil {
+		return fmt.Errorf("update token: %w", err)
+	}
+
+	eventType := store.TokenEventRefreshed
+	if prev.RefreshToken != refresh {
+		eventType = store.TokenEventRotated
+	}
+
+	added, removed := store.DiffScopes(prev.Scopes, input.Scopes)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		insertTokenEventQuery,
+		input.ProfileID,
+		input.Provider,
+		eventType,
+		actor,
+		prev.ExpiresAt,
+		expires,
+		pq.StringArray(added),
+		pq.StringArray(removed),
+		sql.NullString{},
+	); err != nil {
+		return fmt.Errorf("record token event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) RecordTokenEvent(ctx context.Context, event *store.TokenEvent) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if event == nil || event.ProfileID == "" || event.Provider == "" || event.Type == "" {
+		return fmt.Errorf("profile id, provider, and event type are required")
+	}
+
+	actor := event.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertTokenEventQuery,
+		event.ProfileID,
+		event.Provider,
+		event.Type,
+		actor,
+		nullTime(event.OldExpiresAt),
+		nullTime(event.NewExpiresAt),
+		pq.StringArray(event.ScopesAdded),
+		pq.StringArray(event.ScopesRemoved),
+		sql.NullString{String: event.ErrorClass, Valid: event.ErrorClass != ""},
+	); err != nil {
+		return fmt.Errorf("record token event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) GetTokenHistory(ctx context.Context, input *store.GetTokenHistoryInput) ([]store.TokenEvent, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	limit := defaultTokenHistoryPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []struct {
+		ID            string         `db:"id"`
+		EventType     string         `db:"event_type"`
+		Actor         string         `db:"actor"`
+		OldExpiresAt  sql.NullTime   `db:"old_expires_at"`
+		NewExpiresAt  sql.NullTime   `db:"new_expires_at"`
+		ScopesAdded   pq.StringArray `db:"scopes_added"`
+		ScopesRemoved pq.StringArray `db:"scopes_removed"`
+		ErrorClass    sql.NullString `db:"error_class"`
+		CreatedAt     time.Time      `db:"created_at"`
+	}
+
+	if err := s.reads.reader(ctx).SelectContext(ctx, &rows, selectTokenHistoryQuery, input.ProfileID, input.Provider, limit); err != nil {
+		return nil, fmt.Errorf("get token history: %w", err)
+	}
+
+	events := make([]store.TokenEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, store.TokenEvent{
+			ID:            row.ID,
+			ProfileID:     input.ProfileID,
+			Provider:      input.Provider,
+			Type:          store.TokenEventType(row.EventType),
+			Actor:         row.Actor,
+			OldExpiresAt:  timePtr(row.OldExpiresAt),
+			NewExpiresAt:  timePtr(row.NewExpiresAt),
+			ScopesAdded:   row.ScopesAdded,
+			ScopesRemoved: row.ScopesRemoved,
+			ErrorClass:    row.ErrorClass.String,
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+
+	return events, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
+
+func (s *TokenStore) ListTokens(ctx context.Context, input *store.ListTokensInput) (*store.ListTokensOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	limit := defaultTokensPage
+	var afterProvider, afterID *string
+
+	if input != nil {
+		if input.Limit > 0 {
+			limit = input.Limit
+		}
+		if input.After != nil {
+			afterProvider, afterID = &input.After.Provider, &input.After.ProfileID
+		}
+	}
+
+	var rows []struct {
+		ProfileID        string         `db:"profile_id"`
+		Provider         string         `db:"provider"`
+		HasRefreshToken  bool           `db:"has_refresh_token"`
+		ExpiresAt        sql.NullTime   `db:"expires_at"`
+		Scopes           pq.StringArray `db:"scopes"`
+		UpdatedAt        time.Time      `db:"updated_at"`
+		ProfileDeletedAt sql.NullTime   `db:"profile_deleted_at"`
+	}
+
+	// Fetch one extra row to learn whether another page exists without counting.
+	if err := s.reads.reader(ctx).SelectContext(ctx, &rows, listTokensQuery, limit+1, afterProvider, afterID); err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	tokens := make([]store.TokenInfo, 0, len(rows))
+	for _, row := range rows {
+		info := store.TokenInfo{
+			ProfileID:       row.ProfileID,
+			Provider:        row.Provider,
+			HasRefreshToken: row.HasRefreshToken,
+			Scopes:          row.Scopes,
+			UpdatedAt:       row.UpdatedAt,
+		}
+		if row.ExpiresAt.Valid {
+			expires := row.ExpiresAt.Time
+			info.ExpiresAt = &expires
+		}
+		if row.ProfileDeletedAt.Valid {
+			deleted := row.ProfileDeletedAt.Time
+			info.ProfileDeletedAt = &deleted
+		}
+		tokens = append(tokens, info)
+	}
+
+	return &store.ListTokensOutput{
+		Tokens:  tokens,
+		HasMore: hasMore,
+	}, nil
+}
+
+func (s *TokenStore) ListDormantTokens(ctx context.Context, input *store.ListDormantTokensInput) ([]store.DormantToken, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.InactiveSince.IsZero() {
+		return nil, fmt.Errorf("inactive since is required")
+	}
+
+	limit := defaultTokensPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var (
+		afterAt                *time.Time
+		afterProvider, afterID string
 	)
+	if input.After != nil {
+		at := input.After.LastActiveAt.UTC()
+		afterAt, afterProvider, afterID = &at, input.After.Provider, input.After.ProfileID
+	}
+
+	var rows []struct {
+		ProfileID    string    `db:"profile_id"`
+		Provider     string    `db:"provider"`
+		LastActiveAt time.Time `db:"last_active_at"`
+	}
+
+	if err := s.db.SelectContext(
+		ctx,
+		&rows,
+		listDormantTokensQuery,
+		input.InactiveSince.UTC(),
+		pq.StringArray(input.ExcludeProfileIDs),
+		limit,
+		afterAt,
+		afterProvider,
+		afterID,
+	); err != nil {
+		return nil, fmt.Errorf("list dormant tokens: %w", err)
+	}
+
+	tokens := make([]store.DormantToken, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, store.DormantToken{
+			ProfileID:    row.ProfileID,
+			Provider:     row.Provider,
+			LastActiveAt: row.LastActiveAt,
+		})
+	}
+
+	return tokens, nil
+}
+
+func (s *TokenStore) DeleteToken(ctx context.Context, input *store.DeleteTokenInput) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return fmt.Errorf("profile id and provider are required")
+	}
+
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("update token: %w", err)
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, recordDeletedTokenQuery, input.ProfileID, input.Provider, actor); err != nil {
+		return fmt.Errorf("record token event: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err == nil && rows == 0 {
-		return fmt.Errorf("token not found for profile %s and provider %s", input.ProfileID, input.Provider)
+	if _, err := tx.ExecContext(ctx, deleteTokenQuery, input.ProfileID, input.Provider); err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit token deletion: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) CreateTokenHealthReport(ctx context.Context, report *store.TokenHealthReport) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if report == nil {
+		return fmt.Errorf("report is required")
+	}
+
+	createdAt := report.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertTokenHealthReportQuery,
+		report.RunID,
+		report.Total,
+		report.Healthy,
+		report.ExpiringSoon,
+		report.Expired,
+		report.ScopesDrifted,
+		report.ProfileDeleted,
+		createdAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("insert token health report: %w", err)
 	}
 
 	return nil
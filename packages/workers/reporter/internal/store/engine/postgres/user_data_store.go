@@ -2,23 +2,35 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 
-	"hourly/workers/reporter/internal/atlassian"
 	"hourly/workers/reporter/internal/domain"
 	"hourly/workers/reporter/internal/store"
 )
 
 type UserDataStore struct {
 	db *sqlx.DB
+	// pool is set with DriverPGX, whose bulk writes bypass database/sql.
+	pool     *pgxpool.Pool
+	reads    *readRouter
+	hasher   *store.TombstoneHasher
+	policies store.ProviderPolicies
 }
 
 const (
 	defaultAccountsPage = 1000
+	defaultPurgeBatch   = 100
+
+	// reportedChunkSize bounds the account IDs sent per UpdateLastReported
+	// statement.
+	reportedChunkSize = 1000
 )
 
 const (
@@ -26,33 +38,63 @@ const (
 SELECT
 	COUNT(*)
 FROM
-	profiles
+	personal_data_inventory
 WHERE
 	provider = $1
-	AND deleted_at IS NULL
 	AND (
 		reported_at IS NULL
 		OR reported_at <= $2
+	)
+	AND (
+		$3::text[] IS NULL
+		OR account_id = ANY($3)
 	)`
 
 	selectAccountsQuery = `
 SELECT
-	id AS account_id,
-	updated_at
+	account_id,
+	data_retrieved_at AS updated_at
 FROM
-	profiles
+	personal_data_inventory
 WHERE
 	provider = $1
-	AND deleted_at IS NULL
 	AND (
 		reported_at IS NULL
 		OR reported_at <= $2
 	)
+	AND (
+		$4::text[] IS NULL
+		OR account_id = ANY($4)
+	)
 ORDER BY
-	updated_at DESC,
-	id
-LIMIT $3
-OFFSET $4`
+	data_retrieved_at DESC,
+	account_id
+LIMIT $3`
+
+	selectAccountsAfterQuery = `
+SELECT
+	account_id,
+	data_retrieved_at AS updated_at
+FROM
+	personal_data_inventory
+WHERE
+	provider = $1
+	AND (
+		reported_at IS NULL
+		OR reported_at <= $2
+	)
+	AND (
+		data_retrieved_at < $4
+		OR (data_retrieved_at = $4 AND account_id > $5)
+	)
+	AND (
+		$6::text[] IS NULL
+		OR account_id = ANY($6)
+	)
+ORDER BY
+	data_retrieved_at DESC,
+	account_id
+LIMIT $3`
 
 	updateReportedAtQuery = `
 UPDATE
@@ -64,6 +106,39 @@ WHERE
 	AND id = ANY($3)
 	AND deleted_at IS NULL`
 
+	updateInventoryReportedAtQuery = `
+UPDATE
+	personal_data_inventory
+SET
+	reported_at = $1
+WHERE
+	provider = $2
+	AND account_id = ANY($3)
+RETURNING
+	account_id`
+
+	recordDeletedTokensQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	scopes_removed
+)
+SELECT
+	profile_id,
+	provider,
+	'deleted',
+	'worker',
+	expires_at,
+	scopes
+FROM
+	tokens
+WHERE
+	provider = $1
+	AND profile_id = $2`
+
 	deleteTokensQuery = `
 DELETE FROM
 	tokens
@@ -71,11 +146,63 @@ WHERE
 	provider = $1
 	AND profile_id = $2`
 
+	// pseudonymizeTokenEventsQuery moves the account's token history under its
+	// keyed tombstone hash ($3), so that it no longer names the account.
+	pseudonymizeTokenEventsQuery = `
+UPDATE
+	token_events
+SET
+	profile_id = $3
+WHERE
+	provider = $1
+	AND profile_id = $2`
+
+	deleteAccountTokenEventsQuery = `
+DELETE FROM
+	token_events
+WHERE
+	provider = $1
+	AND profile_id = $2`
+
+	deleteSessionLinksQuery = `
+DELETE FROM
+	profiles_on_sessions
+WHERE
+	profile_provider = $1
+	AND profile_id = $2
+RETURNING
+	session_id`
+
+	deleteOrphanedSessionsQuery = `
+DELETE FROM
+	sessions s
+WHERE
+	s.id = ANY($1)
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			profiles_on_sessions pos
+		WHERE
+			pos.session_id = s.id
+	)`
+
+	scrubSessionsQuery = `
+UPDATE
+	sessions
+SET
+	data = data #- ARRAY['user', 'oauth', $2::text],
+	updated_at = now()
+WHERE
+	id = ANY($1)
+	AND data #> ARRAY['user', 'oauth', $2::text] IS NOT NULL`
+
+	// softDeleteAccountQuery only stamps the profile: it has had no data
+	// column to clear since the 20260101173858 remove-profile-data migration.
 	softDeleteAccountQuery = `
 UPDATE
 	profiles
 SET
-	data = NULL,
 	reported_at = $3,
 	deleted_at = $3,
 	updated_at = now()
@@ -83,6 +210,55 @@ WHERE
 	provider = $2
 	AND id = $1`
 
+	selectPurgeableProfilesQuery = `
+SELECT
+	id,
+	provider,
+	deleted_at
+FROM
+	profiles
+WHERE
+	deleted_at < $1
+	AND (
+		$3 = ''
+		OR provider = $3
+	)
+ORDER BY
+	deleted_at,
+	provider,
+	id
+LIMIT $2
+FOR UPDATE SKIP LOCKED`
+
+	insertTombstoneQuery = `
+INSERT INTO profile_tombstones (
+	provider,
+	id_hash,
+	deleted_at
+) VALUES (
+	$1, $2, $3
+)
+ON CONFLICT (provider, id_hash) DO UPDATE SET
+	deleted_at = EXCLUDED.deleted_at,
+	purged_at = now()`
+
+	// deleteTokenEventsQuery deletes the profile's token history, whether still
+	// under its id ($2) or pseudonymized under its tombstone hash ($3).
+	deleteTokenEventsQuery = `
+DELETE FROM
+	token_events
+WHERE
+	provider = $1
+	AND profile_id IN ($2, $3)`
+
+	purgeProfileQuery = `
+DELETE FROM
+	profiles
+WHERE
+	provider = $1
+	AND id = $2
+	AND deleted_at IS NOT NULL`
+
 	refreshAccountQuery = `
 UPDATE
 	profiles
@@ -103,23 +279,45 @@ func (s *UserDataStore) GetAccountsToReport(ctx context.Context, input *store.Ge
 		return nil, fmt.Errorf("store not opened")
 	}
 
+	if input == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	policy, err := s.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := defaultAccountsPage
-	offset := 0
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
 
-	if input != nil {
-		if input.Limit > 0 {
-			limit = input.Limit
-		}
-		if input.Offset > 0 {
-			offset = input.Offset
-		}
+	after, err := store.DecodeAccountsCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cycle period is read from the primary so that a change made by the
+	// previous run is never missed because of replica lag.
+	period, err := getCyclePeriod(ctx, s.db, input.Provider, policy.CyclePeriodDays)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().UTC().Add(-period.Duration())
+
+	var accountIDs pq.StringArray
+	if input.AccountIDs != nil {
+		accountIDs = input.AccountIDs
 	}
 
-	cutoff := time.Now().UTC().Add(-time.Duration(atlassian.DefaultCyclePeriodDays) * 24 * time.Hour)
+	db := s.reads.reader(ctx)
 
 	var total int
-	if err := s.db.GetContext(ctx, &total, countAccountsQuery, store.ProviderAtlassian, cutoff); err != nil {
-		return nil, fmt.Errorf("count accounts to report: %w", err)
+	if input.IncludeCount {
+		if err := db.GetContext(ctx, &total, countAccountsQuery, input.Provider, cutoff, accountIDs); err != nil {
+			return nil, fmt.Errorf("count accounts to report: %w", err)
+		}
 	}
 
 	var rows []struct {
@@ -127,10 +325,21 @@ func (s *UserDataStore) GetAccountsToReport(ctx context.Context, input *store.Ge
 		UpdatedAt time.Time `db:"updated_at"`
 	}
 
-	if err := s.db.SelectContext(ctx, &rows, selectAccountsQuery, store.ProviderAtlassian, cutoff, limit, offset); err != nil {
+	// Fetch one extra row to learn whether another page exists.
+	if after == nil {
+		err = db.SelectContext(ctx, &rows, selectAccountsQuery, input.Provider, cutoff, limit+1, accountIDs)
+	} else {
+		err = db.SelectContext(ctx, &rows, selectAccountsAfterQuery, input.Provider, cutoff, limit+1, after.UpdatedAt, after.AccountID, accountIDs)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("list accounts to report: %w", err)
 	}
 
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
 	accounts := make([]domain.Account, 0, len(rows))
 	for _, row := range rows {
 		accounts = append(accounts, domain.Account{
@@ -139,35 +348,67 @@ func (s *UserDataStore) GetAccountsToReport(ctx context.Context, input *store.Ge
 		})
 	}
 
-	hasMore := offset+len(accounts) < total
-
-	return &store.GetAccountsToReportOutput{
+	output := &store.GetAccountsToReportOutput{
 		Accounts:   accounts,
 		TotalCount: total,
 		HasMore:    hasMore,
-	}, nil
+	}
+
+	if hasMore {
+		last := accounts[len(accounts)-1]
+		output.NextCursor = store.AccountsCursor{UpdatedAt: last.UpdatedAt, AccountID: last.AccountID}.Encode()
+	}
+
+	return output, nil
 }
 
-func (s *UserDataStore) UpdateLastReported(ctx context.Context, input *store.UpdateLastReportedInput) error {
+func (s *UserDataStore) UpdateLastReported(ctx context.Context, input *store.UpdateLastReportedInput) (*store.UpdateLastReportedOutput, error) {
 	if s.db == nil {
-		return fmt.Errorf("store not opened")
+		return nil, fmt.Errorf("store not opened")
 	}
 
 	if input == nil || len(input.AccountIDs) == 0 {
-		return nil
+		return store.NewUpdateLastReportedOutput(nil, nil), nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	if s.pool != nil {
+		updated, err := updateLastReportedPGX(ctx, s.pool, input.Provider, input.AccountIDs, input.ReportedAt.UTC())
+		if err != nil {
+			return nil, err
+		}
+		return store.NewUpdateLastReportedOutput(input.AccountIDs, updated), nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reportedAt := input.ReportedAt.UTC()
+
+	var updated []string
+	for chunk := range slices.Chunk(input.AccountIDs, reportedChunkSize) {
+		var marked []string
+		if err := tx.SelectContext(ctx, &marked, updateInventoryReportedAtQuery, reportedAt, input.Provider, pq.Array(chunk)); err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+		updated = append(updated, marked...)
+
+		if _, err := tx.ExecContext(ctx, updateReportedAtQuery, reportedAt, input.Provider, pq.Array(chunk)); err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
 	}
 
-	if _, err := s.db.ExecContext(
-		ctx,
-		updateReportedAtQuery,
-		input.ReportedAt.UTC(),
-		store.ProviderAtlassian,
-		pq.Array(input.AccountIDs),
-	); err != nil {
-		return fmt.Errorf("update reported_at: %w", err)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit reported_at: %w", err)
 	}
 
-	return nil
+	return store.NewUpdateLastReportedOutput(input.AccountIDs, updated), nil
 }
 
 func (s *UserDataStore) DeleteUserData(ctx context.Context, input *store.DeleteUserDataInput) (*store.DeleteUserDataOutput, error) {
@@ -184,27 +425,102 @@ func (s *UserDataStore) DeleteUserData(ctx context.Context, input *store.DeleteU
 		}, nil
 	}
 
-	var itemsDeleted int
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := deferErasure(ctx, tx, input.Provider, input.AccountID, now)
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit deferred erasure of account %s: %w", input.AccountID, err)
+		}
+		return &store.DeleteUserDataOutput{
+			Deferred: true,
+			HoldID:   hold.ID,
+		}, nil
+	}
+
+	var items store.ErasedItems
+
+	if _, err := tx.ExecContext(ctx, recordDeletedTokensQuery, input.Provider, input.AccountID); err != nil {
+		return nil, fmt.Errorf("record deleted tokens for account %s: %w", input.AccountID, err)
+	}
+
+	tokenResult, err := tx.ExecContext(ctx, deleteTokensQuery, input.Provider, input.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("delete tokens for account %s: %w", input.AccountID, err)
+	}
+	items.Tokens = rowsAffected(tokenResult)
+
+	// Without a tombstone key the history cannot be pseudonymized, so it is
+	// deleted, and the account is erased without a tombstone.
+	var idHash string
+	if s.hasher != nil {
+		idHash = s.hasher.Hash(input.Provider, input.AccountID)
 
-	if tokenResult, err := s.db.ExecContext(ctx, deleteTokensQuery, store.ProviderAtlassian, input.AccountID); err == nil {
-		if rows, _ := tokenResult.RowsAffected(); rows > 0 {
-			itemsDeleted += int(rows)
+		if _, err := tx.ExecContext(ctx, pseudonymizeTokenEventsQuery, input.Provider, input.AccountID, idHash); err != nil {
+			return nil, fmt.Errorf("pseudonymize token history of account %s: %w", input.AccountID, err)
 		}
 	} else {
-		return nil, fmt.Errorf("delete tokens for account %s: %w", input.AccountID, err)
+		if _, err := tx.ExecContext(ctx, deleteAccountTokenEventsQuery, input.Provider, input.AccountID); err != nil {
+			return nil, fmt.Errorf("delete token history of account %s: %w", input.AccountID, err)
+		}
+		items.Untombstoned = true
+	}
+
+	var sessionIDs []string
+	if err := tx.SelectContext(ctx, &sessionIDs, deleteSessionLinksQuery, input.Provider, input.AccountID); err != nil {
+		return nil, fmt.Errorf("delete session links for account %s: %w", input.AccountID, err)
+	}
+	items.SessionLinks = len(sessionIDs)
+
+	if len(sessionIDs) > 0 {
+		sessionResult, err := tx.ExecContext(ctx, deleteOrphanedSessionsQuery, pq.Array(sessionIDs))
+		if err != nil {
+			return nil, fmt.Errorf("delete sessions for account %s: %w", input.AccountID, err)
+		}
+		items.Sessions = rowsAffected(sessionResult)
+
+		scrubResult, err := tx.ExecContext(ctx, scrubSessionsQuery, pq.Array(sessionIDs), input.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("scrub sessions for account %s: %w", input.AccountID, err)
+		}
+		items.SessionsScrubbed = rowsAffected(scrubResult)
 	}
 
-	profileResult, err := s.db.ExecContext(ctx, softDeleteAccountQuery, input.AccountID, store.ProviderAtlassian, now)
+	profileResult, err := tx.ExecContext(ctx, softDeleteAccountQuery, input.AccountID, input.Provider, now)
 	if err != nil {
 		return nil, fmt.Errorf("soft delete account %s: %w", input.AccountID, err)
 	}
-	if rows, _ := profileResult.RowsAffected(); rows > 0 {
-		itemsDeleted += int(rows)
+	items.Profiles = rowsAffected(profileResult)
+
+	if _, err := tx.ExecContext(ctx, resolveDeferredErasuresQuery, input.Provider, input.AccountID, now); err != nil {
+		return nil, fmt.Errorf("resolve deferred erasure of account %s: %w", input.AccountID, err)
+	}
+
+	if idHash != "" {
+		if _, err := tx.ExecContext(ctx, insertAccountTombstoneQuery, input.Provider, idHash, now); err != nil {
+			return nil, fmt.Errorf("tombstone account %s: %w", input.AccountID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit erasure of account %s: %w", input.AccountID, err)
 	}
 
 	return &store.DeleteUserDataOutput{
 		DeletedAt:    now.Format(time.RFC3339),
-		ItemsDeleted: itemsDeleted,
+		ItemsDeleted: items.Total(),
+		Items:        items,
 	}, nil
 }
 
@@ -222,7 +538,18 @@ func (s *UserDataStore) RefreshUserData(ctx context.Context, input *store.Refres
 		}, nil
 	}
 
-	result, err := s.db.ExecContext(ctx, refreshAccountQuery, now, store.ProviderAtlassian, input.AccountID)
+	policy, err := s.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Refresh == store.RefreshNone {
+		return &store.RefreshUserDataOutput{
+			RefreshedAt:  now.Format(time.RFC3339),
+			ItemsUpdated: 0,
+		}, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, refreshAccountQuery, now, input.Provider, input.AccountID)
 	if err != nil {
 		return nil, fmt.Errorf("refresh account %s: %w", input.AccountID, err)
 	}
@@ -234,3 +561,76 @@ func (s *UserDataStore) RefreshUserData(ctx context.Context, input *store.Refres
 		ItemsUpdated: int(rows),
 	}, nil
 }
+
+func (s *UserDataStore) PurgeDeletedProfiles(ctx context.Context, input *store.PurgeDeletedProfilesInput) (*store.PurgeDeletedProfilesOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.DeletedBefore.IsZero() {
+		return nil, fmt.Errorf("deleted before is required")
+	}
+
+	limit := defaultPurgeBatch
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []struct {
+		ID        string    `db:"id"`
+		Provider  string    `db:"provider"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+
+	if err := tx.SelectContext(ctx, &rows, selectPurgeableProfilesQuery, input.DeletedBefore.UTC(), limit, input.Provider); err != nil {
+		return nil, fmt.Errorf("select purgeable profiles: %w", err)
+	}
+
+	purged := 0
+	for _, row := range rows {
+		// The tombstone and pseudonymized history are keyed by the tombstone
+		// hash, so without a key no tombstone is written and only the history
+		// still under the profile ID is deleted.
+		var idHash string
+		if s.hasher != nil {
+			idHash = s.hasher.Hash(row.Provider, row.ID)
+			if _, err := tx.ExecContext(ctx, insertTombstoneQuery, row.Provider, idHash, row.DeletedAt); err != nil {
+				return nil, fmt.Errorf("write tombstone: %w", err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteTokenEventsQuery, row.Provider, row.ID, idHash); err != nil {
+			return nil, fmt.Errorf("delete token history: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, purgeProfileQuery, row.Provider, row.ID)
+		if err != nil {
+			return nil, fmt.Errorf("purge profile: %w", err)
+		}
+		purged += rowsAffected(result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit purge: %w", err)
+	}
+
+	return &store.PurgeDeletedProfilesOutput{
+		Purged:  purged,
+		HasMore: len(rows) == limit,
+	}, nil
+}
+
+// rowsAffected reports the affected row count, treating an unsupported count as zero.
+func rowsAffected(result sql.Result) int {
+	rows, err := result.RowsAffected()
+	if err != nil || rows < 0 {
+		return 0
+	}
+	return int(rows)
+}
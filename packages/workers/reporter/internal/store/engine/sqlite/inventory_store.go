@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type InventoryStore struct {
+	db *sqlx.DB
+}
+
+// registerPersonalDataQuery needs the WHERE clause so that SQLite parses the
+// ON CONFLICT as an upsert rather than a join constraint.
+const registerPersonalDataQuery = `
+INSERT INTO personal_data_inventory (
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at,
+	created_at,
+	updated_at
+)
+SELECT DISTINCT
+	?1,
+	value,
+	json_array(?2),
+	?3,
+	?4,
+	?4
+FROM
+	json_each(?5)
+WHERE
+	true
+ON CONFLICT (provider, account_id) DO UPDATE SET
+	stores = (
+		SELECT json_group_array(value) FROM (
+			SELECT value FROM json_each(personal_data_inventory.stores)
+			UNION
+			SELECT ?2
+			ORDER BY value
+		)
+	),
+	data_retrieved_at = max(personal_data_inventory.data_retrieved_at, excluded.data_retrieved_at),
+	updated_at = excluded.updated_at`
+
+const unregisterPersonalDataQuery = `
+UPDATE personal_data_inventory SET
+	stores = (
+		SELECT json_group_array(value) FROM json_each(personal_data_inventory.stores) WHERE value <> ?2
+	),
+	updated_at = ?4
+WHERE
+	provider = ?1
+	AND account_id IN (SELECT value FROM json_each(?3))
+	AND EXISTS (SELECT 1 FROM json_each(personal_data_inventory.stores) WHERE value = ?2)`
+
+const deleteEmptyPersonalDataQuery = `
+DELETE FROM
+	personal_data_inventory
+WHERE
+	provider = ?1
+	AND account_id IN (SELECT value FROM json_each(?2))
+	AND json_array_length(stores) = 0`
+
+const selectPersonalDataQuery = `
+SELECT
+	provider,
+	account_id,
+	stores,
+	data_retrieved_at,
+	reported_at,
+	created_at,
+	updated_at
+FROM
+	personal_data_inventory
+WHERE
+	provider = ?1
+	AND account_id = ?2`
+
+func (s *Store) Inventory() store.InventoryStore {
+	return s.inventory
+}
+
+func (s *InventoryStore) RegisterPersonalData(ctx context.Context, input *store.RegisterPersonalDataInput) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" || input.RetrievedAt.IsZero() {
+		return fmt.Errorf("provider, store, and retrieved at are required")
+	}
+
+	if len(input.AccountIDs) == 0 {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		registerPersonalDataQuery,
+		input.Provider,
+		input.Store,
+		formatTime(input.RetrievedAt),
+		formatTime(time.Now()),
+		encodeStrings(input.AccountIDs),
+	); err != nil {
+		return fmt.Errorf("register %s personal data: %w", input.Store, err)
+	}
+
+	return nil
+}
+
+func (s *InventoryStore) UnregisterPersonalData(ctx context.Context, input *store.UnregisterPersonalDataInput) (*store.UnregisterPersonalDataOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Provider == "" || input.Store == "" {
+		return nil, fmt.Errorf("provider and store are required")
+	}
+
+	if len(input.AccountIDs) == 0 {
+		return &store.UnregisterPersonalDataOutput{}, nil
+	}
+
+	accountIDs := encodeStrings(input.AccountIDs)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, unregisterPersonalDataQuery, input.Provider, input.Store, accountIDs, formatTime(time.Now())); err != nil {
+		return nil, fmt.Errorf("unregister %s personal data: %w", input.Store, err)
+	}
+
+	result, err := tx.ExecContext(ctx, deleteEmptyPersonalDataQuery, input.Provider, accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("remove empty inventory entries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit unregister: %w", err)
+	}
+
+	return &store.UnregisterPersonalDataOutput{Removed: rowsAffected(result)}, nil
+}
+
+func (s *InventoryStore) GetPersonalData(ctx context.Context, input *store.PersonalDataKey) (*store.PersonalDataEntry, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	var row struct {
+		Provider        string         `db:"provider"`
+		AccountID       string         `db:"account_id"`
+		Stores          string         `db:"stores"`
+		DataRetrievedAt string         `db:"data_retrieved_at"`
+		ReportedAt      sql.NullString `db:"reported_at"`
+		CreatedAt       string         `db:"created_at"`
+		UpdatedAt       string         `db:"updated_at"`
+	}
+	if err := s.db.GetContext(ctx, &row, selectPersonalDataQuery, input.Provider, input.AccountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get personal data of account %s: %w", input.AccountID, err)
+	}
+
+	entry := &store.PersonalDataEntry{
+		AccountID: row.AccountID,
+		Provider:  row.Provider,
+	}
+
+	var err error
+	if entry.Stores, err = decodeStrings(row.Stores); err != nil {
+		return nil, err
+	}
+	slices.Sort(entry.Stores)
+	if entry.DataRetrievedAt, err = parseTime(row.DataRetrievedAt); err != nil {
+		return nil, err
+	}
+	if entry.ReportedAt, err = parseNullTime(row.ReportedAt); err != nil {
+		return nil, err
+	}
+	if entry.CreatedAt, err = parseTime(row.CreatedAt); err != nil {
+		return nil, err
+	}
+	if entry.UpdatedAt, err = parseTime(row.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,359 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultPendingErasuresPage = 100
+)
+
+type LegalHoldStore struct {
+	db *sqlx.DB
+}
+
+const legalHoldColumns = `
+	id,
+	account_id,
+	provider,
+	reason,
+	placed_by,
+	placed_at,
+	expires_at,
+	released_at,
+	erasure_deferred_at,
+	erasure_resolved_at`
+
+// activeLegalHoldCondition matches the unreleased, unexpired hold of the
+// account given by ?1 (provider) and ?2 (account id) at time ?3.
+const activeLegalHoldCondition = `
+	provider = ?1
+	AND account_id = ?2
+	AND released_at IS NULL
+	AND (expires_at IS NULL OR expires_at > ?3)`
+
+const releaseExpiredLegalHoldQuery = `
+UPDATE legal_holds SET
+	released_at = expires_at
+WHERE
+	provider = ?1
+	AND account_id = ?2
+	AND released_at IS NULL
+	AND expires_at <= ?3`
+
+const placeLegalHoldQuery = `
+INSERT INTO legal_holds (
+	account_id,
+	provider,
+	reason,
+	placed_by,
+	placed_at,
+	expires_at
+) VALUES (
+	?1, ?2, ?3, NULLIF(?4, ''), ?5, ?6
+)
+ON CONFLICT (provider, account_id) WHERE released_at IS NULL DO UPDATE SET
+	reason = excluded.reason,
+	placed_by = excluded.placed_by,
+	expires_at = excluded.expires_at
+RETURNING` + legalHoldColumns
+
+const releaseLegalHoldQuery = `
+UPDATE legal_holds SET
+	released_at = ?3
+WHERE
+	provider = ?1
+	AND account_id = ?2
+	AND released_at IS NULL`
+
+const selectErasurePendingQuery = `
+SELECT EXISTS (
+	SELECT
+		1
+	FROM
+		legal_holds
+	WHERE
+		provider = ?1
+		AND account_id = ?2
+		AND erasure_deferred_at IS NOT NULL
+		AND erasure_resolved_at IS NULL
+)`
+
+const selectActiveLegalHoldQuery = `
+SELECT` + legalHoldColumns + `
+FROM
+	legal_holds
+WHERE` + activeLegalHoldCondition
+
+const deferErasureQuery = `
+UPDATE legal_holds SET
+	erasure_deferred_at = COALESCE(erasure_deferred_at, ?3)
+WHERE` + activeLegalHoldCondition + `
+RETURNING` + legalHoldColumns
+
+const resolveDeferredErasuresQuery = `
+UPDATE legal_holds SET
+	erasure_resolved_at = ?3
+WHERE
+	provider = ?1
+	AND account_id = ?2
+	AND erasure_deferred_at IS NOT NULL
+	AND erasure_resolved_at IS NULL`
+
+const listPendingErasuresQuery = `
+SELECT
+	h.account_id,
+	h.provider,
+	MIN(h.erasure_deferred_at) AS deferred_at
+FROM
+	legal_holds h
+WHERE
+	h.erasure_deferred_at IS NOT NULL
+	AND h.erasure_resolved_at IS NULL
+	AND (?3 = '' OR h.provider = ?3)
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			legal_holds a
+		WHERE
+			a.provider = h.provider
+			AND a.account_id = h.account_id
+			AND a.released_at IS NULL
+			AND (a.expires_at IS NULL OR a.expires_at > ?1)
+	)
+GROUP BY
+	h.provider,
+	h.account_id
+HAVING
+	?4 IS NULL
+	OR (MIN(h.erasure_deferred_at), h.provider, h.account_id) > (?4, ?5, ?6)
+ORDER BY
+	deferred_at,
+	h.provider,
+	h.account_id
+LIMIT ?2`
+
+type legalHoldRow struct {
+	ID                string         `db:"id"`
+	AccountID         string         `db:"account_id"`
+	Provider          string         `db:"provider"`
+	Reason            string         `db:"reason"`
+	PlacedBy          sql.NullString `db:"placed_by"`
+	PlacedAt          string         `db:"placed_at"`
+	ExpiresAt         sql.NullString `db:"expires_at"`
+	ReleasedAt        sql.NullString `db:"released_at"`
+	ErasureDeferredAt sql.NullString `db:"erasure_deferred_at"`
+	ErasureResolvedAt sql.NullString `db:"erasure_resolved_at"`
+}
+
+func (row legalHoldRow) hold() (*store.LegalHold, error) {
+	hold := &store.LegalHold{
+		ID:        row.ID,
+		AccountID: row.AccountID,
+		Provider:  row.Provider,
+		Reason:    row.Reason,
+		PlacedBy:  row.PlacedBy.String,
+	}
+
+	var err error
+	if hold.PlacedAt, err = parseTime(row.PlacedAt); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		dst   **time.Time
+		value sql.NullString
+	}{
+		{&hold.ExpiresAt, row.ExpiresAt},
+		{&hold.ReleasedAt, row.ReleasedAt},
+		{&hold.ErasureDeferredAt, row.ErasureDeferredAt},
+		{&hold.ErasureResolvedAt, row.ErasureResolvedAt},
+	} {
+		if *field.dst, err = parseNullTime(field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return hold, nil
+}
+
+func (s *Store) LegalHolds() store.LegalHoldStore {
+	return s.legalHolds
+}
+
+func (s *LegalHoldStore) PlaceLegalHold(ctx context.Context, input *store.PlaceLegalHoldInput) (*store.LegalHold, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" || input.Reason == "" {
+		return nil, fmt.Errorf("account id, provider, and reason are required")
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("legal hold expiry must be in the future")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// An expired hold is closed off so that the new hold gets its own row
+	// while the expired one keeps any erasure it deferred.
+	if _, err := tx.ExecContext(ctx, releaseExpiredLegalHoldQuery, input.Provider, input.AccountID, formatTime(now)); err != nil {
+		return nil, fmt.Errorf("release expired legal hold: %w", err)
+	}
+
+	var row legalHoldRow
+	if err := tx.GetContext(ctx, &row, placeLegalHoldQuery, input.AccountID, input.Provider, input.Reason, input.PlacedBy, formatTime(now), nullTime(input.ExpiresAt)); err != nil {
+		return nil, fmt.Errorf("place legal hold on account %s: %w", input.AccountID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit legal hold: %w", err)
+	}
+
+	return row.hold()
+}
+
+func (s *LegalHoldStore) ReleaseLegalHold(ctx context.Context, input *store.LegalHoldKey) (*store.ReleaseLegalHoldOutput, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, releaseLegalHoldQuery, input.Provider, input.AccountID, formatTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("release legal hold on account %s: %w", input.AccountID, err)
+	}
+
+	var pending bool
+	if err := tx.GetContext(ctx, &pending, selectErasurePendingQuery, input.Provider, input.AccountID); err != nil {
+		return nil, fmt.Errorf("check pending erasure: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit legal hold release: %w", err)
+	}
+
+	return &store.ReleaseLegalHoldOutput{
+		Released:       rowsAffected(result) > 0,
+		ErasurePending: pending,
+	}, nil
+}
+
+func (s *LegalHoldStore) GetLegalHold(ctx context.Context, input *store.LegalHoldKey) (*store.LegalHold, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	var row legalHoldRow
+	if err := s.db.GetContext(ctx, &row, selectActiveLegalHoldQuery, input.Provider, input.AccountID, formatTime(time.Now())); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get legal hold: %w", err)
+	}
+
+	return row.hold()
+}
+
+func (s *LegalHoldStore) DeferErasure(ctx context.Context, input *store.LegalHoldKey) (*store.LegalHold, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	return deferErasure(ctx, s.db, input.Provider, input.AccountID, time.Now().UTC())
+}
+
+func (s *LegalHoldStore) ListPendingErasures(ctx context.Context, input *store.ListPendingErasuresInput) ([]store.PendingErasure, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	limit := defaultPendingErasuresPage
+	var (
+		provider               string
+		afterAt                sql.NullString
+		afterProvider, afterID string
+	)
+	if input != nil {
+		if input.Limit > 0 {
+			limit = input.Limit
+		}
+		provider = input.Provider
+		if input.After != nil {
+			afterAt = nullTime(&input.After.DeferredAt)
+			afterProvider, afterID = input.After.Provider, input.After.AccountID
+		}
+	}
+
+	var rows []struct {
+		AccountID  string `db:"account_id"`
+		Provider   string `db:"provider"`
+		DeferredAt string `db:"deferred_at"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, listPendingErasuresQuery, formatTime(time.Now()), limit, provider, afterAt, afterProvider, afterID); err != nil {
+		return nil, fmt.Errorf("list pending erasures: %w", err)
+	}
+
+	erasures := make([]store.PendingErasure, 0, len(rows))
+	for _, row := range rows {
+		deferredAt, err := parseTime(row.DeferredAt)
+		if err != nil {
+			return nil, err
+		}
+		erasures = append(erasures, store.PendingErasure{
+			AccountID:  row.AccountID,
+			Provider:   row.Provider,
+			DeferredAt: deferredAt,
+		})
+	}
+
+	return erasures, nil
+}
+
+// deferErasure marks the account's active hold as deferring an erasure and
+// returns it, or nil when the account is not held.
+func deferErasure(ctx context.Context, q sqlx.QueryerContext, provider, accountID string, now time.Time) (*store.LegalHold, error) {
+	var row legalHoldRow
+	if err := sqlx.GetContext(ctx, q, &row, deferErasureQuery, provider, accountID, formatTime(now)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("defer erasure of account %s: %w", accountID, err)
+	}
+
+	return row.hold()
+}
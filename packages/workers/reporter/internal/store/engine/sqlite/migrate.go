@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+const (
+	migrateUpMarker   = "-- migrate:up"
+	migrateDownMarker = "-- migrate:down"
+)
+
+const createSchemaMigrationsQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version TEXT PRIMARY KEY
+)`
+
+// migrate applies the "-- migrate:up" section of every embedded migration that
+// has not been applied yet, in version order. Files follow dbmate's format.
+func migrate(ctx context.Context, db *sqlx.DB) error {
+	if _, err := db.ExecContext(ctx, createSchemaMigrationsQuery); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var applied []string
+	if err := db.SelectContext(ctx, &applied, `SELECT version FROM schema_migrations`); err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+
+	done := make(map[string]bool, len(applied))
+	for _, version := range applied {
+		done[version] = true
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("list migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version, _, _ := strings.Cut(strings.TrimPrefix(name, "migrations/"), "_")
+		if done[version] {
+			continue
+		}
+
+		data, err := migrations.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read migration %s: %w", name, err)
+		}
+
+		up, err := upSection(string(data))
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin migration %s: %w", name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, up); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply migration %s: %w", name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record migration %s: %w", name, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func upSection(content string) (string, error) {
+	_, rest, ok := strings.Cut(content, migrateUpMarker)
+	if !ok {
+		return "", fmt.Errorf("missing %q section", migrateUpMarker)
+	}
+	up, _, _ := strings.Cut(rest, migrateDownMarker)
+	return strings.TrimSpace(up), nil
+}
@@ -0,0 +1,16 @@
+-- migrate:up
+CREATE TABLE profiles (
+	id          TEXT NOT NULL,
+	provider    TEXT NOT NULL,
+
+	reported_at TEXT,
+	deleted_at  TEXT,
+
+	created_at  TEXT NOT NULL,
+	updated_at  TEXT NOT NULL,
+
+	PRIMARY KEY (id, provider)
+);
+
+-- migrate:down
+DROP TABLE profiles;
@@ -0,0 +1,13 @@
+-- migrate:up
+CREATE TABLE sessions (
+	id         TEXT PRIMARY KEY,
+	data       TEXT NOT NULL DEFAULT '{}',
+
+	expires_at TEXT,
+
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+-- migrate:down
+DROP TABLE sessions;
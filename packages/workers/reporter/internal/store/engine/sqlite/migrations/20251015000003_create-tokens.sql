@@ -0,0 +1,20 @@
+-- migrate:up
+CREATE TABLE tokens (
+	provider      TEXT NOT NULL,
+	profile_id    TEXT NOT NULL,
+
+	access_token  TEXT NOT NULL,
+	refresh_token TEXT,
+	expires_at    TEXT,
+	-- JSON array of scope strings
+	scopes        TEXT NOT NULL DEFAULT '[]',
+
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL,
+
+	PRIMARY KEY (profile_id, provider),
+	FOREIGN KEY (profile_id, provider) REFERENCES profiles(id, provider) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- migrate:down
+DROP TABLE tokens;
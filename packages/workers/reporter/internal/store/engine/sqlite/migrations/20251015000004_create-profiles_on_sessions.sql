@@ -0,0 +1,20 @@
+-- migrate:up
+CREATE TABLE profiles_on_sessions (
+	profile_id       TEXT NOT NULL,
+	profile_provider TEXT NOT NULL,
+	session_id       TEXT NOT NULL,
+	connection_type  TEXT NOT NULL CHECK (connection_type IN ('worklog-target', 'data-source')),
+
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+
+	PRIMARY KEY (profile_id, profile_provider, session_id),
+	FOREIGN KEY (profile_id, profile_provider) REFERENCES profiles(id, provider) ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_profiles_on_sessions_session_id ON profiles_on_sessions(session_id);
+CREATE INDEX idx_profiles_on_sessions_profile ON profiles_on_sessions(profile_id, profile_provider);
+
+-- migrate:down
+DROP TABLE profiles_on_sessions;
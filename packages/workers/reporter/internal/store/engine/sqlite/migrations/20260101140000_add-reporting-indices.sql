@@ -0,0 +1,7 @@
+-- migrate:up
+CREATE INDEX idx_profiles_provider_reported_at_updated_at_active
+  ON profiles (provider, reported_at, updated_at DESC)
+  WHERE deleted_at IS NULL;
+
+-- migrate:down
+DROP INDEX idx_profiles_provider_reported_at_updated_at_active;
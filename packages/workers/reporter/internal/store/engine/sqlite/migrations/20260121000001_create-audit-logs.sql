@@ -0,0 +1,38 @@
+-- migrate:up
+CREATE TABLE audit_logs (
+	id                   TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+	occurred_at          TEXT NOT NULL,
+
+	action_type          TEXT NOT NULL,
+	action_description   TEXT NOT NULL,
+	severity             TEXT NOT NULL DEFAULT 'info',
+
+	target_resource_type TEXT NOT NULL,
+	target_resource_id   TEXT,
+
+	outcome              TEXT NOT NULL,
+
+	correlation_id       TEXT NOT NULL,
+	request_id           TEXT NOT NULL,
+	request_path         TEXT NOT NULL,
+	request_method       TEXT NOT NULL,
+
+	metadata             TEXT,
+
+	created_at           TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_logs_correlation ON audit_logs (correlation_id, occurred_at);
+
+CREATE TRIGGER audit_log_immutable_update BEFORE UPDATE ON audit_logs
+BEGIN
+	SELECT RAISE(ABORT, 'Audit logs are immutable - UPDATE and DELETE are not allowed');
+END;
+
+CREATE TRIGGER audit_log_immutable_delete BEFORE DELETE ON audit_logs
+BEGIN
+	SELECT RAISE(ABORT, 'Audit logs are immutable - UPDATE and DELETE are not allowed');
+END;
+
+-- migrate:down
+DROP TABLE audit_logs;
@@ -0,0 +1,19 @@
+-- migrate:up
+CREATE TABLE token_health_reports (
+	id              TEXT    PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+	run_id          TEXT    NOT NULL,
+
+	total           INTEGER NOT NULL,
+	healthy         INTEGER NOT NULL,
+	expiring_soon   INTEGER NOT NULL,
+	expired         INTEGER NOT NULL,
+	scopes_drifted  INTEGER NOT NULL,
+	profile_deleted INTEGER NOT NULL,
+
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_token_health_reports_created_at ON token_health_reports(created_at DESC);
+
+-- migrate:down
+DROP TABLE token_health_reports;
@@ -0,0 +1,21 @@
+-- migrate:up
+CREATE TABLE token_events (
+	id             TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+	profile_id     TEXT NOT NULL,
+	provider       TEXT NOT NULL,
+	event_type     TEXT NOT NULL CHECK (event_type IN ('refreshed', 'rotated', 'revoked', 'deleted', 'refresh-failed')),
+	actor          TEXT NOT NULL CHECK (actor IN ('worker', 'web')),
+
+	old_expires_at TEXT,
+	new_expires_at TEXT,
+	scopes_added   TEXT NOT NULL DEFAULT '[]',
+	scopes_removed TEXT NOT NULL DEFAULT '[]',
+	error_class    TEXT,
+
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_token_events_profile ON token_events(profile_id, provider, created_at DESC);
+
+-- migrate:down
+DROP TABLE token_events;
@@ -0,0 +1,15 @@
+-- migrate:up
+CREATE TABLE profile_tombstones (
+	provider   TEXT NOT NULL,
+	id_hash    TEXT NOT NULL,
+	deleted_at TEXT NOT NULL,
+	purged_at  TEXT NOT NULL,
+
+	PRIMARY KEY (provider, id_hash)
+);
+
+CREATE INDEX idx_profiles_deleted_at ON profiles(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- migrate:down
+DROP INDEX idx_profiles_deleted_at;
+DROP TABLE profile_tombstones;
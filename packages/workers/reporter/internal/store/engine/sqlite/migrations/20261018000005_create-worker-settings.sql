@@ -0,0 +1,9 @@
+-- migrate:up
+CREATE TABLE worker_settings (
+	key        TEXT PRIMARY KEY,
+	value      TEXT NOT NULL,
+	changed_at TEXT NOT NULL
+);
+
+-- migrate:down
+DROP TABLE worker_settings;
@@ -0,0 +1,39 @@
+-- migrate:up
+CREATE TABLE privacy_actions (
+	id           TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+	account_id   TEXT NOT NULL,
+	provider     TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	received_at  TEXT NOT NULL,
+	workflow_id  TEXT NOT NULL,
+	run_id       TEXT NOT NULL,
+
+	action       TEXT NOT NULL CHECK (action IN ('erased', 'refreshed', 'deferred')),
+	completed_at TEXT,
+	items        TEXT NOT NULL DEFAULT '{}',
+	error        TEXT,
+
+	created_at TEXT NOT NULL,
+
+	UNIQUE (run_id, provider, account_id, status)
+);
+
+CREATE INDEX idx_privacy_actions_account ON privacy_actions(provider, account_id, received_at DESC);
+CREATE INDEX idx_privacy_actions_received_at ON privacy_actions(received_at);
+
+CREATE TRIGGER privacy_action_immutable_update
+	BEFORE UPDATE ON privacy_actions
+BEGIN
+	SELECT RAISE(ABORT, 'Privacy actions are immutable - UPDATE and DELETE are not allowed');
+END;
+
+CREATE TRIGGER privacy_action_immutable_delete
+	BEFORE DELETE ON privacy_actions
+BEGIN
+	SELECT RAISE(ABORT, 'Privacy actions are immutable - UPDATE and DELETE are not allowed');
+END;
+
+-- migrate:down
+DROP TRIGGER privacy_action_immutable_delete;
+DROP TRIGGER privacy_action_immutable_update;
+DROP TABLE privacy_actions;
@@ -0,0 +1,21 @@
+-- migrate:up
+CREATE TABLE legal_holds (
+	id          TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+	account_id  TEXT NOT NULL,
+	provider    TEXT NOT NULL,
+	reason      TEXT NOT NULL,
+	placed_by   TEXT,
+	placed_at   TEXT NOT NULL,
+	expires_at  TEXT,
+	released_at TEXT,
+
+	erasure_deferred_at TEXT,
+	erasure_resolved_at TEXT
+);
+
+CREATE UNIQUE INDEX idx_legal_holds_unreleased ON legal_holds(provider, account_id) WHERE released_at IS NULL;
+CREATE INDEX idx_legal_holds_pending_erasure ON legal_holds(erasure_deferred_at)
+	WHERE erasure_deferred_at IS NOT NULL AND erasure_resolved_at IS NULL;
+
+-- migrate:down
+DROP TABLE legal_holds;
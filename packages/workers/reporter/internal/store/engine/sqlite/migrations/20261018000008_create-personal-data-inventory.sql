@@ -0,0 +1,94 @@
+-- migrate:up
+CREATE TABLE personal_data_inventory (
+	provider          TEXT NOT NULL,
+	account_id        TEXT NOT NULL,
+	stores            TEXT NOT NULL DEFAULT '[]',
+	data_retrieved_at TEXT NOT NULL,
+	reported_at       TEXT,
+
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+
+	PRIMARY KEY (provider, account_id)
+);
+
+CREATE INDEX idx_personal_data_inventory_reporting ON personal_data_inventory(provider, data_retrieved_at DESC, account_id);
+
+CREATE TRIGGER profiles_register_personal_data_insert
+	AFTER INSERT ON profiles
+	WHEN NEW.deleted_at IS NULL
+BEGIN
+	INSERT INTO personal_data_inventory (provider, account_id, stores, data_retrieved_at, reported_at, created_at, updated_at)
+	VALUES (NEW.provider, NEW.id, '["profiles"]', NEW.updated_at, NEW.reported_at, NEW.updated_at, NEW.updated_at)
+	ON CONFLICT (provider, account_id) DO UPDATE SET
+		stores = (
+			SELECT json_group_array(value) FROM (
+				SELECT value FROM json_each(personal_data_inventory.stores)
+				UNION
+				SELECT 'profiles'
+				ORDER BY value
+			)
+		),
+		data_retrieved_at = max(personal_data_inventory.data_retrieved_at, excluded.data_retrieved_at),
+		updated_at = excluded.updated_at;
+END;
+
+CREATE TRIGGER profiles_register_personal_data_update
+	AFTER UPDATE OF updated_at, deleted_at ON profiles
+	WHEN NEW.deleted_at IS NULL
+BEGIN
+	INSERT INTO personal_data_inventory (provider, account_id, stores, data_retrieved_at, reported_at, created_at, updated_at)
+	VALUES (NEW.provider, NEW.id, '["profiles"]', NEW.updated_at, NEW.reported_at, NEW.updated_at, NEW.updated_at)
+	ON CONFLICT (provider, account_id) DO UPDATE SET
+		stores = (
+			SELECT json_group_array(value) FROM (
+				SELECT value FROM json_each(personal_data_inventory.stores)
+				UNION
+				SELECT 'profiles'
+				ORDER BY value
+			)
+		),
+		data_retrieved_at = max(personal_data_inventory.data_retrieved_at, excluded.data_retrieved_at),
+		updated_at = excluded.updated_at;
+END;
+
+CREATE TRIGGER profiles_unregister_personal_data_update
+	AFTER UPDATE OF deleted_at ON profiles
+	WHEN NEW.deleted_at IS NOT NULL
+BEGIN
+	UPDATE personal_data_inventory SET
+		stores = (SELECT json_group_array(value) FROM json_each(personal_data_inventory.stores) WHERE value <> 'profiles'),
+		updated_at = NEW.updated_at
+	WHERE
+		provider = OLD.provider
+		AND account_id = OLD.id;
+
+	DELETE FROM personal_data_inventory
+	WHERE
+		provider = OLD.provider
+		AND account_id = OLD.id
+		AND json_array_length(stores) = 0;
+END;
+
+CREATE TRIGGER profiles_unregister_personal_data_delete
+	AFTER DELETE ON profiles
+BEGIN
+	UPDATE personal_data_inventory SET
+		stores = (SELECT json_group_array(value) FROM json_each(personal_data_inventory.stores) WHERE value <> 'profiles')
+	WHERE
+		provider = OLD.provider
+		AND account_id = OLD.id;
+
+	DELETE FROM personal_data_inventory
+	WHERE
+		provider = OLD.provider
+		AND account_id = OLD.id
+		AND json_array_length(stores) = 0;
+END;
+
+-- migrate:down
+DROP TRIGGER profiles_unregister_personal_data_delete;
+DROP TRIGGER profiles_unregister_personal_data_update;
+DROP TRIGGER profiles_register_personal_data_update;
+DROP TRIGGER profiles_register_personal_data_insert;
+DROP TABLE personal_data_inventory;
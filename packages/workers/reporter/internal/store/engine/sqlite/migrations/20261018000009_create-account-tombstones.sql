@@ -0,0 +1,11 @@
+-- migrate:up
+CREATE TABLE account_tombstones (
+	provider  TEXT NOT NULL,
+	id_hash   TEXT NOT NULL,
+	closed_at TEXT NOT NULL,
+
+	PRIMARY KEY (provider, id_hash)
+);
+
+-- migrate:down
+DROP TABLE account_tombstones;
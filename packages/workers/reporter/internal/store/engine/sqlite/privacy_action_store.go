@@ -0,0 +1,234 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	defaultPrivacyActionsPage = 500
+)
+
+type PrivacyActionStore struct {
+	db *sqlx.DB
+}
+
+const insertPrivacyActionQuery = `
+INSERT INTO privacy_actions (
+	account_id,
+	provider,
+	status,
+	received_at,
+	workflow_id,
+	run_id,
+	action,
+	completed_at,
+	items,
+	error,
+	created_at
+) VALUES (
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (run_id, provider, account_id, status) DO NOTHING`
+
+const privacyActionColumns = `
+	id,
+	account_id,
+	provider,
+	status,
+	received_at,
+	workflow_id,
+	run_id,
+	action,
+	completed_at,
+	items,
+	error,
+	created_at`
+
+const selectAccountPrivacyActionsQuery = `
+SELECT` + privacyActionColumns + `
+FROM
+	privacy_actions
+WHERE
+	account_id = ?
+	AND provider = ?
+ORDER BY
+	received_at DESC,
+	id
+LIMIT ?`
+
+const listPrivacyActionsQuery = `
+SELECT` + privacyActionColumns + `
+FROM
+	privacy_actions
+WHERE
+	received_at >= ?
+	AND received_at < ?
+ORDER BY
+	received_at,
+	id
+LIMIT ? OFFSET ?`
+
+type privacyActionRow struct {
+	ID          string         `db:"id"`
+	AccountID   string         `db:"account_id"`
+	Provider    string         `db:"provider"`
+	Status      string         `db:"status"`
+	ReceivedAt  string         `db:"received_at"`
+	WorkflowID  string         `db:"workflow_id"`
+	RunID       string         `db:"run_id"`
+	Action      string         `db:"action"`
+	CompletedAt sql.NullString `db:"completed_at"`
+	Items       string         `db:"items"`
+	Error       sql.NullString `db:"error"`
+	CreatedAt   string         `db:"created_at"`
+}
+
+func (s *Store) PrivacyActions() store.PrivacyActionStore {
+	return s.privacyActions
+}
+
+func (s *PrivacyActionStore) RecordPrivacyAction(ctx context.Context, action *store.PrivacyAction) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if action == nil || action.AccountID == "" || action.Provider == "" || action.Status == "" || action.RunID == "" || action.Action == "" {
+		return fmt.Errorf("account id, provider, status, run id, and action are required")
+	}
+
+	items := action.Items
+	if items == nil {
+		items = map[string]int{}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("marshal privacy action items: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertPrivacyActionQuery,
+		action.AccountID,
+		action.Provider,
+		action.Status,
+		formatTime(action.ReceivedAt),
+		action.WorkflowID,
+		action.RunID,
+		action.Action,
+		nullTime(action.CompletedAt),
+		string(data),
+		sql.NullString{String: action.Error, Valid: action.Error != ""},
+		formatTime(time.Now()),
+	); err != nil {
+		return fmt.Errorf("record privacy action: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PrivacyActionStore) GetAccountPrivacyActions(ctx context.Context, input *store.GetAccountPrivacyActionsInput) ([]store.PrivacyAction, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("account id and provider are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []privacyActionRow
+	if err := s.db.SelectContext(ctx, &rows, selectAccountPrivacyActionsQuery, input.AccountID, input.Provider, limit); err != nil {
+		return nil, fmt.Errorf("get account privacy actions: %w", err)
+	}
+
+	return privacyActions(rows)
+}
+
+func (s *PrivacyActionStore) ListPrivacyActions(ctx context.Context, input *store.ListPrivacyActionsInput) (*store.ListPrivacyActionsOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ReceivedFrom.IsZero() || input.ReceivedTo.IsZero() {
+		return nil, fmt.Errorf("received from and received to are required")
+	}
+
+	limit := defaultPrivacyActionsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []privacyActionRow
+
+	// Fetch one extra row to learn whether another page exists without counting.
+	if err := s.db.SelectContext(ctx, &rows, listPrivacyActionsQuery, formatTime(input.ReceivedFrom), formatTime(input.ReceivedTo), limit+1, max(input.Offset, 0)); err != nil {
+		return nil, fmt.Errorf("list privacy actions: %w", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	actions, err := privacyActions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.ListPrivacyActionsOutput{
+		Actions: actions,
+		HasMore: hasMore,
+	}, nil
+}
+
+func privacyActions(rows []privacyActionRow) ([]store.PrivacyAction, error) {
+	actions := make([]store.PrivacyAction, 0, len(rows))
+	for _, row := range rows {
+		receivedAt, err := parseTime(row.ReceivedAt)
+		if err != nil {
+			return nil, err
+		}
+		completedAt, err := parseNullTime(row.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		createdAt, err := parseTime(row.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		var items map[string]int
+		if err := json.Unmarshal([]byte(row.Items), &items); err != nil {
+			return nil, fmt.Errorf("decode privacy action items: %w", err)
+		}
+
+		actions = append(actions, store.PrivacyAction{
+			ID:          row.ID,
+			AccountID:   row.AccountID,
+			Provider:    row.Provider,
+			Status:      row.Status,
+			ReceivedAt:  receivedAt,
+			WorkflowID:  row.WorkflowID,
+			RunID:       row.RunID,
+			Action:      store.PrivacyActionType(row.Action),
+			CompletedAt: completedAt,
+			Items:       items,
+			Error:       row.Error.String,
+			CreatedAt:   createdAt,
+		})
+	}
+
+	return actions, nil
+}
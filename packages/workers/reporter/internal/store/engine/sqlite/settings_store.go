@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/store"
+)
+
+type SettingsStore struct {
+	db *sqlx.DB
+}
+
+const selectSettingQuery = `
+SELECT
+	value,
+	changed_at
+FROM
+	worker_settings
+WHERE
+	key = ?`
+
+const upsertSettingQuery = `
+INSERT INTO worker_settings (key, value, changed_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (key) DO UPDATE SET
+	value = excluded.value,
+	changed_at = excluded.changed_at
+WHERE
+	worker_settings.value <> excluded.value`
+
+func (s *Store) Settings() store.SettingsStore {
+	return s.settings
+}
+
+func (s *SettingsStore) GetCyclePeriod(ctx context.Context) (*store.CyclePeriod, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	return getCyclePeriod(ctx, s.db, store.ProviderAtlassian, atlassian.DefaultCyclePeriodDays)
+}
+
+func (s *SettingsStore) SetCyclePeriod(ctx context.Context, input *store.SetCyclePeriodInput) (*store.SetCyclePeriodOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.Days <= 0 {
+		return nil, fmt.Errorf("cycle period days must be positive")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := getCyclePeriod(ctx, tx, store.ProviderAtlassian, atlassian.DefaultCyclePeriodDays)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, upsertSettingQuery, store.SettingCyclePeriodDays, strconv.Itoa(input.Days), formatTime(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("store cycle period: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit cycle period: %w", err)
+	}
+
+	return &store.SetCyclePeriodOutput{
+		PreviousDays: previous.Days,
+		Changed:      rowsAffected(result) > 0,
+	}, nil
+}
+
+// getCyclePeriod reads the provider's stored cycle period, falling back to
+// defaultDays.
+func getCyclePeriod(ctx context.Context, q sqlx.QueryerContext, provider string, defaultDays int) (*store.CyclePeriod, error) {
+	var row struct {
+		Value     string `db:"value"`
+		ChangedAt string `db:"changed_at"`
+	}
+	if err := sqlx.GetContext(ctx, q, &row, selectSettingQuery, store.CyclePeriodSetting(provider)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &store.CyclePeriod{Days: defaultDays}, nil
+		}
+		return nil, fmt.Errorf("get cycle period: %w", err)
+	}
+
+	days, err := strconv.Atoi(row.Value)
+	if err != nil || days <= 0 {
+		return nil, fmt.Errorf("invalid stored cycle period %q", row.Value)
+	}
+
+	changedAt, err := parseTime(row.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.CyclePeriod{Days: days, ChangedAt: &changedAt}, nil
+}
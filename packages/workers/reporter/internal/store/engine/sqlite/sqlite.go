@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// timeLayout stores timestamps as fixed-width UTC text so that lexical
+// comparison and ordering match chronological order.
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+type Store struct {
+	db *sqlx.DB
+
+	path string
+
+	tombstoneHasher *store.TombstoneHasher
+	policies        store.ProviderPolicies
+
+	userData       *UserDataStore
+	tokens         *TokenStore
+	audit          *AuditStore
+	settings       *SettingsStore
+	privacyActions *PrivacyActionStore
+	legalHolds     *LegalHoldStore
+	inventory      *InventoryStore
+	tombstones     *AccountTombstoneStore
+}
+
+type Options struct {
+	// Path is the database file path, or ":memory:".
+	Path string
+
+	// TombstoneKey is the secret account tombstones are hashed with. Without
+	// it, tombstone lookups fail with store.ErrTombstonesDisabled and accounts
+	// are erased without tombstones.
+	TombstoneKey string
+
+	// Policies sets how each provider's accounts are kept current
+	// (default: store.DefaultProviderPolicies).
+	Policies store.ProviderPolicies
+}
+
+func New(opts Options) (*Store, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("sqlite database path is required")
+	}
+
+	var hasher *store.TombstoneHasher
+	if opts.TombstoneKey != "" {
+		h, err := store.NewTombstoneHasher(opts.TombstoneKey)
+		if err != nil {
+			return nil, err
+		}
+		hasher = h
+	}
+
+	policies := opts.Policies
+	if policies == nil {
+		policies = store.DefaultProviderPolicies()
+	}
+	if err := policies.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		path:            opts.Path,
+		tombstoneHasher: hasher,
+		policies:        policies,
+		userData:        &UserDataStore{},
+		tokens:          &TokenStore{},
+		audit:           &AuditStore{},
+		settings:        &SettingsStore{},
+		privacyActions:  &PrivacyActionStore{},
+		legalHolds:      &LegalHoldStore{},
+		inventory:       &InventoryStore{},
+		tombstones:      &AccountTombstoneStore{},
+	}, nil
+}
+
+// PathFromURL extracts the database path from a sqlite:// or file: URL.
+func PathFromURL(rawURL string) (string, error) {
+	for _, prefix := range []string{"sqlite://", "sqlite3://", "file:"} {
+		if path, ok := strings.CutPrefix(rawURL, prefix); ok {
+			path, _, _ = strings.Cut(path, "?")
+			unescaped, err := url.PathUnescape(path)
+			if err != nil {
+				return "", fmt.Errorf("invalid sqlite path: %w", err)
+			}
+			if unescaped == "" {
+				return "", fmt.Errorf("sqlite database path is required")
+			}
+			return unescaped, nil
+		}
+	}
+
+	return "", fmt.Errorf("not a sqlite url: %s", rawURL)
+}
+
+func (s *Store) Open(ctx context.Context) error {
+	if s.db != nil {
+		return nil
+	}
+
+	dsn := "file:" + s.path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+	db, err := sqlx.ConnectContext(ctx, "sqlite", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection serializes transactions and
+	// keeps ":memory:" databases shared.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return err
+	}
+
+	s.db = db
+	s.userData = &UserDataStore{db: db, hasher: s.tombstoneHasher, policies: s.policies}
+	s.tokens = &TokenStore{db: db}
+	s.audit = &AuditStore{db: db}
+	s.settings = &SettingsStore{db: db}
+	s.privacyActions = &PrivacyActionStore{db: db}
+	s.legalHolds = &LegalHoldStore{db: db}
+	s.inventory = &InventoryStore{db: db}
+	s.tombstones = &AccountTombstoneStore{db: db, hasher: s.tombstoneHasher}
+
+	return nil
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	return s.db.PingContext(ctx)
+}
+
+func (s *Store) Close(ctx context.Context) error {
+	if s.db == nil {
+		return nil
+	}
+
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+
+	s.db = nil
+	s.userData = &UserDataStore{}
+	s.tokens = &TokenStore{}
+	s.audit = &AuditStore{}
+	s.settings = &SettingsStore{}
+	s.privacyActions = &PrivacyActionStore{}
+	s.legalHolds = &LegalHoldStore{}
+	s.inventory = &InventoryStore{}
+	s.tombstones = &AccountTombstoneStore{}
+
+	return nil
+}
+
+func (s *Store) UserData() store.UserDataStore {
+	return s.userData
+}
+
+func (s *Store) Tokens() store.TokenStore {
+	return s.tokens
+}
+
+func (s *Store) Audit() store.AuditStore {
+	return s.audit
+}
+
+// rowsAffected reports the affected row count, treating an unsupported count as zero.
+func rowsAffected(result sql.Result) int {
+	rows, err := result.RowsAffected()
+	if err != nil || rows < 0 {
+		return 0
+	}
+	return int(rows)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func encodeStrings(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+func decodeStrings(value string) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, fmt.Errorf("decode string array: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,245 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/store/engine/sqlite"
+	"hourly/workers/reporter/internal/store/storetest"
+)
+
+// ts renders times the way the engine stores them.
+func ts(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+func tsPtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := ts(*t)
+	return &s
+}
+
+type fixtures struct {
+	db *sqlx.DB
+}
+
+func (f fixtures) CreateProfile(t *testing.T, p storetest.Profile) {
+	updatedAt := p.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	if _, err := f.db.Exec(
+		`INSERT INTO profiles (id, provider, created_at, updated_at, reported_at, deleted_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Provider, ts(updatedAt), ts(updatedAt), tsPtr(p.ReportedAt), tsPtr(p.DeletedAt),
+	); err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+}
+
+func (f fixtures) CreateToken(t *testing.T, token store.Token) {
+	var refresh *string
+	if token.RefreshToken != "" {
+		refresh = &token.RefreshToken
+	}
+
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	encoded, err := json.Marshal(scopes)
+	if err != nil {
+		t.Fatalf("encode scopes: %v", err)
+	}
+
+	now := ts(time.Now())
+	if _, err := f.db.Exec(
+		`INSERT INTO tokens (profile_id, provider, access_token, refresh_token, expires_at, scopes, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ProfileID, token.Provider, token.AccessToken, refresh, tsPtr(token.ExpiresAt), string(encoded), now, now,
+	); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+}
+
+func (f fixtures) CreateSession(t *testing.T, s storetest.Session) {
+	data, err := json.Marshal(sessionData(s.Data))
+	if err != nil {
+		t.Fatalf("encode session data: %v", err)
+	}
+
+	updatedAt := ts(s.UpdatedAt)
+	if _, err := f.db.Exec(
+		`INSERT INTO sessions (id, data, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		s.ID, string(data), updatedAt, updatedAt,
+	); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	for _, p := range s.Profiles {
+		if _, err := f.db.Exec(
+			`INSERT INTO profiles_on_sessions (profile_id, profile_provider, session_id, connection_type, created_at, updated_at) VALUES (?, ?, ?, 'data-source', ?, ?)`,
+			p.ID, p.Provider, s.ID, updatedAt, updatedAt,
+		); err != nil {
+			t.Fatalf("link session: %v", err)
+		}
+	}
+}
+
+func (f fixtures) LoadSession(t *testing.T, id string) *storetest.Session {
+	var data string
+	if err := f.db.Get(&data, `SELECT data FROM sessions WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		t.Fatalf("load session: %v", err)
+	}
+
+	session := &storetest.Session{ID: id}
+	if err := json.Unmarshal([]byte(data), &session.Data); err != nil {
+		t.Fatalf("decode session data: %v", err)
+	}
+
+	var links []struct {
+		ProfileID string `db:"profile_id"`
+		Provider  string `db:"profile_provider"`
+	}
+	if err := f.db.Select(&links, `SELECT profile_id, profile_provider FROM profiles_on_sessions WHERE session_id = ? ORDER BY profile_provider, profile_id`, id); err != nil {
+		t.Fatalf("load session links: %v", err)
+	}
+	for _, link := range links {
+		session.Profiles = append(session.Profiles, storetest.Profile{ID: link.ProfileID, Provider: link.Provider})
+	}
+
+	return session
+}
+
+func sessionData(data map[string]any) map[string]any {
+	if data == nil {
+		return map[string]any{}
+	}
+	return data
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, storetest.Fixtures) {
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "reporter.db")
+
+		st, err := sqlite.New(sqlite.Options{Path: path, TombstoneKey: storetest.TombstoneKey, Policies: storetest.Policies()})
+		if err != nil {
+			t.Fatalf("new store: %v", err)
+		}
+		if err := st.Open(ctx); err != nil {
+			t.Fatalf("open store: %v", err)
+		}
+		t.Cleanup(func() { st.Close(ctx) })
+
+		db, err := sqlx.Connect("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return st, fixtures{db: db}
+	})
+}
+
+// TestWithoutTombstoneKey covers a store created without a tombstone key:
+// lookups fail, and accounts are still erased, without tombstones and with
+// their token history deleted rather than pseudonymized.
+func TestWithoutTombstoneKey(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "reporter.db")
+
+	st, err := sqlite.New(sqlite.Options{Path: path})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := st.Open(ctx); err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close(ctx) })
+
+	db, err := sqlx.Connect("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	f := fixtures{db: db}
+
+	deletedAt := time.Now().Add(-time.Hour)
+	f.CreateProfile(t, storetest.Profile{ID: "deleted", Provider: store.ProviderAtlassian, DeletedAt: &deletedAt})
+
+	for _, id := range []string{"closed", "closed-1", "closed-2"} {
+		f.CreateProfile(t, storetest.Profile{ID: id, Provider: store.ProviderAtlassian})
+		f.CreateToken(t, store.Token{ProfileID: id, Provider: store.ProviderAtlassian, AccessToken: "a"})
+		if err := st.Tokens().RecordTokenEvent(ctx, &store.TokenEvent{
+			ProfileID: id,
+			Provider:  store.ProviderAtlassian,
+			Type:      store.TokenEventRefreshed,
+			Actor:     store.TokenActorWorker,
+		}); err != nil {
+			t.Fatalf("RecordTokenEvent: %v", err)
+		}
+	}
+
+	if _, err := st.AccountTombstones().FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"closed"},
+	}); !errors.Is(err, store.ErrTombstonesDisabled) {
+		t.Fatalf("FilterTombstoned = %v, want ErrTombstonesDisabled", err)
+	}
+
+	erased, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{
+		Provider:  store.ProviderAtlassian,
+		AccountID: "closed",
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+	if !erased.Items.Untombstoned || erased.Items.Profiles != 1 || erased.Items.Tokens != 1 {
+		t.Fatalf("DeleteUserData items = %+v, want the account erased without a tombstone", erased.Items)
+	}
+
+	batch, err := st.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"closed-1", "closed-2"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserDataBatch: %v", err)
+	}
+	for _, result := range batch.Results {
+		if !result.Items.Untombstoned || result.Items.Profiles != 1 {
+			t.Fatalf("DeleteUserDataBatch %s items = %+v, want the account erased without a tombstone", result.AccountID, result.Items)
+		}
+	}
+
+	for _, table := range []string{"token_events", "account_tombstones"} {
+		var n int
+		if err := db.Get(&n, `SELECT count(*) FROM `+table); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if n != 0 {
+			t.Fatalf("%s has %d rows, want none", table, n)
+		}
+	}
+
+	out, err := st.UserData().PurgeDeletedProfiles(ctx, &store.PurgeDeletedProfilesInput{DeletedBefore: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("PurgeDeletedProfiles: %v", err)
+	}
+	if out.Purged != 1 {
+		t.Fatalf("purged %d profiles, want 1", out.Purged)
+	}
+}
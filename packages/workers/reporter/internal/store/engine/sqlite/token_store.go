@@ -0,0 +1,766 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+type TokenStore struct {
+	db *sqlx.DB
+
+	// refreshMu serializes RefreshToken; a SQLite database is not shared
+	// between worker replicas.
+	refreshMu sync.Mutex
+}
+
+const getTokenQuery = `
+SELECT
+	t.access_token,
+	t.refresh_token,
+	t.expires_at,
+	t.scopes
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	t.profile_id = ?
+	AND t.provider = ?
+	AND p.deleted_at IS NULL`
+
+const listLinkedTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	t.access_token,
+	t.refresh_token,
+	t.expires_at,
+	t.scopes
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	p.deleted_at IS NULL
+	AND (
+		(t.profile_id = ?1 AND t.provider = ?2)
+		OR EXISTS (
+			SELECT
+				1
+			FROM
+				profiles_on_sessions own
+				JOIN profiles_on_sessions l ON l.session_id = own.session_id
+			WHERE
+				own.profile_id = ?1
+				AND own.profile_provider = ?2
+				AND l.profile_id = t.profile_id
+				AND l.profile_provider = t.provider
+		)
+	)
+ORDER BY
+	t.provider,
+	t.profile_id`
+
+const getRefreshableTokenQuery = `
+SELECT
+	t.access_token,
+	t.refresh_token,
+	t.expires_at,
+	t.scopes
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	t.profile_id = ?
+	AND t.provider = ?
+	AND p.deleted_at IS NULL
+	AND t.refresh_token IS NOT NULL
+	AND t.refresh_token <> ''`
+
+const lockTokenQuery = `
+SELECT
+	refresh_token,
+	expires_at,
+	scopes
+FROM
+	tokens
+WHERE
+	profile_id = ?
+	AND provider = ?`
+
+const updateTokenQuery = `
+UPDATE
+	tokens
+SET
+	access_token = ?,
+	refresh_token = ?,
+	expires_at = ?,
+	scopes = ?,
+	updated_at = ?
+WHERE
+	profile_id = ?
+	AND provider = ?`
+
+const insertTokenEventQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	new_expires_at,
+	scopes_added,
+	scopes_removed,
+	error_class,
+	created_at
+) VALUES (
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)`
+
+const selectTokenHistoryQuery = `
+SELECT
+	id,
+	event_type,
+	actor,
+	old_expires_at,
+	new_expires_at,
+	scopes_added,
+	scopes_removed,
+	error_class,
+	created_at
+FROM
+	token_events
+WHERE
+	profile_id = ?
+	AND provider = ?
+ORDER BY
+	created_at DESC,
+	rowid DESC
+LIMIT ?`
+
+const listDormantTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	COALESCE(MAX(s.updated_at), t.updated_at) AS last_active_at
+FROM
+	tokens t
+	JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+	LEFT JOIN profiles_on_sessions pos ON pos.profile_id = t.profile_id AND pos.profile_provider = t.provider
+	LEFT JOIN sessions s ON s.id = pos.session_id
+WHERE
+	p.deleted_at IS NULL
+	AND t.profile_id NOT IN (SELECT value FROM json_each(?1))
+GROUP BY
+	t.profile_id,
+	t.provider,
+	t.updated_at
+HAVING
+	COALESCE(MAX(s.updated_at), t.updated_at) < ?2
+	AND (
+		?4 IS NULL
+		OR (COALESCE(MAX(s.updated_at), t.updated_at), t.provider, t.profile_id) > (?4, ?5, ?6)
+	)
+ORDER BY
+	last_active_at,
+	t.provider,
+	t.profile_id
+LIMIT ?3`
+
+const recordDeletedTokenQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	scopes_removed,
+	created_at
+)
+SELECT
+	profile_id,
+	provider,
+	'deleted',
+	?,
+	expires_at,
+	scopes,
+	?
+FROM
+	tokens
+WHERE
+	profile_id = ?
+	AND provider = ?`
+
+const deleteTokenQuery = `
+DELETE FROM
+	tokens
+WHERE
+	profile_id = ?
+	AND provider = ?`
+
+const listTokensQuery = `
+SELECT
+	t.profile_id,
+	t.provider,
+	t.refresh_token IS NOT NULL AND t.refresh_token <> '' AS has_refresh_token,
+	t.expires_at,
+	t.scopes,
+	t.updated_at,
+	p.deleted_at AS profile_deleted_at
+FROM
+	tokens t
+	LEFT JOIN profiles p ON p.id = t.profile_id AND p.provider = t.provider
+WHERE
+	?2 IS NULL
+	OR (t.provider, t.profile_id) > (?2, ?3)
+ORDER BY
+	t.provider,
+	t.profile_id
+LIMIT ?1`
+
+const insertTokenHealthReportQuery = `
+INSERT INTO token_health_reports (
+	run_id,
+	total,
+	healthy,
+	expiring_soon,
+	expired,
+	scopes_drifted,
+	profile_deleted,
+	created_at
+) VALUES (
+	?, ?, ?, ?, ?, ?, ?, ?
+)`
+
+const (
+	defaultTokensPage       = 500
+	defaultTokenHistoryPage = 100
+)
+
+func (s *TokenStore) GetToken(ctx context.Context, input *store.GetTokenInput) (*store.Token, error) {
+	return s.fetchToken(ctx, getTokenQuery, input)
+}
+
+func (s *TokenStore) GetRefreshableToken(ctx context.Context, input *store.GetTokenInput) (*store.Token, error) {
+	return s.fetchToken(ctx, getRefreshableTokenQuery, input)
+}
+
+func (s *TokenStore) fetchToken(ctx context.Context, query string, input *store.GetTokenInput) (*store.Token, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	var row struct {
+		AccessToken  string         `db:"access_token"`
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullString `db:"expires_at"`
+		Scopes       string         `db:"scopes"`
+	}
+
+	err := s.db.GetContext(ctx, &row, query, input.ProfileID, input.Provider)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+
+	expires, err := parseNullTime(row.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := decodeStrings(row.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.Token{
+		ProfileID:    input.ProfileID,
+		Provider:     input.Provider,
+		AccessToken:  row.AccessToken,
+		RefreshToken: row.RefreshToken.String,
+		ExpiresAt:    expires,
+		Scopes:       scopes,
+	}, nil
+}
+
+func (s *TokenStore) ListLinkedTokens(ctx context.Context, input *store.GetTokenInput) ([]store.Token, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	var rows []struct {
+		ProfileID    string         `db:"profile_id"`
+		Provider     string         `db:"provider"`
+		AccessToken  string         `db:"access_token"`
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullString `db:"expires_at"`
+		Scopes       string         `db:"scopes"`
+	}
+
+	if err := s.db.SelectContext(ctx, &rows, listLinkedTokensQuery, input.ProfileID, input.Provider); err != nil {
+		return nil, fmt.Errorf("list linked tokens: %w", err)
+	}
+
+	tokens := make([]store.Token, 0, len(rows))
+	for _, row := range rows {
+		expires, err := parseNullTime(row.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+
+		scopes, err := decodeStrings(row.Scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, store.Token{
+			ProfileID:    row.ProfileID,
+			Provider:     row.Provider,
+			AccessToken:  row.AccessToken,
+			RefreshToken: row.RefreshToken.String,
+			ExpiresAt:    expires,
+			Scopes:       scopes,
+		})
+	}
+
+	return tokens, nil
+}
+
+func (s *TokenStore) UpdateToken(ctx context.Context, input *store.UpdateTokenInput) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if input == nil {
+		return fmt.Errorf("input is required")
+	}
+
+	if input.ProfileID == "" || input.Provider == "" || input.AccessToken == "" {
+		return fmt.Errorf("profile id, provider, and access token are required")
+	}
+
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	refresh := sql.NullString{String: input.RefreshToken, Valid: input.RefreshToken != ""}
+	expires := nullTime(input.ExpiresAt)
+	now := formatTime(time.Now())
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var prev struct {
+		RefreshToken sql.NullString `db:"refresh_token"`
+		ExpiresAt    sql.NullString `db:"expires_at"`
+		Scopes       string         `db:"scopes"`
+	}
+
+	if err := tx.GetContext(ctx, &prev, lockTokenQuery, input.ProfileID, input.Provider); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("token not found for profile %s and provider %s", input.ProfileID, input.Provider)
+		}
+		return fmt.Errorf("lock token: %w", err)
+	}
+
+	prevScopes, err := decodeStrings(prev.Scopes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		updateTokenQuery,
+		input.AccessToken,
+		refresh,
+		expires,
+		encodeStrings(input.Scopes),
+		now,
+		input.ProfileID,
+		input.Provider,
+	); err != nil {
+		return fmt.Errorf("update token: %w", err)
+	}
+
+	eventType := store.TokenEventRefreshed
+	if prev.RefreshToken != refresh {
+		eventType = store.TokenEventRotated
+	}
+
+	added, removed := store.DiffScopes(prevScopes, input.Scopes)
+
+	if _, err := tx.ExecContext(
+		ctx,
+		insertTokenEventQuery,
+		input.ProfileID,
+		input.Provider,
+		eventType,
+		actor,
+		prev.ExpiresAt,
+		expires,
+		encodeStrings(added),
+		encodeStrings(removed),
+		sql.NullString{},
+		now,
+	); err != nil {
+		return fmt.Errorf("record token event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit token update: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) RefreshToken(ctx context.Context, input *store.RefreshTokenInput) (*store.Token, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" || input.Exchange == nil {
+		return nil, fmt.Errorf("profile id, provider, and exchange are required")
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	token, err := s.GetToken(ctx, &store.GetTokenInput{ProfileID: input.ProfileID, Provider: input.Provider})
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	update, err := input.Exchange(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if update == nil {
+		return token, nil
+	}
+
+	update.ProfileID = input.ProfileID
+	update.Provider = input.Provider
+	if err := s.UpdateToken(ctx, update); err != nil {
+		return nil, err
+	}
+
+	return &store.Token{
+		ProfileID:    update.ProfileID,
+		Provider:     update.Provider,
+		AccessToken:  update.AccessToken,
+		RefreshToken: update.RefreshToken,
+		ExpiresAt:    update.ExpiresAt,
+		Scopes:       update.Scopes,
+	}, nil
+}
+
+func (s *TokenStore) RecordTokenEvent(ctx context.Context, event *store.TokenEvent) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if event == nil || event.ProfileID == "" || event.Provider == "" || event.Type == "" {
+		return fmt.Errorf("profile id, provider, and event type are required")
+	}
+
+	actor := event.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertTokenEventQuery,
+		event.ProfileID,
+		event.Provider,
+		event.Type,
+		actor,
+		nullTime(event.OldExpiresAt),
+		nullTime(event.NewExpiresAt),
+		encodeStrings(event.ScopesAdded),
+		encodeStrings(event.ScopesRemoved),
+		sql.NullString{String: event.ErrorClass, Valid: event.ErrorClass != ""},
+		formatTime(time.Now()),
+	); err != nil {
+		return fmt.Errorf("record token event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) GetTokenHistory(ctx context.Context, input *store.GetTokenHistoryInput) ([]store.TokenEvent, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return nil, fmt.Errorf("profile id and provider are required")
+	}
+
+	limit := defaultTokenHistoryPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var rows []struct {
+		ID            string         `db:"id"`
+		EventType     string         `db:"event_type"`
+		Actor         string         `db:"actor"`
+		OldExpiresAt  sql.NullString `db:"old_expires_at"`
+		NewExpiresAt  sql.NullString `db:"new_expires_at"`
+		ScopesAdded   string         `db:"scopes_added"`
+		ScopesRemoved string         `db:"scopes_removed"`
+		ErrorClass    sql.NullString `db:"error_class"`
+		CreatedAt     string         `db:"created_at"`
+	}
+
+	if err := s.db.SelectContext(ctx, &rows, selectTokenHistoryQuery, input.ProfileID, input.Provider, limit); err != nil {
+		return nil, fmt.Errorf("get token history: %w", err)
+	}
+
+	events := make([]store.TokenEvent, 0, len(rows))
+	for _, row := range rows {
+		event := store.TokenEvent{
+			ID:         row.ID,
+			ProfileID:  input.ProfileID,
+			Provider:   input.Provider,
+			Type:       store.TokenEventType(row.EventType),
+			Actor:      row.Actor,
+			ErrorClass: row.ErrorClass.String,
+		}
+
+		var err error
+		if event.OldExpiresAt, err = parseNullTime(row.OldExpiresAt); err != nil {
+			return nil, err
+		}
+		if event.NewExpiresAt, err = parseNullTime(row.NewExpiresAt); err != nil {
+			return nil, err
+		}
+		if event.ScopesAdded, err = decodeStrings(row.ScopesAdded); err != nil {
+			return nil, err
+		}
+		if event.ScopesRemoved, err = decodeStrings(row.ScopesRemoved); err != nil {
+			return nil, err
+		}
+		if event.CreatedAt, err = parseTime(row.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (s *TokenStore) ListTokens(ctx context.Context, input *store.ListTokensInput) (*store.ListTokensOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	limit := defaultTokensPage
+	var afterProvider, afterID *string
+
+	if input != nil {
+		if input.Limit > 0 {
+			limit = input.Limit
+		}
+		if input.After != nil {
+			afterProvider, afterID = &input.After.Provider, &input.After.ProfileID
+		}
+	}
+
+	var rows []struct {
+		ProfileID        string         `db:"profile_id"`
+		Provider         string         `db:"provider"`
+		HasRefreshToken  bool           `db:"has_refresh_token"`
+		ExpiresAt        sql.NullString `db:"expires_at"`
+		Scopes           string         `db:"scopes"`
+		UpdatedAt        string         `db:"updated_at"`
+		ProfileDeletedAt sql.NullString `db:"profile_deleted_at"`
+	}
+
+	// Fetch one extra row to learn whether another page exists without counting.
+	if err := s.db.SelectContext(ctx, &rows, listTokensQuery, limit+1, afterProvider, afterID); err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	tokens := make([]store.TokenInfo, 0, len(rows))
+	for _, row := range rows {
+		info := store.TokenInfo{
+			ProfileID:       row.ProfileID,
+			Provider:        row.Provider,
+			HasRefreshToken: row.HasRefreshToken,
+		}
+
+		var err error
+		if info.ExpiresAt, err = parseNullTime(row.ExpiresAt); err != nil {
+			return nil, err
+		}
+		if info.Scopes, err = decodeStrings(row.Scopes); err != nil {
+			return nil, err
+		}
+		if info.UpdatedAt, err = parseTime(row.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if info.ProfileDeletedAt, err = parseNullTime(row.ProfileDeletedAt); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, info)
+	}
+
+	return &store.ListTokensOutput{
+		Tokens:  tokens,
+		HasMore: hasMore,
+	}, nil
+}
+
+func (s *TokenStore) ListDormantTokens(ctx context.Context, input *store.ListDormantTokensInput) ([]store.DormantToken, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.InactiveSince.IsZero() {
+		return nil, fmt.Errorf("inactive since is required")
+	}
+
+	limit := defaultTokensPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	var (
+		afterAt                sql.NullString
+		afterProvider, afterID string
+	)
+	if input.After != nil {
+		afterAt = nullTime(&input.After.LastActiveAt)
+		afterProvider, afterID = input.After.Provider, input.After.ProfileID
+	}
+
+	var rows []struct {
+		ProfileID    string `db:"profile_id"`
+		Provider     string `db:"provider"`
+		LastActiveAt string `db:"last_active_at"`
+	}
+
+	if err := s.db.SelectContext(
+		ctx,
+		&rows,
+		listDormantTokensQuery,
+		encodeStrings(input.ExcludeProfileIDs),
+		formatTime(input.InactiveSince),
+		limit,
+		afterAt,
+		afterProvider,
+		afterID,
+	); err != nil {
+		return nil, fmt.Errorf("list dormant tokens: %w", err)
+	}
+
+	tokens := make([]store.DormantToken, 0, len(rows))
+	for _, row := range rows {
+		lastActive, err := parseTime(row.LastActiveAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, store.DormantToken{
+			ProfileID:    row.ProfileID,
+			Provider:     row.Provider,
+			LastActiveAt: lastActive,
+		})
+	}
+
+	return tokens, nil
+}
+
+func (s *TokenStore) DeleteToken(ctx context.Context, input *store.DeleteTokenInput) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.ProfileID == "" || input.Provider == "" {
+		return fmt.Errorf("profile id and provider are required")
+	}
+
+	actor := input.Actor
+	if actor == "" {
+		actor = store.TokenActorWorker
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, recordDeletedTokenQuery, actor, formatTime(time.Now()), input.ProfileID, input.Provider); err != nil {
+		return fmt.Errorf("record token event: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteTokenQuery, input.ProfileID, input.Provider); err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit token deletion: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenStore) CreateTokenHealthReport(ctx context.Context, report *store.TokenHealthReport) error {
+	if s.db == nil {
+		return fmt.Errorf("store not opened")
+	}
+
+	if report == nil {
+		return fmt.Errorf("report is required")
+	}
+
+	createdAt := report.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if _, err := s.db.ExecContext(
+		ctx,
+		insertTokenHealthReportQuery,
+		report.RunID,
+		report.Total,
+		report.Healthy,
+		report.ExpiringSoon,
+		report.Expired,
+		report.ScopesDrifted,
+		report.ProfileDeleted,
+		formatTime(createdAt),
+	); err != nil {
+		return fmt.Errorf("insert token health report: %w", err)
+	}
+
+	return nil
+}
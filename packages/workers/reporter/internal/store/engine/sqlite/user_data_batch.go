@@ -0,0 +1,325 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+const (
+	// deferErasuresQuery is deferErasureQuery for a set of accounts given by
+	// ?2 (JSON array of account ids).
+	deferErasuresQuery = `
+UPDATE legal_holds SET
+	erasure_deferred_at = COALESCE(erasure_deferred_at, ?3)
+WHERE
+	provider = ?1
+	AND account_id IN (SELECT value FROM json_each(?2))
+	AND released_at IS NULL
+	AND (expires_at IS NULL OR expires_at > ?3)
+RETURNING` + legalHoldColumns
+
+	recordDeletedAccountTokensQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	scopes_removed,
+	created_at
+)
+SELECT
+	profile_id,
+	provider,
+	'deleted',
+	'worker',
+	expires_at,
+	scopes,
+	?
+FROM
+	tokens
+WHERE
+	provider = ?
+	AND profile_id IN (SELECT value FROM json_each(?))`
+
+	deleteAccountTokensQuery = `
+DELETE FROM
+	tokens
+WHERE
+	provider = ?
+	AND profile_id IN (SELECT value FROM json_each(?))
+RETURNING
+	profile_id`
+
+	deleteAccountSessionLinksQuery = `
+DELETE FROM
+	profiles_on_sessions
+WHERE
+	profile_provider = ?
+	AND profile_id IN (SELECT value FROM json_each(?))
+RETURNING
+	profile_id,
+	session_id`
+
+	deleteOrphanedAccountSessionsQuery = `
+DELETE FROM
+	sessions
+WHERE
+	id IN (SELECT value FROM json_each(?))
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			profiles_on_sessions pos
+		WHERE
+			pos.session_id = sessions.id
+	)
+RETURNING
+	id`
+
+	scrubAccountSessionsQuery = `
+UPDATE
+	sessions
+SET
+	data = json_remove(data, ?2),
+	updated_at = ?3
+WHERE
+	id IN (SELECT value FROM json_each(?1))
+	AND json_extract(data, ?2) IS NOT NULL
+RETURNING
+	id`
+
+	softDeleteAccountsQuery = `
+UPDATE
+	profiles
+SET
+	reported_at = ?1,
+	deleted_at = ?1,
+	updated_at = ?1
+WHERE
+	provider = ?2
+	AND id IN (SELECT value FROM json_each(?3))
+RETURNING
+	id`
+
+	resolveAccountsDeferredErasuresQuery = `
+UPDATE legal_holds SET
+	erasure_resolved_at = ?3
+WHERE
+	provider = ?1
+	AND account_id IN (SELECT value FROM json_each(?2))
+	AND erasure_deferred_at IS NOT NULL
+	AND erasure_resolved_at IS NULL`
+
+	// insertAccountTombstonesQuery needs its WHERE clause for SQLite to parse
+	// the upsert after a SELECT.
+	insertAccountTombstonesQuery = `
+INSERT INTO account_tombstones (
+	provider,
+	id_hash,
+	closed_at
+)
+SELECT
+	?1, value, ?3
+FROM
+	json_each(?2)
+WHERE
+	true
+ON CONFLICT (provider, id_hash) DO NOTHING`
+
+	refreshAccountsQuery = `
+UPDATE
+	profiles
+SET
+	updated_at = ?
+WHERE
+	provider = ?
+	AND id IN (SELECT value FROM json_each(?))
+	AND deleted_at IS NULL
+RETURNING
+	id`
+)
+
+func (s *UserDataStore) DeleteUserDataBatch(ctx context.Context, input *store.DeleteUserDataBatchInput) (*store.DeleteUserDataBatchOutput, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return &store.DeleteUserDataBatchOutput{Results: []store.DeleteUserDataResult{}}, nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+	stamp := formatTime(now)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var holds []legalHoldRow
+	if err := tx.SelectContext(ctx, &holds, deferErasuresQuery, input.Provider, encodeStrings(accountIDs), stamp); err != nil {
+		return nil, fmt.Errorf("defer erasures: %w", err)
+	}
+	holdIDs := make(map[string]string, len(holds))
+	for _, hold := range holds {
+		if _, ok := holdIDs[hold.AccountID]; !ok {
+			holdIDs[hold.AccountID] = hold.ID
+		}
+	}
+
+	var erase []string
+	for _, id := range accountIDs {
+		if _, held := holdIDs[id]; !held && id != "" {
+			erase = append(erase, id)
+		}
+	}
+
+	items := make(map[string]*store.ErasedItems, len(erase))
+	for _, id := range erase {
+		items[id] = &store.ErasedItems{}
+	}
+
+	if len(erase) > 0 {
+		ids := encodeStrings(erase)
+
+		if _, err := tx.ExecContext(ctx, recordDeletedAccountTokensQuery, stamp, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("record deleted tokens: %w", err)
+		}
+
+		var tokenOwners []string
+		if err := tx.SelectContext(ctx, &tokenOwners, deleteAccountTokensQuery, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("delete tokens: %w", err)
+		}
+		for _, id := range tokenOwners {
+			items[id].Tokens++
+		}
+
+		// Without a tombstone key the history cannot be pseudonymized, so it
+		// is deleted, and the accounts are erased without tombstones.
+		var hashes []string
+		if s.hasher != nil {
+			hashes = make([]string, len(erase))
+		}
+		for i, id := range erase {
+			if hashes == nil {
+				if _, err := tx.ExecContext(ctx, deleteAccountTokenEventsQuery, input.Provider, id); err != nil {
+					return nil, fmt.Errorf("delete token history of account %s: %w", id, err)
+				}
+				items[id].Untombstoned = true
+				continue
+			}
+
+			hashes[i] = s.hasher.Hash(input.Provider, id)
+			if _, err := tx.ExecContext(ctx, pseudonymizeTokenEventsQuery, input.Provider, id, hashes[i]); err != nil {
+				return nil, fmt.Errorf("pseudonymize token history of account %s: %w", id, err)
+			}
+		}
+
+		var links []struct {
+			ProfileID string `db:"profile_id"`
+			SessionID string `db:"session_id"`
+		}
+		if err := tx.SelectContext(ctx, &links, deleteAccountSessionLinksQuery, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("delete session links: %w", err)
+		}
+
+		if len(links) > 0 {
+			sessionIDs := make([]string, 0, len(links))
+			for _, link := range links {
+				items[link.ProfileID].SessionLinks++
+				sessionIDs = append(sessionIDs, link.SessionID)
+			}
+			sessions := encodeStrings(sessionIDs)
+
+			var deleted []string
+			if err := tx.SelectContext(ctx, &deleted, deleteOrphanedAccountSessionsQuery, sessions); err != nil {
+				return nil, fmt.Errorf("delete sessions: %w", err)
+			}
+
+			var scrubbed []string
+			if err := tx.SelectContext(ctx, &scrubbed, scrubAccountSessionsQuery, sessions, "$.user.oauth."+input.Provider, stamp); err != nil {
+				return nil, fmt.Errorf("scrub sessions: %w", err)
+			}
+
+			deletedSet := make(map[string]bool, len(deleted))
+			for _, id := range deleted {
+				deletedSet[id] = true
+			}
+			scrubbedSet := make(map[string]bool, len(scrubbed))
+			for _, id := range scrubbed {
+				scrubbedSet[id] = true
+			}
+			for _, link := range links {
+				if deletedSet[link.SessionID] {
+					items[link.ProfileID].Sessions++
+				}
+				if scrubbedSet[link.SessionID] {
+					items[link.ProfileID].SessionsScrubbed++
+				}
+			}
+		}
+
+		var profiles []string
+		if err := tx.SelectContext(ctx, &profiles, softDeleteAccountsQuery, stamp, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("soft delete accounts: %w", err)
+		}
+		for _, id := range profiles {
+			items[id].Profiles++
+		}
+
+		if _, err := tx.ExecContext(ctx, resolveAccountsDeferredErasuresQuery, input.Provider, ids, stamp); err != nil {
+			return nil, fmt.Errorf("resolve deferred erasures: %w", err)
+		}
+
+		if hashes != nil {
+			if _, err := tx.ExecContext(ctx, insertAccountTombstonesQuery, input.Provider, encodeStrings(hashes), stamp); err != nil {
+				return nil, fmt.Errorf("tombstone accounts: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit erasures: %w", err)
+	}
+
+	return store.NewDeleteUserDataBatchOutput(accountIDs, now, holdIDs, items), nil
+}
+
+func (s *UserDataStore) RefreshUserDataBatch(ctx context.Context, input *store.RefreshUserDataBatchInput) (*store.RefreshUserDataBatchOutput, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return &store.RefreshUserDataBatchOutput{Results: []store.RefreshUserDataResult{}}, nil
+	}
+
+	policy, err := s.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+
+	var refreshed []string
+	if policy.Refresh != store.RefreshNone {
+		if err := s.db.SelectContext(ctx, &refreshed, refreshAccountsQuery, formatTime(now), input.Provider, encodeStrings(accountIDs)); err != nil {
+			return nil, fmt.Errorf("refresh accounts: %w", err)
+		}
+	}
+
+	return store.NewRefreshUserDataBatchOutput(accountIDs, now, refreshed), nil
+}
@@ -0,0 +1,616 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hourly/workers/reporter/internal/domain"
+	"hourly/workers/reporter/internal/store"
+)
+
+type UserDataStore struct {
+	db       *sqlx.DB
+	hasher   *store.TombstoneHasher
+	policies store.ProviderPolicies
+}
+
+const (
+	defaultAccountsPage = 1000
+	defaultPurgeBatch   = 100
+
+	// reportedChunkSize bounds the account IDs sent per UpdateLastReported
+	// statement.
+	reportedChunkSize = 1000
+)
+
+const (
+	countAccountsQuery = `
+SELECT
+	COUNT(*)
+FROM
+	personal_data_inventory
+WHERE
+	provider = ?1
+	AND (
+		reported_at IS NULL
+		OR reported_at <= ?2
+	)
+	AND (
+		?3 IS NULL
+		OR account_id IN (SELECT value FROM json_each(?3))
+	)`
+
+	selectAccountsQuery = `
+SELECT
+	account_id,
+	data_retrieved_at AS updated_at
+FROM
+	personal_data_inventory
+WHERE
+	provider = ?1
+	AND (
+		reported_at IS NULL
+		OR reported_at <= ?2
+	)
+	AND (
+		?3 IS NULL
+		OR account_id IN (SELECT value FROM json_each(?3))
+	)
+ORDER BY
+	data_retrieved_at DESC,
+	account_id
+LIMIT ?4`
+
+	selectAccountsAfterQuery = `
+SELECT
+	account_id,
+	data_retrieved_at AS updated_at
+FROM
+	personal_data_inventory
+WHERE
+	provider = ?1
+	AND (
+		reported_at IS NULL
+		OR reported_at <= ?2
+	)
+	AND (
+		?3 IS NULL
+		OR account_id IN (SELECT value FROM json_each(?3))
+	)
+	AND (
+		data_retrieved_at < ?5
+		OR (data_retrieved_at = ?5 AND account_id > ?6)
+	)
+ORDER BY
+	data_retrieved_at DESC,
+	account_id
+LIMIT ?4`
+
+	updateReportedAtQuery = `
+UPDATE
+	profiles
+SET
+	reported_at = ?
+WHERE
+	provider = ?
+	AND id IN (SELECT value FROM json_each(?))
+	AND deleted_at IS NULL`
+
+	updateInventoryReportedAtQuery = `
+UPDATE
+	personal_data_inventory
+SET
+	reported_at = ?
+WHERE
+	provider = ?
+	AND account_id IN (SELECT value FROM json_each(?))
+RETURNING
+	account_id`
+
+	recordDeletedTokensQuery = `
+INSERT INTO token_events (
+	profile_id,
+	provider,
+	event_type,
+	actor,
+	old_expires_at,
+	scopes_removed,
+	created_at
+)
+SELECT
+	profile_id,
+	provider,
+	'deleted',
+	'worker',
+	expires_at,
+	scopes,
+	?
+FROM
+	tokens
+WHERE
+	provider = ?
+	AND profile_id = ?`
+
+	deleteTokensQuery = `
+DELETE FROM
+	tokens
+WHERE
+	provider = ?
+	AND profile_id = ?`
+
+	// pseudonymizeTokenEventsQuery moves the account's token history under its
+	// keyed tombstone hash (?3), so that it no longer names the account.
+	pseudonymizeTokenEventsQuery = `
+UPDATE
+	token_events
+SET
+	profile_id = ?3
+WHERE
+	provider = ?1
+	AND profile_id = ?2`
+
+	deleteAccountTokenEventsQuery = `
+DELETE FROM
+	token_events
+WHERE
+	provider = ?1
+	AND profile_id = ?2`
+
+	deleteSessionLinksQuery = `
+DELETE FROM
+	profiles_on_sessions
+WHERE
+	profile_provider = ?
+	AND profile_id = ?
+RETURNING
+	session_id`
+
+	deleteOrphanedSessionsQuery = `
+DELETE FROM
+	sessions
+WHERE
+	id IN (SELECT value FROM json_each(?))
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			profiles_on_sessions pos
+		WHERE
+			pos.session_id = sessions.id
+	)`
+
+	scrubSessionsQuery = `
+UPDATE
+	sessions
+SET
+	data = json_remove(data, ?2),
+	updated_at = ?3
+WHERE
+	id IN (SELECT value FROM json_each(?1))
+	AND json_extract(data, ?2) IS NOT NULL`
+
+	softDeleteAccountQuery = `
+UPDATE
+	profiles
+SET
+	reported_at = ?,
+	deleted_at = ?,
+	updated_at = ?
+WHERE
+	provider = ?
+	AND id = ?`
+
+	selectPurgeableProfilesQuery = `
+SELECT
+	id,
+	provider,
+	deleted_at
+FROM
+	profiles
+WHERE
+	deleted_at < ?1
+	AND (
+		?3 = ''
+		OR provider = ?3
+	)
+ORDER BY
+	deleted_at,
+	provider,
+	id
+LIMIT ?2`
+
+	insertTombstoneQuery = `
+INSERT INTO profile_tombstones (
+	provider,
+	id_hash,
+	deleted_at,
+	purged_at
+) VALUES (
+	?1, ?2, ?3, ?4
+)
+ON CONFLICT (provider, id_hash) DO UPDATE SET
+	deleted_at = excluded.deleted_at,
+	purged_at = excluded.purged_at`
+
+	// deleteTokenEventsQuery deletes the profile's token history, whether still
+	// under its id (?2) or pseudonymized under its tombstone hash (?3).
+	deleteTokenEventsQuery = `
+DELETE FROM
+	token_events
+WHERE
+	provider = ?1
+	AND profile_id IN (?2, ?3)`
+
+	purgeProfileQuery = `
+DELETE FROM
+	profiles
+WHERE
+	provider = ?
+	AND id = ?
+	AND deleted_at IS NOT NULL`
+
+	refreshAccountQuery = `
+UPDATE
+	profiles
+SET
+	updated_at = ?
+WHERE
+	provider = ?
+	AND id = ?
+	AND deleted_at IS NULL`
+)
+
+func (s *UserDataStore) GetAccountsToReport(ctx context.Context, input *store.GetAccountsToReportInput) (*store.GetAccountsToReportOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	policy, err := s.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := defaultAccountsPage
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	after, err := store.DecodeAccountsCursor(input.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	period, err := getCyclePeriod(ctx, s.db, input.Provider, policy.CyclePeriodDays)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := formatTime(time.Now().UTC().Add(-period.Duration()))
+
+	var accountIDs sql.NullString
+	if input.AccountIDs != nil {
+		accountIDs = sql.NullString{String: encodeStrings(input.AccountIDs), Valid: true}
+	}
+
+	var total int
+	if input.IncludeCount {
+		if err := s.db.GetContext(ctx, &total, countAccountsQuery, input.Provider, cutoff, accountIDs); err != nil {
+			return nil, fmt.Errorf("count accounts to report: %w", err)
+		}
+	}
+
+	var rows []struct {
+		AccountID string `db:"account_id"`
+		UpdatedAt string `db:"updated_at"`
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	if after == nil {
+		err = s.db.SelectContext(ctx, &rows, selectAccountsQuery, input.Provider, cutoff, accountIDs, limit+1)
+	} else {
+		err = s.db.SelectContext(ctx, &rows, selectAccountsAfterQuery, input.Provider, cutoff, accountIDs, limit+1, formatTime(after.UpdatedAt), after.AccountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list accounts to report: %w", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	accounts := make([]domain.Account, 0, len(rows))
+	for _, row := range rows {
+		updatedAt, err := parseTime(row.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, domain.Account{
+			AccountID: row.AccountID,
+			UpdatedAt: updatedAt,
+		})
+	}
+
+	output := &store.GetAccountsToReportOutput{
+		Accounts:   accounts,
+		TotalCount: total,
+		HasMore:    hasMore,
+	}
+
+	if hasMore {
+		last := accounts[len(accounts)-1]
+		output.NextCursor = store.AccountsCursor{UpdatedAt: last.UpdatedAt, AccountID: last.AccountID}.Encode()
+	}
+
+	return output, nil
+}
+
+func (s *UserDataStore) UpdateLastReported(ctx context.Context, input *store.UpdateLastReportedInput) (*store.UpdateLastReportedOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || len(input.AccountIDs) == 0 {
+		return store.NewUpdateLastReportedOutput(nil, nil), nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reportedAt := formatTime(input.ReportedAt)
+
+	var updated []string
+	for chunk := range slices.Chunk(input.AccountIDs, reportedChunkSize) {
+		ids := encodeStrings(chunk)
+
+		var marked []string
+		if err := tx.SelectContext(ctx, &marked, updateInventoryReportedAtQuery, reportedAt, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+		updated = append(updated, marked...)
+
+		if _, err := tx.ExecContext(ctx, updateReportedAtQuery, reportedAt, input.Provider, ids); err != nil {
+			return nil, fmt.Errorf("update reported_at: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit reported_at: %w", err)
+	}
+
+	return store.NewUpdateLastReportedOutput(input.AccountIDs, updated), nil
+}
+
+func (s *UserDataStore) DeleteUserData(ctx context.Context, input *store.DeleteUserDataInput) (*store.DeleteUserDataOutput, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" {
+		return &store.DeleteUserDataOutput{
+			DeletedAt:    now.Format(time.RFC3339),
+			ItemsDeleted: 0,
+		}, nil
+	}
+
+	if input.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := deferErasure(ctx, tx, input.Provider, input.AccountID, now)
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("commit deferred erasure of account %s: %w", input.AccountID, err)
+		}
+		return &store.DeleteUserDataOutput{
+			Deferred: true,
+			HoldID:   hold.ID,
+		}, nil
+	}
+
+	stamp := formatTime(now)
+	var items store.ErasedItems
+
+	if _, err := tx.ExecContext(ctx, recordDeletedTokensQuery, stamp, input.Provider, input.AccountID); err != nil {
+		return nil, fmt.Errorf("record deleted tokens for account %s: %w", input.AccountID, err)
+	}
+
+	tokenResult, err := tx.ExecContext(ctx, deleteTokensQuery, input.Provider, input.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("delete tokens for account %s: %w", input.AccountID, err)
+	}
+	items.Tokens = rowsAffected(tokenResult)
+
+	// Without a tombstone key the history cannot be pseudonymized, so it is
+	// deleted, and the account is erased without a tombstone.
+	var idHash string
+	if s.hasher != nil {
+		idHash = s.hasher.Hash(input.Provider, input.AccountID)
+
+		if _, err := tx.ExecContext(ctx, pseudonymizeTokenEventsQuery, input.Provider, input.AccountID, idHash); err != nil {
+			return nil, fmt.Errorf("pseudonymize token history of account %s: %w", input.AccountID, err)
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, deleteAccountTokenEventsQuery, input.Provider, input.AccountID); err != nil {
+			return nil, fmt.Errorf("delete token history of account %s: %w", input.AccountID, err)
+		}
+		items.Untombstoned = true
+	}
+
+	var sessionIDs []string
+	if err := tx.SelectContext(ctx, &sessionIDs, deleteSessionLinksQuery, input.Provider, input.AccountID); err != nil {
+		return nil, fmt.Errorf("delete session links for account %s: %w", input.AccountID, err)
+	}
+	items.SessionLinks = len(sessionIDs)
+
+	if len(sessionIDs) > 0 {
+		ids := encodeStrings(sessionIDs)
+
+		sessionResult, err := tx.ExecContext(ctx, deleteOrphanedSessionsQuery, ids)
+		if err != nil {
+			return nil, fmt.Errorf("delete sessions for account %s: %w", input.AccountID, err)
+		}
+		items.Sessions = rowsAffected(sessionResult)
+
+		scrubResult, err := tx.ExecContext(ctx, scrubSessionsQuery, ids, "$.user.oauth."+input.Provider, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("scrub sessions for account %s: %w", input.AccountID, err)
+		}
+		items.SessionsScrubbed = rowsAffected(scrubResult)
+	}
+
+	profileResult, err := tx.ExecContext(ctx, softDeleteAccountQuery, stamp, stamp, stamp, input.Provider, input.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("soft delete account %s: %w", input.AccountID, err)
+	}
+	items.Profiles = rowsAffected(profileResult)
+
+	if _, err := tx.ExecContext(ctx, resolveDeferredErasuresQuery, input.Provider, input.AccountID, stamp); err != nil {
+		return nil, fmt.Errorf("resolve deferred erasure of account %s: %w", input.AccountID, err)
+	}
+
+	if idHash != "" {
+		if _, err := tx.ExecContext(ctx, insertAccountTombstoneQuery, input.Provider, idHash, stamp); err != nil {
+			return nil, fmt.Errorf("tombstone account %s: %w", input.AccountID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit erasure of account %s: %w", input.AccountID, err)
+	}
+
+	return &store.DeleteUserDataOutput{
+		DeletedAt:    now.Format(time.RFC3339),
+		ItemsDeleted: items.Total(),
+		Items:        items,
+	}, nil
+}
+
+func (s *UserDataStore) RefreshUserData(ctx context.Context, input *store.RefreshUserDataInput) (*store.RefreshUserDataOutput, error) {
+	now := time.Now().UTC()
+
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.AccountID == "" {
+		return &store.RefreshUserDataOutput{
+			RefreshedAt:  now.Format(time.RFC3339),
+			ItemsUpdated: 0,
+		}, nil
+	}
+
+	policy, err := s.policies.Policy(input.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Refresh == store.RefreshNone {
+		return &store.RefreshUserDataOutput{
+			RefreshedAt:  now.Format(time.RFC3339),
+			ItemsUpdated: 0,
+		}, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, refreshAccountQuery, formatTime(now), input.Provider, input.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("refresh account %s: %w", input.AccountID, err)
+	}
+
+	rows, _ := result.RowsAffected()
+
+	return &store.RefreshUserDataOutput{
+		RefreshedAt:  now.Format(time.RFC3339),
+		ItemsUpdated: int(rows),
+	}, nil
+}
+
+func (s *UserDataStore) PurgeDeletedProfiles(ctx context.Context, input *store.PurgeDeletedProfilesInput) (*store.PurgeDeletedProfilesOutput, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("store not opened")
+	}
+
+	if input == nil || input.DeletedBefore.IsZero() {
+		return nil, fmt.Errorf("deleted before is required")
+	}
+
+	limit := defaultPurgeBatch
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []struct {
+		ID        string `db:"id"`
+		Provider  string `db:"provider"`
+		DeletedAt string `db:"deleted_at"`
+	}
+
+	if err := tx.SelectContext(ctx, &rows, selectPurgeableProfilesQuery, formatTime(input.DeletedBefore), limit, input.Provider); err != nil {
+		return nil, fmt.Errorf("select purgeable profiles: %w", err)
+	}
+
+	now := formatTime(time.Now())
+	purged := 0
+	for _, row := range rows {
+		// The tombstone and pseudonymized history are keyed by the tombstone
+		// hash, so without a key no tombstone is written and only the history
+		// still under the profile ID is deleted.
+		var idHash string
+		if s.hasher != nil {
+			idHash = s.hasher.Hash(row.Provider, row.ID)
+			if _, err := tx.ExecContext(ctx, insertTombstoneQuery, row.Provider, idHash, row.DeletedAt, now); err != nil {
+				return nil, fmt.Errorf("write tombstone: %w", err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteTokenEventsQuery, row.Provider, row.ID, idHash); err != nil {
+			return nil, fmt.Errorf("delete token history: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, purgeProfileQuery, row.Provider, row.ID)
+		if err != nil {
+			return nil, fmt.Errorf("purge profile: %w", err)
+		}
+		purged += rowsAffected(result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit purge: %w", err)
+	}
+
+	return &store.PurgeDeletedProfilesOutput{
+		Purged:  purged,
+		HasMore: len(rows) == limit,
+	}, nil
+}
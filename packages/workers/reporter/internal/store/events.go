@@ -0,0 +1,7 @@
+package store
+
+// ProfileCreated is emitted by engines that can observe profile inserts.
+type ProfileCreated struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+}
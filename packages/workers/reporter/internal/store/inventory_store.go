@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Personal data stores recorded in the inventory.
+const (
+	// PersonalDataProfiles is the profiles table. Profiles are registered and
+	// unregistered by the database itself as they are created and erased.
+	PersonalDataProfiles = "profiles"
+	// PersonalDataJiraUsers is the web app's cache of Jira users, which the web
+	// app registers whenever it fetches users from Jira.
+	PersonalDataJiraUsers = "jira_users_cache"
+	// PersonalDataWorklogAuthors is reserved for a cache of worklog authors.
+	// The web app passes worklogs through without caching them, so nothing
+	// registers it yet.
+	PersonalDataWorklogAuthors = "worklog_authors_cache"
+)
+
+// PersonalDataEntry is an account in the personal data inventory.
+type PersonalDataEntry struct {
+	AccountID string   `json:"accountId"`
+	Provider  string   `json:"provider"`
+	Stores    []string `json:"stores"`
+	// DataRetrievedAt is the latest time data for the account was retrieved,
+	// across all stores. It is reported as the account's updatedAt.
+	DataRetrievedAt time.Time  `json:"dataRetrievedAt"`
+	ReportedAt      *time.Time `json:"reportedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// RegisterPersonalDataInput records that a store holds data for accounts.
+type RegisterPersonalDataInput struct {
+	Provider   string   `json:"provider"`
+	Store      string   `json:"store"`
+	AccountIDs []string `json:"accountIds"`
+	// RetrievedAt is when the data was retrieved from the provider.
+	RetrievedAt time.Time `json:"retrievedAt"`
+}
+
+// UnregisterPersonalDataInput records that a store no longer holds data for
+// accounts.
+type UnregisterPersonalDataInput struct {
+	Provider   string   `json:"provider"`
+	Store      string   `json:"store"`
+	AccountIDs []string `json:"accountIds"`
+}
+
+// UnregisterPersonalDataOutput contains the result of unregistering a store.
+type UnregisterPersonalDataOutput struct {
+	// Removed counts the accounts left without any store, which are dropped
+	// from the inventory.
+	Removed int `json:"removed"`
+}
+
+// PersonalDataKey identifies an account in the inventory.
+type PersonalDataKey struct {
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+}
+
+// InventoryStore manages the personal data inventory: every account whose
+// personal data the app stores, whether or not it has a profile.
+// GetAccountsToReport reports from the inventory.
+type InventoryStore interface {
+	// RegisterPersonalData adds the store to each account's entry, creating
+	// entries as needed. DataRetrievedAt only moves forward.
+	RegisterPersonalData(ctx context.Context, input *RegisterPersonalDataInput) error
+
+	// UnregisterPersonalData removes the store from each account's entry.
+	UnregisterPersonalData(ctx context.Context, input *UnregisterPersonalDataInput) (*UnregisterPersonalDataOutput, error)
+
+	// GetPersonalData returns the account's entry, or nil.
+	GetPersonalData(ctx context.Context, input *PersonalDataKey) (*PersonalDataEntry, error)
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// LegalHold keeps an account's data from being erased, for example during a
+// dispute. A hold is active until it is released or expires.
+type LegalHold struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"accountId"`
+	Provider  string     `json:"provider"`
+	Reason    string     `json:"reason"`
+	PlacedBy  string     `json:"placedBy,omitempty"`
+	PlacedAt  time.Time  `json:"placedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+
+	// ErasureDeferredAt is when an erasure was first deferred by this hold.
+	ErasureDeferredAt *time.Time `json:"erasureDeferredAt,omitempty"`
+	// ErasureResolvedAt is when the deferred erasure finally ran.
+	ErasureResolvedAt *time.Time `json:"erasureResolvedAt,omitempty"`
+}
+
+// Active reports whether the hold blocks erasure at the given time.
+func (h *LegalHold) Active(now time.Time) bool {
+	return h.ReleasedAt == nil && (h.ExpiresAt == nil || h.ExpiresAt.After(now))
+}
+
+// PlaceLegalHoldInput contains the hold to place on an account.
+type PlaceLegalHoldInput struct {
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+	Reason    string `json:"reason"`
+	PlacedBy  string `json:"placedBy,omitempty"`
+	// ExpiresAt ends the hold automatically; nil holds until released.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// LegalHoldKey identifies the account a hold applies to.
+type LegalHoldKey struct {
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+}
+
+// ReleaseLegalHoldOutput contains the result of releasing a hold.
+type ReleaseLegalHoldOutput struct {
+	Released bool `json:"released"`
+	// ErasurePending is true when an erasure deferred by a hold now awaits
+	// ListPendingErasures.
+	ErasurePending bool `json:"erasurePending"`
+}
+
+// PendingErasure is an account whose erasure was deferred by a hold that is
+// no longer active.
+type PendingErasure struct {
+	AccountID  string    `json:"accountId"`
+	Provider   string    `json:"provider"`
+	DeferredAt time.Time `json:"deferredAt"`
+}
+
+// ListPendingErasuresInput contains parameters for listing pending erasures.
+type ListPendingErasuresInput struct {
+	// Provider, when set, limits the list to accounts of that provider.
+	Provider string `json:"provider,omitempty"`
+	// After is a keyset cursor: only erasures ordered after it are returned,
+	// so callers can page past erasures that failed.
+	After *PendingErasure `json:"after,omitempty"`
+	Limit int             `json:"limit"`
+}
+
+// LegalHoldStore manages legal holds on account erasure. Erasures deferred by
+// a hold are never dropped: they stay pending until DeleteUserData runs for
+// the account without an active hold.
+type LegalHoldStore interface {
+	// PlaceLegalHold places a hold on an account. If the account already has
+	// an active hold, its reason, expiry and placer are updated instead.
+	PlaceLegalHold(ctx context.Context, input *PlaceLegalHoldInput) (*LegalHold, error)
+
+	// ReleaseLegalHold releases the account's hold. Releasing an account
+	// without a hold is not an error.
+	ReleaseLegalHold(ctx context.Context, input *LegalHoldKey) (*ReleaseLegalHoldOutput, error)
+
+	// GetLegalHold returns the account's active hold, or nil.
+	GetLegalHold(ctx context.Context, input *LegalHoldKey) (*LegalHold, error)
+
+	// DeferErasure marks the account's active hold as deferring an erasure
+	// and returns it, or returns nil when the account is not held.
+	DeferErasure(ctx context.Context, input *LegalHoldKey) (*LegalHold, error)
+
+	// ListPendingErasures returns accounts with a deferred erasure and no
+	// active hold, oldest deferral first, then by provider and account id.
+	ListPendingErasures(ctx context.Context, input *ListPendingErasuresInput) ([]PendingErasure, error)
+}
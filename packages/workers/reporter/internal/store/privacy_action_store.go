@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// PrivacyActionType is the action taken on an account status received from
+// Atlassian.
+type PrivacyActionType string
+
+const (
+	// PrivacyActionErased records that a closed account's data was erased.
+	PrivacyActionErased PrivacyActionType = "erased"
+	// PrivacyActionRefreshed records that an updated account's data was refreshed.
+	PrivacyActionRefreshed PrivacyActionType = "refreshed"
+	// PrivacyActionDeferred records that the action failed and is left to a
+	// later run, which receives the status again.
+	PrivacyActionDeferred PrivacyActionType = "deferred"
+)
+
+// PrivacyAction is an entry in the privacy action ledger.
+type PrivacyAction struct {
+	ID        string `json:"id,omitempty"`
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+	// Status is the account status received from the provider, e.g. "closed".
+	Status     string    `json:"status"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	WorkflowID string    `json:"workflowId"`
+	RunID      string    `json:"runId"`
+
+	Action PrivacyActionType `json:"action"`
+	// CompletedAt is when the action finished; nil for deferred actions.
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// Items counts the rows touched by the action, by kind.
+	Items map[string]int `json:"items,omitempty"`
+	// Error describes why the action was deferred, or what a completed
+	// erasure left out.
+	Error string `json:"error,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GetAccountPrivacyActionsInput contains parameters for reading an account's
+// ledger entries.
+type GetAccountPrivacyActionsInput struct {
+	AccountID string `json:"accountId"`
+	Provider  string `json:"provider"`
+	Limit     int    `json:"limit"`
+}
+
+// ListPrivacyActionsInput selects ledger entries by the time their status
+// was received.
+type ListPrivacyActionsInput struct {
+	// ReceivedFrom is the inclusive lower bound.
+	ReceivedFrom time.Time `json:"receivedFrom"`
+	// ReceivedTo is the exclusive upper bound.
+	ReceivedTo time.Time `json:"receivedTo"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+}
+
+// ListPrivacyActionsOutput contains a page of ledger entries.
+type ListPrivacyActionsOutput struct {
+	Actions []PrivacyAction `json:"actions"`
+	HasMore bool            `json:"hasMore"`
+}
+
+// PrivacyActionStore is the append-only ledger of actions taken on account
+// statuses received from Atlassian. It outlives Temporal history retention.
+type PrivacyActionStore interface {
+	// RecordPrivacyAction appends an entry. Entries are immutable once
+	// written; recording the same run, account and status again is a no-op
+	// so that callers may retry.
+	RecordPrivacyAction(ctx context.Context, action *PrivacyAction) error
+
+	// GetAccountPrivacyActions returns the entries of an account, newest first.
+	GetAccountPrivacyActions(ctx context.Context, input *GetAccountPrivacyActionsInput) ([]PrivacyAction, error)
+
+	// ListPrivacyActions returns a page of the entries received in
+	// [ReceivedFrom, ReceivedTo), oldest first.
+	ListPrivacyActions(ctx context.Context, input *ListPrivacyActionsInput) (*ListPrivacyActionsOutput, error)
+}
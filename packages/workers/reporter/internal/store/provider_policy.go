@@ -0,0 +1,77 @@
+package store
+
+import (
+	"fmt"
+
+	"hourly/workers/reporter/internal/atlassian"
+)
+
+// RefreshMode is what RefreshUserData does for an account its provider
+// reports as changed.
+type RefreshMode string
+
+const (
+	// RefreshTouch bumps the profile's updated_at, so the web app re-fetches
+	// the profile on next use.
+	RefreshTouch RefreshMode = "touch"
+	// RefreshNone leaves the profile as it is.
+	RefreshNone RefreshMode = "none"
+)
+
+// ProviderPolicy is how the accounts of one provider are kept current.
+type ProviderPolicy struct {
+	// CyclePeriodDays is how long a report stays current when no cycle
+	// period is stored for the provider (see CyclePeriodSetting).
+	CyclePeriodDays int `json:"cyclePeriodDays"`
+	// Refresh is what RefreshUserData does for a changed account.
+	Refresh RefreshMode `json:"refresh"`
+}
+
+// ProviderPolicies holds the policy of each provider the user data store
+// serves, keyed by provider.
+type ProviderPolicies map[string]ProviderPolicy
+
+// DefaultProviderPolicies returns the policies used when none are
+// configured: Atlassian only, on its default cycle period.
+func DefaultProviderPolicies() ProviderPolicies {
+	return ProviderPolicies{
+		ProviderAtlassian: {
+			CyclePeriodDays: atlassian.DefaultCyclePeriodDays,
+			Refresh:         RefreshTouch,
+		},
+	}
+}
+
+// Policy returns the provider's policy. A provider without one is an error,
+// so that a misconfigured sweep fails instead of reporting nothing.
+func (p ProviderPolicies) Policy(provider string) (ProviderPolicy, error) {
+	if provider == "" {
+		return ProviderPolicy{}, fmt.Errorf("provider is required")
+	}
+	policy, ok := p[provider]
+	if !ok {
+		return ProviderPolicy{}, fmt.Errorf("no policy for provider %q", provider)
+	}
+	return policy, nil
+}
+
+// Validate checks every policy's cycle period and refresh mode.
+func (p ProviderPolicies) Validate() error {
+	for provider, policy := range p {
+		if policy.CyclePeriodDays <= 0 {
+			return fmt.Errorf("provider %q: cycle period days must be positive", provider)
+		}
+		switch policy.Refresh {
+		case RefreshTouch, RefreshNone:
+		default:
+			return fmt.Errorf("provider %q: unknown refresh mode %q", provider, policy.Refresh)
+		}
+	}
+	return nil
+}
+
+// CyclePeriodSetting returns the worker_settings key holding the provider's
+// stored cycle period.
+func CyclePeriodSetting(provider string) string {
+	return provider + ".cycle_period_days"
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// SettingCyclePeriodDays is the worker_settings key holding the reporting
+// cycle period last requested by Atlassian's Cycle-Period header. It is
+// CyclePeriodSetting(ProviderAtlassian).
+const SettingCyclePeriodDays = ProviderAtlassian + ".cycle_period_days"
+
+// CyclePeriod is the reporting cycle accounts are re-reported on.
+type CyclePeriod struct {
+	Days int `json:"days"`
+	// ChangedAt is when Days last changed; nil when the default is in use.
+	ChangedAt *time.Time `json:"changedAt,omitempty"`
+}
+
+// Duration returns the cycle period as a duration.
+func (p *CyclePeriod) Duration() time.Duration {
+	return time.Duration(p.Days) * 24 * time.Hour
+}
+
+// SetCyclePeriodInput contains the cycle period to persist.
+type SetCyclePeriodInput struct {
+	Days int `json:"days"`
+}
+
+// SetCyclePeriodOutput contains the result of persisting a cycle period.
+type SetCyclePeriodOutput struct {
+	PreviousDays int  `json:"previousDays"`
+	Changed      bool `json:"changed"`
+}
+
+// SettingsStore holds worker settings that must survive restarts.
+type SettingsStore interface {
+	// GetCyclePeriod returns the stored cycle period, or the default
+	// (atlassian.DefaultCyclePeriodDays) when none has been stored.
+	GetCyclePeriod(ctx context.Context) (*CyclePeriod, error)
+
+	// SetCyclePeriod stores the cycle period. ChangedAt only moves when the
+	// value differs from the stored one.
+	SetCyclePeriod(ctx context.Context, input *SetCyclePeriodInput) (*SetCyclePeriodOutput, error)
+}
@@ -9,6 +9,15 @@ type Store interface {
 	Open(ctx context.Context) error
 	Close(ctx context.Context) error
 
+	// Ping verifies that the underlying database is reachable.
+	Ping(ctx context.Context) error
+
 	UserData() UserDataStore
 	Tokens() TokenStore
+	Audit() AuditStore
+	Settings() SettingsStore
+	PrivacyActions() PrivacyActionStore
+	LegalHolds() LegalHoldStore
+	Inventory() InventoryStore
+	AccountTombstones() AccountTombstoneStore
 }
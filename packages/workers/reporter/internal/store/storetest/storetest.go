@@ -0,0 +1,1612 @@
+// Package storetest provides a conformance suite for store.Store engines.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// Profile is a profiles row created by fixtures.
+type Profile struct {
+	ID         string
+	Provider   string
+	UpdatedAt  time.Time
+	ReportedAt *time.Time
+	DeletedAt  *time.Time
+}
+
+// Session is a sessions row linked to the given profiles.
+type Session struct {
+	ID        string
+	Profiles  []Profile
+	Data      map[string]any
+	UpdatedAt time.Time
+}
+
+// Fixtures seeds and inspects rows that the worker never reads through the
+// store interfaces.
+type Fixtures interface {
+	CreateProfile(t *testing.T, profile Profile)
+	CreateToken(t *testing.T, token store.Token)
+	CreateSession(t *testing.T, session Session)
+	// LoadSession returns the session with its linked profile keys, or nil
+	// when it does not exist.
+	LoadSession(t *testing.T, id string) *Session
+}
+
+// TombstoneKey is an account tombstone key for engines under test.
+const TombstoneKey = "storetest-account-tombstone-key-0123456789"
+
+// Policies returns the provider policies engines under test are created
+// with: Atlassian's defaults, and GitLab on a 30 day cycle with no refresh.
+func Policies() store.ProviderPolicies {
+	policies := store.DefaultProviderPolicies()
+	policies[store.ProviderGitLab] = store.ProviderPolicy{CyclePeriodDays: 30, Refresh: store.RefreshNone}
+	return policies
+}
+
+// Factory returns an opened, empty store and fixtures that write into it.
+type Factory func(t *testing.T) (store.Store, Fixtures)
+
+// Run runs the conformance suite against the engine produced by newStore.
+func Run(t *testing.T, newStore Factory) {
+	t.Run("Ping", func(t *testing.T) { testPing(t, newStore) })
+	t.Run("GetAccountsToReport", func(t *testing.T) { testGetAccountsToReport(t, newStore) })
+	t.Run("GetAccountsToReportPagination", func(t *testing.T) { testGetAccountsToReportPagination(t, newStore) })
+	t.Run("UpdateLastReported", func(t *testing.T) { testUpdateLastReported(t, newStore) })
+	t.Run("DeleteUserData", func(t *testing.T) { testDeleteUserData(t, newStore) })
+	t.Run("DeleteUserDataBatch", func(t *testing.T) { testDeleteUserDataBatch(t, newStore) })
+	t.Run("PurgeDeletedProfiles", func(t *testing.T) { testPurgeDeletedProfiles(t, newStore) })
+	t.Run("PurgeDeletedProfilesByProvider", func(t *testing.T) { testPurgeDeletedProfilesByProvider(t, newStore) })
+	t.Run("RefreshUserData", func(t *testing.T) { testRefreshUserData(t, newStore) })
+	t.Run("RefreshUserDataBatch", func(t *testing.T) { testRefreshUserDataBatch(t, newStore) })
+	t.Run("ProviderPolicies", func(t *testing.T) { testProviderPolicies(t, newStore) })
+	t.Run("GetToken", func(t *testing.T) { testGetToken(t, newStore) })
+	t.Run("ListLinkedTokens", func(t *testing.T) { testListLinkedTokens(t, newStore) })
+	t.Run("UpdateToken", func(t *testing.T) { testUpdateToken(t, newStore) })
+	t.Run("RefreshToken", func(t *testing.T) { testRefreshToken(t, newStore) })
+	t.Run("ListTokens", func(t *testing.T) { testListTokens(t, newStore) })
+	t.Run("ListDormantTokens", func(t *testing.T) { testListDormantTokens(t, newStore) })
+	t.Run("DeleteToken", func(t *testing.T) { testDeleteToken(t, newStore) })
+	t.Run("CyclePeriod", func(t *testing.T) { testCyclePeriod(t, newStore) })
+	t.Run("PrivacyActions", func(t *testing.T) { testPrivacyActions(t, newStore) })
+	t.Run("LegalHolds", func(t *testing.T) { testLegalHolds(t, newStore) })
+	t.Run("ListPendingErasures", func(t *testing.T) { testListPendingErasures(t, newStore) })
+	t.Run("PersonalDataInventory", func(t *testing.T) { testPersonalDataInventory(t, newStore) })
+	t.Run("AccountTombstones", func(t *testing.T) { testAccountTombstones(t, newStore) })
+}
+
+func ago(d time.Duration) *time.Time {
+	t := time.Now().UTC().Add(-d)
+	return &t
+}
+
+const day = 24 * time.Hour
+
+func accountIDs(t *testing.T, st store.Store, input *store.GetAccountsToReportInput) ([]string, *store.GetAccountsToReportOutput) {
+	t.Helper()
+
+	out, err := st.UserData().GetAccountsToReport(context.Background(), input)
+	if err != nil {
+		t.Fatalf("GetAccountsToReport: %v", err)
+	}
+
+	ids := make([]string, 0, len(out.Accounts))
+	for _, acc := range out.Accounts {
+		ids = append(ids, acc.AccountID)
+	}
+	return ids, out
+}
+
+func testPing(t *testing.T, newStore Factory) {
+	st, _ := newStore(t)
+
+	if err := st.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func testGetAccountsToReport(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+
+	now := time.Now().UTC()
+	fx.CreateProfile(t, Profile{ID: "never-reported", Provider: store.ProviderAtlassian, UpdatedAt: now.Add(-time.Hour)})
+	fx.CreateProfile(t, Profile{ID: "reported-long-ago", Provider: store.ProviderAtlassian, UpdatedAt: now.Add(-2 * time.Hour), ReportedAt: ago(30 * day)})
+	fx.CreateProfile(t, Profile{ID: "reported-recently", Provider: store.ProviderAtlassian, UpdatedAt: now, ReportedAt: ago(day)})
+	fx.CreateProfile(t, Profile{ID: "deleted", Provider: store.ProviderAtlassian, UpdatedAt: now, DeletedAt: ago(day)})
+	fx.CreateProfile(t, Profile{ID: "gitlab", Provider: store.ProviderGitLab, UpdatedAt: now})
+
+	ids, out := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian, IncludeCount: true})
+
+	want := []string{"never-reported", "reported-long-ago"}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("accounts = %v, want %v", ids, want)
+	}
+	if out.TotalCount != 2 || out.HasMore || out.NextCursor != "" {
+		t.Fatalf("total = %d, hasMore = %v, nextCursor = %q, want 2, false, empty", out.TotalCount, out.HasMore, out.NextCursor)
+	}
+
+	ids, out = accountIDs(t, st, &store.GetAccountsToReportInput{
+		Provider:     store.ProviderAtlassian,
+		AccountIDs:   []string{"reported-long-ago", "reported-recently", "deleted", "missing"},
+		IncludeCount: true,
+	})
+	if want := []string{"reported-long-ago"}; !slices.Equal(ids, want) || out.TotalCount != 1 {
+		t.Fatalf("filtered accounts = %v (total %d), want %v", ids, out.TotalCount, want)
+	}
+
+	ids, _ = accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian, AccountIDs: []string{}})
+	if len(ids) != 0 {
+		t.Fatalf("empty account filter returned %v", ids)
+	}
+}
+
+func testGetAccountsToReportPagination(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+
+	updatedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	fx.CreateProfile(t, Profile{ID: "b", Provider: store.ProviderAtlassian, UpdatedAt: updatedAt})
+	fx.CreateProfile(t, Profile{ID: "a", Provider: store.ProviderAtlassian, UpdatedAt: updatedAt})
+	fx.CreateProfile(t, Profile{ID: "c", Provider: store.ProviderAtlassian, UpdatedAt: updatedAt.Add(time.Minute)})
+
+	first, out := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian, Limit: 2, IncludeCount: true})
+	if want := []string{"c", "a"}; !slices.Equal(first, want) {
+		t.Fatalf("first page = %v, want %v", first, want)
+	}
+	if !out.HasMore || out.TotalCount != 3 || out.NextCursor == "" {
+		t.Fatalf("first page total = %d, hasMore = %v, nextCursor = %q, want 3, true, set", out.TotalCount, out.HasMore, out.NextCursor)
+	}
+
+	// Profiles created or bumped while paging sort ahead of the cursor and
+	// must not shift the remaining pages.
+	fx.CreateProfile(t, Profile{ID: "new", Provider: store.ProviderAtlassian, UpdatedAt: time.Now().UTC()})
+
+	second, out := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian, Limit: 2, Cursor: out.NextCursor})
+	if want := []string{"b"}; !slices.Equal(second, want) {
+		t.Fatalf("second page = %v, want %v", second, want)
+	}
+	if out.HasMore || out.NextCursor != "" {
+		t.Fatalf("second page hasMore = %v, nextCursor = %q, want false, empty", out.HasMore, out.NextCursor)
+	}
+	if out.TotalCount != 0 {
+		t.Fatalf("second page total = %d, want 0 when count is not requested", out.TotalCount)
+	}
+
+	if _, err := st.UserData().GetAccountsToReport(context.Background(), &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian, Cursor: "not-a-cursor"}); err == nil {
+		t.Fatalf("GetAccountsToReport with invalid cursor: want error")
+	}
+}
+
+func testUpdateLastReported(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "active", Provider: store.ProviderAtlassian})
+	fx.CreateProfile(t, Profile{ID: "other", Provider: store.ProviderAtlassian})
+	fx.CreateProfile(t, Profile{ID: "deleted", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+
+	out, err := st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"missing", "active", "deleted", "active"},
+		ReportedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("UpdateLastReported: %v", err)
+	}
+	if want := []string{"active"}; !slices.Equal(out.Updated, want) {
+		t.Fatalf("updated = %v, want %v", out.Updated, want)
+	}
+	if want := []string{"missing", "deleted"}; !slices.Equal(out.Missed, want) {
+		t.Fatalf("missed = %v, want %v", out.Missed, want)
+	}
+
+	ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if want := []string{"other"}; !slices.Equal(ids, want) {
+		t.Fatalf("accounts after report = %v, want %v", ids, want)
+	}
+
+	out, err = st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("UpdateLastReported with no ids: %v", err)
+	}
+	if len(out.Updated) != 0 || len(out.Missed) != 0 {
+		t.Fatalf("UpdateLastReported with no ids = %+v, want nothing", out)
+	}
+
+	// More IDs than fit in one chunk, with the known account in the last one.
+	many := make([]string, 0, 2501)
+	for i := range 2500 {
+		many = append(many, fmt.Sprintf("unknown-%04d", i))
+	}
+	many = append(many, "other")
+
+	out, err = st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: many,
+		ReportedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("UpdateLastReported with many ids: %v", err)
+	}
+	if want := []string{"other"}; !slices.Equal(out.Updated, want) {
+		t.Fatalf("updated = %v, want %v", out.Updated, want)
+	}
+	if len(out.Missed) != 2500 || out.Missed[0] != "unknown-0000" || out.Missed[2499] != "unknown-2499" {
+		t.Fatalf("missed %d accounts, want the 2500 unknown ones in order", len(out.Missed))
+	}
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian}); len(ids) != 0 {
+		t.Fatalf("accounts after report = %v, want none", ids)
+	}
+}
+
+func testDeleteUserData(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	closed := Profile{ID: "closed", Provider: store.ProviderAtlassian}
+	other := Profile{ID: "other", Provider: store.ProviderGitLab}
+
+	fx.CreateProfile(t, closed)
+	fx.CreateProfile(t, other)
+	fx.CreateToken(t, store.Token{
+		ProfileID:    "closed",
+		Provider:     store.ProviderAtlassian,
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		Scopes:       []string{"read:me"},
+	})
+	fx.CreateSession(t, Session{ID: "only-closed", Profiles: []Profile{closed}, UpdatedAt: time.Now().UTC()})
+	fx.CreateSession(t, Session{
+		ID:       "shared",
+		Profiles: []Profile{closed, other},
+		Data: map[string]any{
+			"user": map[string]any{
+				"oauth": map[string]any{
+					store.ProviderAtlassian: "atlassian-state",
+					store.ProviderGitLab:    "gitlab-state",
+				},
+			},
+		},
+		UpdatedAt: time.Now().UTC(),
+	})
+	fx.CreateSession(t, Session{ID: "unrelated", Profiles: []Profile{other}, UpdatedAt: time.Now().UTC()})
+
+	out, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: "closed"})
+	if err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+
+	want := store.ErasedItems{Tokens: 1, SessionLinks: 2, Sessions: 1, SessionsScrubbed: 1, Profiles: 1}
+	if out.Items != want {
+		t.Fatalf("items = %+v, want %+v", out.Items, want)
+	}
+	if out.ItemsDeleted != want.Total() {
+		t.Fatalf("items deleted = %d, want %d", out.ItemsDeleted, want.Total())
+	}
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "closed", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if token != nil {
+		t.Fatalf("token still readable after erasure")
+	}
+
+	ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if len(ids) != 0 {
+		t.Fatalf("erased account still reported: %v", ids)
+	}
+
+	if session := fx.LoadSession(t, "only-closed"); session != nil {
+		t.Fatalf("session that only served the erased profile still exists")
+	}
+
+	shared := fx.LoadSession(t, "shared")
+	if shared == nil {
+		t.Fatalf("shared session was deleted")
+	}
+	if len(shared.Profiles) != 1 || shared.Profiles[0].ID != "other" {
+		t.Fatalf("shared session links = %+v, want only other", shared.Profiles)
+	}
+	user, _ := shared.Data["user"].(map[string]any)
+	oauth, _ := user["oauth"].(map[string]any)
+	if _, ok := oauth[store.ProviderAtlassian]; ok {
+		t.Fatalf("shared session still holds the erased provider: %v", shared.Data)
+	}
+	if _, ok := oauth[store.ProviderGitLab]; !ok {
+		t.Fatalf("shared session lost the other provider: %v", shared.Data)
+	}
+
+	if unrelated := fx.LoadSession(t, "unrelated"); unrelated == nil || len(unrelated.Profiles) != 1 {
+		t.Fatalf("unrelated session changed: %+v", unrelated)
+	}
+
+	// The token history, deletion included, is kept under the account's
+	// tombstone hash only.
+	history, err := st.Tokens().GetTokenHistory(ctx, &store.GetTokenHistoryInput{ProfileID: "closed", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetTokenHistory: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("history = %+v, want none under the erased account id", history)
+	}
+
+	again, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: "closed"})
+	if err != nil {
+		t.Fatalf("repeated DeleteUserData: %v", err)
+	}
+	if again.Items != (store.ErasedItems{Profiles: again.Items.Profiles}) || again.Items.Profiles > 1 {
+		t.Fatalf("repeated erasure items = %+v, want at most the profile tombstone", again.Items)
+	}
+}
+
+func testDeleteUserDataBatch(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	first := Profile{ID: "first", Provider: store.ProviderAtlassian}
+	second := Profile{ID: "second", Provider: store.ProviderAtlassian}
+	held := Profile{ID: "held", Provider: store.ProviderAtlassian}
+	other := Profile{ID: "other", Provider: store.ProviderGitLab}
+
+	for _, p := range []Profile{first, second, held, other} {
+		fx.CreateProfile(t, p)
+	}
+	for _, p := range []Profile{first, second, held} {
+		fx.CreateToken(t, store.Token{ProfileID: p.ID, Provider: p.Provider, AccessToken: "access"})
+	}
+	// Erasing both accounts of the pair session deletes it for each of them.
+	fx.CreateSession(t, Session{ID: "pair", Profiles: []Profile{first, second}, UpdatedAt: time.Now().UTC()})
+	fx.CreateSession(t, Session{
+		ID:       "shared",
+		Profiles: []Profile{first, other},
+		Data: map[string]any{
+			"user": map[string]any{
+				"oauth": map[string]any{
+					store.ProviderAtlassian: "atlassian-state",
+					store.ProviderGitLab:    "gitlab-state",
+				},
+			},
+		},
+		UpdatedAt: time.Now().UTC(),
+	})
+
+	hold, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "held",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "dispute",
+	})
+	if err != nil {
+		t.Fatalf("PlaceLegalHold: %v", err)
+	}
+
+	out, err := st.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"first", "held", "second", "first", "never-stored"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserDataBatch: %v", err)
+	}
+
+	want := []struct {
+		id    string
+		items store.ErasedItems
+	}{
+		{"first", store.ErasedItems{Tokens: 1, SessionLinks: 2, Sessions: 1, SessionsScrubbed: 1, Profiles: 1}},
+		{"held", store.ErasedItems{}},
+		{"second", store.ErasedItems{Tokens: 1, SessionLinks: 1, Sessions: 1, Profiles: 1}},
+		{"never-stored", store.ErasedItems{}},
+	}
+	if len(out.Results) != len(want) {
+		t.Fatalf("results = %+v, want one per distinct account", out.Results)
+	}
+	for i, w := range want {
+		got := out.Results[i]
+		if got.AccountID != w.id || got.Items != w.items || got.ItemsDeleted != w.items.Total() {
+			t.Fatalf("result %d = %+v, want %s with %+v", i, got, w.id, w.items)
+		}
+		if deferred := w.id == "held"; got.Deferred != deferred || (got.DeletedAt == "") != deferred {
+			t.Fatalf("result %d = %+v, want deferred %v", i, got, deferred)
+		}
+	}
+	if out.Results[1].HoldID != hold.ID {
+		t.Fatalf("held result hold = %q, want %q", out.Results[1].HoldID, hold.ID)
+	}
+
+	if session := fx.LoadSession(t, "pair"); session != nil {
+		t.Fatalf("session of two erased accounts still exists: %+v", session)
+	}
+	if shared := fx.LoadSession(t, "shared"); shared == nil || len(shared.Profiles) != 1 || shared.Profiles[0].ID != "other" {
+		t.Fatalf("shared session = %+v, want only other linked", shared)
+	}
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "held", Provider: store.ProviderAtlassian})
+	if err != nil || token == nil {
+		t.Fatalf("held token = %+v, %v, want kept", token, err)
+	}
+
+	ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if want := []string{"held"}; !slices.Equal(ids, want) {
+		t.Fatalf("accounts after batch = %v, want %v", ids, want)
+	}
+
+	tombstoned, err := st.AccountTombstones().FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"first", "second", "held", "never-stored"},
+	})
+	if err != nil {
+		t.Fatalf("FilterTombstoned: %v", err)
+	}
+	if want := []string{"first", "second", "never-stored"}; !slices.Equal(tombstoned, want) {
+		t.Fatalf("tombstoned = %v, want %v", tombstoned, want)
+	}
+
+	released, err := st.LegalHolds().ReleaseLegalHold(ctx, &store.LegalHoldKey{AccountID: "held", Provider: store.ProviderAtlassian})
+	if err != nil || !released.ErasurePending {
+		t.Fatalf("ReleaseLegalHold = %+v, %v, want the deferred erasure pending", released, err)
+	}
+
+	out, err = st.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"held"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserDataBatch after release: %v", err)
+	}
+	if want := (store.ErasedItems{Tokens: 1, Profiles: 1}); len(out.Results) != 1 || out.Results[0].Deferred || out.Results[0].Items != want {
+		t.Fatalf("results after release = %+v, want held erased with %+v", out.Results, want)
+	}
+	if pending := pendingErasureIDs(t, st); len(pending) != 0 {
+		t.Fatalf("pending erasures = %v, want none", pending)
+	}
+
+	empty, err := st.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{Provider: store.ProviderAtlassian})
+	if err != nil || len(empty.Results) != 0 {
+		t.Fatalf("empty batch = %+v, %v, want no results", empty, err)
+	}
+
+	if _, err := st.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{AccountIDs: []string{"first"}}); err == nil {
+		t.Fatal("DeleteUserDataBatch without provider succeeded, want error")
+	}
+}
+
+func testPurgeDeletedProfiles(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "purge-1", Provider: store.ProviderAtlassian, DeletedAt: ago(60 * day)})
+	fx.CreateProfile(t, Profile{ID: "purge-2", Provider: store.ProviderGitLab, DeletedAt: ago(45 * day)})
+	fx.CreateProfile(t, Profile{ID: "recent", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+	fx.CreateProfile(t, Profile{ID: "active", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "purge-1", Provider: store.ProviderAtlassian, AccessToken: "access"})
+	fx.CreateSession(t, Session{ID: "purged-session", Profiles: []Profile{{ID: "purge-1", Provider: store.ProviderAtlassian}}, UpdatedAt: time.Now().UTC()})
+
+	input := &store.PurgeDeletedProfilesInput{DeletedBefore: *ago(30 * day), Limit: 1}
+
+	first, err := st.UserData().PurgeDeletedProfiles(ctx, input)
+	if err != nil {
+		t.Fatalf("PurgeDeletedProfiles: %v", err)
+	}
+	if first.Purged != 1 || !first.HasMore {
+		t.Fatalf("first batch = %+v, want 1 purged with more", first)
+	}
+
+	total := first.Purged
+	for range 3 {
+		out, err := st.UserData().PurgeDeletedProfiles(ctx, input)
+		if err != nil {
+			t.Fatalf("PurgeDeletedProfiles: %v", err)
+		}
+		total += out.Purged
+		if !out.HasMore {
+			break
+		}
+	}
+	if total != 2 {
+		t.Fatalf("purged %d profiles, want 2", total)
+	}
+
+	if session := fx.LoadSession(t, "purged-session"); session == nil || len(session.Profiles) != 0 {
+		t.Fatalf("purged profile still linked to session: %+v", session)
+	}
+
+	for _, tc := range []struct {
+		id      string
+		present bool
+	}{
+		{"purge-1", false},
+		{"recent", true},
+	} {
+		out, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: tc.id})
+		if err != nil {
+			t.Fatalf("DeleteUserData(%s): %v", tc.id, err)
+		}
+		if present := out.Items.Profiles == 1; present != tc.present {
+			t.Fatalf("profile %s present = %v, want %v", tc.id, present, tc.present)
+		}
+	}
+
+	ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if want := []string{"active"}; !slices.Equal(ids, want) {
+		t.Fatalf("accounts = %v, want %v", ids, want)
+	}
+
+	if _, err := st.UserData().PurgeDeletedProfiles(ctx, &store.PurgeDeletedProfilesInput{}); err == nil {
+		t.Fatalf("PurgeDeletedProfiles without cutoff: want error")
+	}
+}
+
+func testRefreshUserData(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "active", Provider: store.ProviderAtlassian, UpdatedAt: time.Now().UTC().Add(-day)})
+	fx.CreateProfile(t, Profile{ID: "deleted", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+
+	out, err := st.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{Provider: store.ProviderAtlassian, AccountID: "active"})
+	if err != nil {
+		t.Fatalf("RefreshUserData: %v", err)
+	}
+	if out.ItemsUpdated != 1 {
+		t.Fatalf("items updated = %d, want 1", out.ItemsUpdated)
+	}
+
+	_, page := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if len(page.Accounts) != 1 || time.Since(page.Accounts[0].UpdatedAt) > time.Minute {
+		t.Fatalf("refreshed account = %+v, want updated_at bumped", page.Accounts)
+	}
+
+	out, err = st.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{Provider: store.ProviderAtlassian, AccountID: "deleted"})
+	if err != nil {
+		t.Fatalf("RefreshUserData deleted: %v", err)
+	}
+	if out.ItemsUpdated != 0 {
+		t.Fatalf("deleted account items updated = %d, want 0", out.ItemsUpdated)
+	}
+}
+
+func testRefreshUserDataBatch(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "active", Provider: store.ProviderAtlassian, UpdatedAt: time.Now().UTC().Add(-day)})
+	fx.CreateProfile(t, Profile{ID: "deleted", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+	fx.CreateProfile(t, Profile{ID: "gitlab", Provider: store.ProviderGitLab, UpdatedAt: time.Now().UTC().Add(-day)})
+
+	updated := func(provider string, accountIDs ...string) map[string]int {
+		t.Helper()
+		out, err := st.UserData().RefreshUserDataBatch(ctx, &store.RefreshUserDataBatchInput{Provider: provider, AccountIDs: accountIDs})
+		if err != nil {
+			t.Fatalf("RefreshUserDataBatch(%s): %v", provider, err)
+		}
+		got := map[string]int{}
+		for _, r := range out.Results {
+			if _, dup := got[r.AccountID]; dup || r.RefreshedAt == "" {
+				t.Fatalf("results = %+v, want one timed result per account", out.Results)
+			}
+			got[r.AccountID] = r.ItemsUpdated
+		}
+		return got
+	}
+
+	got := updated(store.ProviderAtlassian, "active", "deleted", "active", "never-stored")
+	if want := map[string]int{"active": 1, "deleted": 0, "never-stored": 0}; !maps.Equal(got, want) {
+		t.Fatalf("items updated = %v, want %v", got, want)
+	}
+
+	_, page := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if len(page.Accounts) != 1 || time.Since(page.Accounts[0].UpdatedAt) > time.Minute {
+		t.Fatalf("refreshed account = %+v, want updated_at bumped", page.Accounts)
+	}
+
+	// GitLab's policy does not refresh.
+	if got := updated(store.ProviderGitLab, "gitlab"); got["gitlab"] != 0 {
+		t.Fatalf("gitlab items updated = %d, want 0", got["gitlab"])
+	}
+
+	if _, err := st.UserData().RefreshUserDataBatch(ctx, &store.RefreshUserDataBatchInput{Provider: "unknown", AccountIDs: []string{"active"}}); err == nil {
+		t.Fatal("RefreshUserDataBatch for a provider without policy succeeded, want error")
+	}
+}
+
+func testProviderPolicies(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	// Reported ten days ago: due on Atlassian's 7 day cycle, not on GitLab's 30.
+	fx.CreateProfile(t, Profile{ID: "shared", Provider: store.ProviderAtlassian, ReportedAt: ago(10 * day)})
+	fx.CreateProfile(t, Profile{ID: "shared", Provider: store.ProviderGitLab, ReportedAt: ago(10 * day)})
+	fx.CreateProfile(t, Profile{ID: "gitlab-new", Provider: store.ProviderGitLab})
+
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian}); !slices.Equal(ids, []string{"shared"}) {
+		t.Fatalf("atlassian accounts = %v, want [shared]", ids)
+	}
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderGitLab}); !slices.Equal(ids, []string{"gitlab-new"}) {
+		t.Fatalf("gitlab accounts = %v, want [gitlab-new]", ids)
+	}
+
+	for _, input := range []*store.GetAccountsToReportInput{nil, {}, {Provider: "bitbucket"}} {
+		if _, err := st.UserData().GetAccountsToReport(ctx, input); err == nil {
+			t.Fatalf("GetAccountsToReport(%+v): want error", input)
+		}
+	}
+
+	out, err := st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderGitLab,
+		AccountIDs: []string{"gitlab-new", "missing"},
+		ReportedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("UpdateLastReported: %v", err)
+	}
+	if !slices.Equal(out.Updated, []string{"gitlab-new"}) || !slices.Equal(out.Missed, []string{"missing"}) {
+		t.Fatalf("UpdateLastReported = %+v, want gitlab-new updated and missing missed", out)
+	}
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderGitLab}); len(ids) != 0 {
+		t.Fatalf("gitlab accounts after report = %v, want none", ids)
+	}
+	if _, err := st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{AccountIDs: []string{"shared"}}); err == nil {
+		t.Fatalf("UpdateLastReported without provider: want error")
+	}
+
+	// GitLab's policy leaves changed accounts as they are.
+	refreshed, err := st.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{Provider: store.ProviderGitLab, AccountID: "shared"})
+	if err != nil {
+		t.Fatalf("RefreshUserData: %v", err)
+	}
+	if refreshed.ItemsUpdated != 0 {
+		t.Fatalf("gitlab refresh updated %d items, want 0", refreshed.ItemsUpdated)
+	}
+	if _, err := st.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{Provider: "bitbucket", AccountID: "shared"}); err == nil {
+		t.Fatalf("RefreshUserData for a provider without policy: want error")
+	}
+
+	// Erasing the GitLab account leaves the Atlassian one with the same ID.
+	deleted, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderGitLab, AccountID: "shared"})
+	if err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+	if deleted.Items.Profiles != 1 {
+		t.Fatalf("deleted profiles = %d, want 1", deleted.Items.Profiles)
+	}
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian}); !slices.Equal(ids, []string{"shared"}) {
+		t.Fatalf("atlassian accounts after gitlab erasure = %v, want [shared]", ids)
+	}
+	for provider, want := range map[string]bool{store.ProviderGitLab: true, store.ProviderAtlassian: false} {
+		got, err := st.AccountTombstones().IsTombstoned(ctx, &store.AccountTombstoneKey{AccountID: "shared", Provider: provider})
+		if err != nil {
+			t.Fatalf("IsTombstoned: %v", err)
+		}
+		if got != want {
+			t.Fatalf("%s tombstoned = %v, want %v", provider, got, want)
+		}
+	}
+	if _, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{AccountID: "shared"}); err == nil {
+		t.Fatalf("DeleteUserData without provider: want error")
+	}
+}
+
+func testPurgeDeletedProfilesByProvider(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "old", Provider: store.ProviderAtlassian, DeletedAt: ago(60 * day)})
+	fx.CreateProfile(t, Profile{ID: "old", Provider: store.ProviderGitLab, DeletedAt: ago(60 * day)})
+
+	out, err := st.UserData().PurgeDeletedProfiles(ctx, &store.PurgeDeletedProfilesInput{
+		Provider:      store.ProviderGitLab,
+		DeletedBefore: *ago(30 * day),
+	})
+	if err != nil {
+		t.Fatalf("PurgeDeletedProfiles: %v", err)
+	}
+	if out.Purged != 1 || out.HasMore {
+		t.Fatalf("purge = %+v, want 1 purged and no more", out)
+	}
+
+	out, err = st.UserData().PurgeDeletedProfiles(ctx, &store.PurgeDeletedProfilesInput{DeletedBefore: *ago(30 * day)})
+	if err != nil {
+		t.Fatalf("PurgeDeletedProfiles: %v", err)
+	}
+	if out.Purged != 1 {
+		t.Fatalf("purge of remaining providers = %+v, want the atlassian profile", out)
+	}
+}
+
+func testGetToken(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	fx.CreateProfile(t, Profile{ID: "refreshable", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "refreshable", Provider: store.ProviderAtlassian, AccessToken: "a1", RefreshToken: "r1", ExpiresAt: &expires, Scopes: []string{"read:me"}})
+	fx.CreateProfile(t, Profile{ID: "access-only", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "access-only", Provider: store.ProviderAtlassian, AccessToken: "a2"})
+	fx.CreateProfile(t, Profile{ID: "deleted", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+	fx.CreateToken(t, store.Token{ProfileID: "deleted", Provider: store.ProviderAtlassian, AccessToken: "a3", RefreshToken: "r3"})
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "refreshable", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetToken: %v", err)
+	}
+	if token == nil || token.AccessToken != "a1" || token.RefreshToken != "r1" || token.ExpiresAt == nil || !token.ExpiresAt.Equal(expires) {
+		t.Fatalf("token = %+v, want stored values", token)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		profileID   string
+		refreshable bool
+	}{
+		{name: "missing", profileID: "missing"},
+		{name: "deleted profile", profileID: "deleted"},
+		{name: "no refresh token", profileID: "access-only", refreshable: true},
+	} {
+		get := st.Tokens().GetToken
+		if tc.refreshable {
+			get = st.Tokens().GetRefreshableToken
+		}
+		token, err := get(ctx, &store.GetTokenInput{ProfileID: tc.profileID, Provider: store.ProviderAtlassian})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if token != nil {
+			t.Fatalf("%s: token = %+v, want nil", tc.name, token)
+		}
+	}
+
+	if _, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{}); err == nil {
+		t.Fatalf("GetToken without ids: want error")
+	}
+}
+
+func testListLinkedTokens(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	atl := Profile{ID: "atl", Provider: store.ProviderAtlassian}
+	gl := Profile{ID: "gl", Provider: store.ProviderGitLab}
+	deleted := Profile{ID: "gl-deleted", Provider: store.ProviderGitLab, DeletedAt: ago(day)}
+	other := Profile{ID: "other", Provider: store.ProviderGitLab}
+
+	for _, p := range []Profile{atl, gl, deleted, other} {
+		fx.CreateProfile(t, p)
+		fx.CreateToken(t, store.Token{ProfileID: p.ID, Provider: p.Provider, AccessToken: "a-" + p.ID})
+	}
+	fx.CreateSession(t, Session{ID: "linked", Profiles: []Profile{atl, gl}, UpdatedAt: time.Now().UTC()})
+	fx.CreateSession(t, Session{ID: "linked-deleted", Profiles: []Profile{atl, deleted}, UpdatedAt: time.Now().UTC()})
+	fx.CreateSession(t, Session{ID: "unrelated", Profiles: []Profile{other}, UpdatedAt: time.Now().UTC()})
+
+	tokens, err := st.Tokens().ListLinkedTokens(ctx, &store.GetTokenInput{ProfileID: "atl", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("ListLinkedTokens: %v", err)
+	}
+
+	var got []string
+	for _, token := range tokens {
+		got = append(got, token.Provider+":"+token.ProfileID+":"+token.AccessToken)
+	}
+	if want := []string{"atlassian:atl:a-atl", "gitlab:gl:a-gl"}; !slices.Equal(got, want) {
+		t.Fatalf("linked tokens = %v, want %v", got, want)
+	}
+
+	tokens, err = st.Tokens().ListLinkedTokens(ctx, &store.GetTokenInput{ProfileID: "missing", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("ListLinkedTokens missing: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("linked tokens of a missing profile = %+v, want none", tokens)
+	}
+}
+
+func testUpdateToken(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "owner", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "owner", Provider: store.ProviderAtlassian, AccessToken: "a1", RefreshToken: "r1", Scopes: []string{"read:me", "read:jira-work"}})
+
+	err := st.Tokens().UpdateToken(ctx, &store.UpdateTokenInput{ProfileID: "missing", Provider: store.ProviderAtlassian, AccessToken: "x"})
+	if err == nil {
+		t.Fatalf("UpdateToken for missing token: want error")
+	}
+
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	if err := st.Tokens().UpdateToken(ctx, &store.UpdateTokenInput{
+		ProfileID:    "owner",
+		Provider:     store.ProviderAtlassian,
+		AccessToken:  "a2",
+		RefreshToken: "r1",
+		ExpiresAt:    &expires,
+		Scopes:       []string{"read:me", "write:jira-work"},
+		Actor:        store.TokenActorWorker,
+	}); err != nil {
+		t.Fatalf("UpdateToken: %v", err)
+	}
+
+	if err := st.Tokens().UpdateToken(ctx, &store.UpdateTokenInput{
+		ProfileID:    "owner",
+		Provider:     store.ProviderAtlassian,
+		AccessToken:  "a3",
+		RefreshToken: "r2",
+		ExpiresAt:    &expires,
+		Scopes:       []string{"read:me", "write:jira-work"},
+		Actor:        store.TokenActorWeb,
+	}); err != nil {
+		t.Fatalf("UpdateToken rotate: %v", err)
+	}
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "owner", Provider: store.ProviderAtlassian})
+	if err != nil || token == nil {
+		t.Fatalf("GetToken: %v, %+v", err, token)
+	}
+	if token.AccessToken != "a3" || token.RefreshToken != "r2" {
+		t.Fatalf("token = %+v, want rotated values", token)
+	}
+
+	history, err := st.Tokens().GetTokenHistory(ctx, &store.GetTokenHistoryInput{ProfileID: "owner", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetTokenHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history length = %d, want 2", len(history))
+	}
+
+	rotated, refreshed := history[0], history[1]
+	if rotated.Type != store.TokenEventRotated || rotated.Actor != store.TokenActorWeb {
+		t.Fatalf("latest event = %+v, want rotated by web", rotated)
+	}
+	if refreshed.Type != store.TokenEventRefreshed || refreshed.Actor != store.TokenActorWorker {
+		t.Fatalf("first event = %+v, want refreshed by worker", refreshed)
+	}
+	if !slices.Equal(refreshed.ScopesAdded, []string{"write:jira-work"}) || !slices.Equal(refreshed.ScopesRemoved, []string{"read:jira-work"}) {
+		t.Fatalf("scope diff = +%v -%v", refreshed.ScopesAdded, refreshed.ScopesRemoved)
+	}
+	if refreshed.NewExpiresAt == nil || !refreshed.NewExpiresAt.Equal(expires) {
+		t.Fatalf("new expiry = %v, want %v", refreshed.NewExpiresAt, expires)
+	}
+}
+
+func testRefreshToken(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "owner", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "owner", Provider: store.ProviderAtlassian, AccessToken: "a1", RefreshToken: "r1", Scopes: []string{"read:me"}})
+
+	token, err := st.Tokens().RefreshToken(ctx, &store.RefreshTokenInput{
+		ProfileID: "missing",
+		Provider:  store.ProviderAtlassian,
+		Exchange: func(ctx context.Context, token *store.Token) (*store.UpdateTokenInput, error) {
+			t.Fatalf("exchange called for missing token")
+			return nil, nil
+		},
+	})
+	if err != nil || token != nil {
+		t.Fatalf("RefreshToken for missing token = %+v, %v; want nil", token, err)
+	}
+
+	exchangeErr := errors.New("exchange failed")
+	if _, err := st.Tokens().RefreshToken(ctx, &store.RefreshTokenInput{
+		ProfileID: "owner",
+		Provider:  store.ProviderAtlassian,
+		Exchange: func(ctx context.Context, token *store.Token) (*store.UpdateTokenInput, error) {
+			return nil, exchangeErr
+		},
+	}); !errors.Is(err, exchangeErr) {
+		t.Fatalf("RefreshToken with failing exchange = %v, want %v", err, exchangeErr)
+	}
+
+	// Concurrent refreshes are serialized: the second sees the first's result
+	// and keeps it.
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		exchanged int
+		results   = make([]*store.Token, 2)
+		errs      = make([]error, 2)
+	)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = st.Tokens().RefreshToken(ctx, &store.RefreshTokenInput{
+				ProfileID: "owner",
+				Provider:  store.ProviderAtlassian,
+				Exchange: func(ctx context.Context, token *store.Token) (*store.UpdateTokenInput, error) {
+					if token.AccessToken != "a1" {
+						return nil, nil
+					}
+					mu.Lock()
+					exchanged++
+					mu.Unlock()
+					time.Sleep(50 * time.Millisecond)
+					return &store.UpdateTokenInput{AccessToken: "a2", RefreshToken: "r2", Scopes: token.Scopes}, nil
+				},
+			})
+		}()
+	}
+	wg.Wait()
+
+	if exchanged != 1 {
+		t.Fatalf("exchanged %d times, want 1", exchanged)
+	}
+	for i := range 2 {
+		if errs[i] != nil {
+			t.Fatalf("RefreshToken: %v", errs[i])
+		}
+		if results[i] == nil || results[i].AccessToken != "a2" || results[i].RefreshToken != "r2" {
+			t.Fatalf("RefreshToken = %+v, want the refreshed token", results[i])
+		}
+	}
+
+	history, err := st.Tokens().GetTokenHistory(ctx, &store.GetTokenHistoryInput{ProfileID: "owner", Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetTokenHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Type != store.TokenEventRotated {
+		t.Fatalf("history = %+v, want a single rotated event", history)
+	}
+}
+
+func testListTokens(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "b", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "b", Provider: store.ProviderAtlassian, AccessToken: "a", RefreshToken: "r"})
+	fx.CreateProfile(t, Profile{ID: "a", Provider: store.ProviderAtlassian, DeletedAt: ago(day)})
+	fx.CreateToken(t, store.Token{ProfileID: "a", Provider: store.ProviderAtlassian, AccessToken: "a"})
+	fx.CreateProfile(t, Profile{ID: "c", Provider: store.ProviderGitLab})
+	fx.CreateToken(t, store.Token{ProfileID: "c", Provider: store.ProviderGitLab, AccessToken: "a"})
+
+	first, err := st.Tokens().ListTokens(ctx, &store.ListTokensInput{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListTokens: %v", err)
+	}
+	if len(first.Tokens) != 2 || !first.HasMore {
+		t.Fatalf("first page = %+v, want 2 tokens and more", first)
+	}
+	if first.Tokens[0].ProfileID != "a" || first.Tokens[0].ProfileDeletedAt == nil || first.Tokens[0].HasRefreshToken {
+		t.Fatalf("first token = %+v, want deleted profile a without refresh token", first.Tokens[0])
+	}
+	if first.Tokens[1].ProfileID != "b" || !first.Tokens[1].HasRefreshToken {
+		t.Fatalf("second token = %+v, want b with refresh token", first.Tokens[1])
+	}
+
+	// A token deleted behind the cursor does not shift the next page.
+	if err := st.Tokens().DeleteToken(ctx, &store.DeleteTokenInput{ProfileID: "a", Provider: store.ProviderAtlassian}); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+
+	last := first.Tokens[len(first.Tokens)-1]
+	second, err := st.Tokens().ListTokens(ctx, &store.ListTokensInput{
+		Limit: 2,
+		After: &store.TokenCursor{Provider: last.Provider, ProfileID: last.ProfileID},
+	})
+	if err != nil {
+		t.Fatalf("ListTokens: %v", err)
+	}
+	if len(second.Tokens) != 1 || second.HasMore || second.Tokens[0].Provider != store.ProviderGitLab {
+		t.Fatalf("second page = %+v, want the gitlab token only", second)
+	}
+}
+
+func testListDormantTokens(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	dormant := Profile{ID: "dormant", Provider: store.ProviderAtlassian}
+	active := Profile{ID: "active", Provider: store.ProviderAtlassian}
+	owner := Profile{ID: "owner", Provider: store.ProviderAtlassian}
+
+	for _, p := range []Profile{dormant, active, owner} {
+		fx.CreateProfile(t, p)
+		fx.CreateToken(t, store.Token{ProfileID: p.ID, Provider: p.Provider, AccessToken: "a"})
+	}
+
+	fx.CreateSession(t, Session{ID: "old", Profiles: []Profile{dormant, active, owner}, UpdatedAt: now.Add(-200 * day)})
+	fx.CreateSession(t, Session{ID: "new", Profiles: []Profile{active}, UpdatedAt: now.Add(-day)})
+
+	tokens, err := st.Tokens().ListDormantTokens(ctx, &store.ListDormantTokensInput{
+		InactiveSince:     now.Add(-90 * day),
+		ExcludeProfileIDs: []string{"owner"},
+	})
+	if err != nil {
+		t.Fatalf("ListDormantTokens: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].ProfileID != "dormant" {
+		t.Fatalf("dormant tokens = %+v, want only dormant", tokens)
+	}
+
+	later := Profile{ID: "dormant-later", Provider: store.ProviderAtlassian}
+	fx.CreateProfile(t, later)
+	fx.CreateToken(t, store.Token{ProfileID: later.ID, Provider: later.Provider, AccessToken: "a"})
+	fx.CreateSession(t, Session{ID: "older", Profiles: []Profile{later}, UpdatedAt: now.Add(-100 * day)})
+
+	// The cursor pages past earlier candidates, e.g. ones that failed to sweep.
+	var paged []string
+	var after *store.DormantToken
+	for range 3 {
+		page, err := st.Tokens().ListDormantTokens(ctx, &store.ListDormantTokensInput{
+			InactiveSince:     now.Add(-90 * day),
+			ExcludeProfileIDs: []string{"owner"},
+			After:             after,
+			Limit:             1,
+		})
+		if err != nil {
+			t.Fatalf("ListDormantTokens after %+v: %v", after, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page[0].ProfileID)
+		after = &page[0]
+	}
+	if want := []string{"dormant", "dormant-later"}; !slices.Equal(paged, want) {
+		t.Fatalf("paged dormant tokens = %v, want %v", paged, want)
+	}
+}
+
+func testDeleteToken(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "p", Provider: store.ProviderGitLab})
+	fx.CreateToken(t, store.Token{ProfileID: "p", Provider: store.ProviderGitLab, AccessToken: "a"})
+
+	for range 2 {
+		if err := st.Tokens().DeleteToken(ctx, &store.DeleteTokenInput{ProfileID: "p", Provider: store.ProviderGitLab}); err != nil {
+			t.Fatalf("DeleteToken: %v", err)
+		}
+	}
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "p", Provider: store.ProviderGitLab})
+	if err != nil || token != nil {
+		t.Fatalf("token after delete = %+v, %v", token, err)
+	}
+
+	history, err := st.Tokens().GetTokenHistory(ctx, &store.GetTokenHistoryInput{ProfileID: "p", Provider: store.ProviderGitLab})
+	if err != nil {
+		t.Fatalf("GetTokenHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Type != store.TokenEventDeleted {
+		t.Fatalf("history = %+v, want a single deleted event", history)
+	}
+}
+
+func testCyclePeriod(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "reported-5-days-ago", Provider: store.ProviderAtlassian, ReportedAt: ago(5 * day)})
+	fx.CreateProfile(t, Profile{ID: "reported-10-days-ago", Provider: store.ProviderAtlassian, ReportedAt: ago(10 * day)})
+
+	period, err := st.Settings().GetCyclePeriod(ctx)
+	if err != nil {
+		t.Fatalf("GetCyclePeriod: %v", err)
+	}
+	if period.Days != 7 || period.ChangedAt != nil {
+		t.Fatalf("default period = %d days (changed %v), want 7 days, never changed", period.Days, period.ChangedAt)
+	}
+
+	ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if want := []string{"reported-10-days-ago"}; !slices.Equal(ids, want) {
+		t.Fatalf("accounts with default period = %v, want %v", ids, want)
+	}
+
+	set := func(days int) *store.SetCyclePeriodOutput {
+		t.Helper()
+		out, err := st.Settings().SetCyclePeriod(ctx, &store.SetCyclePeriodInput{Days: days})
+		if err != nil {
+			t.Fatalf("SetCyclePeriod(%d): %v", days, err)
+		}
+		return out
+	}
+
+	if out := set(14); !out.Changed || out.PreviousDays != 7 {
+		t.Fatalf("set 14 = %+v, want changed from 7", out)
+	}
+
+	period, err = st.Settings().GetCyclePeriod(ctx)
+	if err != nil {
+		t.Fatalf("GetCyclePeriod: %v", err)
+	}
+	if period.Days != 14 || period.ChangedAt == nil {
+		t.Fatalf("period = %d days (changed %v), want 14 days with change time", period.Days, period.ChangedAt)
+	}
+	changedAt := *period.ChangedAt
+
+	ids, _ = accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if len(ids) != 0 {
+		t.Fatalf("accounts with 14 day period = %v, want none", ids)
+	}
+
+	if out := set(14); out.Changed || out.PreviousDays != 14 {
+		t.Fatalf("set 14 again = %+v, want unchanged", out)
+	}
+	period, err = st.Settings().GetCyclePeriod(ctx)
+	if err != nil {
+		t.Fatalf("GetCyclePeriod: %v", err)
+	}
+	if period.ChangedAt == nil || !period.ChangedAt.Equal(changedAt) {
+		t.Fatalf("changed at = %v after no-op set, want %v", period.ChangedAt, changedAt)
+	}
+
+	if out := set(3); !out.Changed || out.PreviousDays != 14 {
+		t.Fatalf("set 3 = %+v, want changed from 14", out)
+	}
+
+	ids, _ = accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if want := []string{"reported-10-days-ago", "reported-5-days-ago"}; !slices.Equal(ids, want) {
+		t.Fatalf("accounts with 3 day period = %v, want %v", ids, want)
+	}
+
+	if _, err := st.Settings().SetCyclePeriod(ctx, &store.SetCyclePeriodInput{Days: 0}); err == nil {
+		t.Fatal("SetCyclePeriod(0) succeeded, want error")
+	}
+}
+
+func testPrivacyActions(t *testing.T, newStore Factory) {
+	st, _ := newStore(t)
+	ctx := context.Background()
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-10 * day)
+	at := func(d time.Duration) *time.Time {
+		t := base.Add(d)
+		return &t
+	}
+
+	record := func(action store.PrivacyAction) {
+		t.Helper()
+		if err := st.PrivacyActions().RecordPrivacyAction(ctx, &action); err != nil {
+			t.Fatalf("RecordPrivacyAction(%s, %s): %v", action.AccountID, action.RunID, err)
+		}
+	}
+
+	record(store.PrivacyAction{
+		AccountID: "closed", Provider: store.ProviderAtlassian, Status: "closed",
+		ReceivedAt: base, WorkflowID: "privacy-compliance", RunID: "run-1",
+		Action: store.PrivacyActionDeferred, Error: "erasure failed",
+	})
+	record(store.PrivacyAction{
+		AccountID: "updated", Provider: store.ProviderAtlassian, Status: "updated",
+		ReceivedAt: *at(time.Hour), WorkflowID: "privacy-compliance", RunID: "run-1",
+		Action: store.PrivacyActionRefreshed, CompletedAt: at(time.Hour + time.Minute),
+		Items: map[string]int{"updated": 1},
+	})
+	record(store.PrivacyAction{
+		AccountID: "closed", Provider: store.ProviderAtlassian, Status: "closed",
+		ReceivedAt: *at(7 * day), WorkflowID: "privacy-compliance", RunID: "run-2",
+		Action: store.PrivacyActionErased, CompletedAt: at(7*day + time.Minute),
+		Items: map[string]int{"tokens": 1, "profiles": 1},
+	})
+	// A retried recording of the same run, account and status is ignored.
+	record(store.PrivacyAction{
+		AccountID: "closed", Provider: store.ProviderAtlassian, Status: "closed",
+		ReceivedAt: *at(7 * day), WorkflowID: "privacy-compliance", RunID: "run-2",
+		Action: store.PrivacyActionErased, CompletedAt: at(7*day + 2*time.Minute),
+	})
+
+	if err := st.PrivacyActions().RecordPrivacyAction(ctx, &store.PrivacyAction{AccountID: "closed"}); err == nil {
+		t.Fatal("RecordPrivacyAction without provider, status, run or action succeeded, want error")
+	}
+
+	history, err := st.PrivacyActions().GetAccountPrivacyActions(ctx, &store.GetAccountPrivacyActionsInput{
+		AccountID: "closed",
+		Provider:  store.ProviderAtlassian,
+	})
+	if err != nil {
+		t.Fatalf("GetAccountPrivacyActions: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history = %+v, want 2 entries", history)
+	}
+
+	erased, deferred := history[0], history[1]
+	if erased.Action != store.PrivacyActionErased || erased.RunID != "run-2" || erased.ID == "" {
+		t.Fatalf("newest entry = %+v, want erased by run-2", erased)
+	}
+	if !erased.ReceivedAt.Equal(*at(7 * day)) || erased.CompletedAt == nil || !erased.CompletedAt.Equal(*at(7*day + time.Minute)) {
+		t.Fatalf("erased times = %v / %v, want %v / %v", erased.ReceivedAt, erased.CompletedAt, *at(7 * day), *at(7*day + time.Minute))
+	}
+	if erased.Items["tokens"] != 1 || erased.Items["profiles"] != 1 || erased.Status != "closed" || erased.WorkflowID != "privacy-compliance" {
+		t.Fatalf("erased entry = %+v", erased)
+	}
+	if deferred.Action != store.PrivacyActionDeferred || deferred.CompletedAt != nil || deferred.Error != "erasure failed" || len(deferred.Items) != 0 {
+		t.Fatalf("oldest entry = %+v, want deferred with error", deferred)
+	}
+
+	page, err := st.PrivacyActions().ListPrivacyActions(ctx, &store.ListPrivacyActionsInput{
+		ReceivedFrom: base,
+		ReceivedTo:   *at(7 * day),
+		Limit:        1,
+	})
+	if err != nil {
+		t.Fatalf("ListPrivacyActions: %v", err)
+	}
+	if len(page.Actions) != 1 || page.Actions[0].AccountID != "closed" || !page.HasMore {
+		t.Fatalf("first page = %+v, want the deferred entry and more", page)
+	}
+
+	page, err = st.PrivacyActions().ListPrivacyActions(ctx, &store.ListPrivacyActionsInput{
+		ReceivedFrom: base,
+		ReceivedTo:   *at(7 * day),
+		Limit:        1,
+		Offset:       1,
+	})
+	if err != nil {
+		t.Fatalf("ListPrivacyActions: %v", err)
+	}
+	if len(page.Actions) != 1 || page.Actions[0].AccountID != "updated" || page.HasMore {
+		t.Fatalf("second page = %+v, want the refreshed entry only", page)
+	}
+
+	if _, err := st.PrivacyActions().ListPrivacyActions(ctx, &store.ListPrivacyActionsInput{}); err == nil {
+		t.Fatal("ListPrivacyActions without a range succeeded, want error")
+	}
+}
+
+func pendingErasureIDs(t *testing.T, st store.Store) []string {
+	t.Helper()
+
+	pending, err := st.LegalHolds().ListPendingErasures(context.Background(), &store.ListPendingErasuresInput{})
+	if err != nil {
+		t.Fatalf("ListPendingErasures: %v", err)
+	}
+
+	ids := make([]string, 0, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.AccountID)
+	}
+	return ids
+}
+
+func testLegalHolds(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	key := &store.LegalHoldKey{AccountID: "held", Provider: store.ProviderAtlassian}
+
+	fx.CreateProfile(t, Profile{ID: "held", Provider: store.ProviderAtlassian})
+	fx.CreateToken(t, store.Token{ProfileID: "held", Provider: store.ProviderAtlassian, AccessToken: "access"})
+
+	hold, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "held",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "dispute",
+		PlacedBy:  "legal",
+	})
+	if err != nil {
+		t.Fatalf("PlaceLegalHold: %v", err)
+	}
+
+	extended, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "held",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "dispute, extended",
+	})
+	if err != nil {
+		t.Fatalf("PlaceLegalHold again: %v", err)
+	}
+	if extended.ID != hold.ID || extended.Reason != "dispute, extended" {
+		t.Fatalf("re-placed hold = %+v, want hold %s updated", extended, hold.ID)
+	}
+
+	active, err := st.LegalHolds().GetLegalHold(ctx, key)
+	if err != nil || active == nil || active.ID != hold.ID {
+		t.Fatalf("GetLegalHold = %+v, %v, want hold %s", active, err, hold.ID)
+	}
+
+	out, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: "held"})
+	if err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+	if !out.Deferred || out.HoldID != hold.ID || out.ItemsDeleted != 0 {
+		t.Fatalf("held erasure = %+v, want deferred by %s", out, hold.ID)
+	}
+
+	token, err := st.Tokens().GetToken(ctx, &store.GetTokenInput{ProfileID: "held", Provider: store.ProviderAtlassian})
+	if err != nil || token == nil {
+		t.Fatalf("token of held account = %+v, %v, want kept", token, err)
+	}
+
+	if ids := pendingErasureIDs(t, st); len(ids) != 0 {
+		t.Fatalf("pending erasures while held = %v, want none", ids)
+	}
+
+	released, err := st.LegalHolds().ReleaseLegalHold(ctx, key)
+	if err != nil {
+		t.Fatalf("ReleaseLegalHold: %v", err)
+	}
+	if !released.Released || !released.ErasurePending {
+		t.Fatalf("release = %+v, want released with erasure pending", released)
+	}
+
+	if active, err := st.LegalHolds().GetLegalHold(ctx, key); err != nil || active != nil {
+		t.Fatalf("GetLegalHold after release = %+v, %v, want none", active, err)
+	}
+
+	if ids := pendingErasureIDs(t, st); !slices.Equal(ids, []string{"held"}) {
+		t.Fatalf("pending erasures after release = %v, want [held]", ids)
+	}
+
+	out, err = st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: "held"})
+	if err != nil {
+		t.Fatalf("DeleteUserData after release: %v", err)
+	}
+	if out.Deferred || out.Items.Tokens != 1 || out.Items.Profiles != 1 {
+		t.Fatalf("erasure after release = %+v, want tokens and profile erased", out)
+	}
+
+	if ids := pendingErasureIDs(t, st); len(ids) != 0 {
+		t.Fatalf("pending erasures after erasure = %v, want none", ids)
+	}
+
+	if released, err := st.LegalHolds().ReleaseLegalHold(ctx, key); err != nil || released.Released || released.ErasurePending {
+		t.Fatalf("second release = %+v, %v, want nothing released", released, err)
+	}
+
+	// An expired hold stops deferring and leaves its deferred erasure pending.
+	expiresAt := time.Now().UTC().Add(200 * time.Millisecond)
+	expiring, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "expiring",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "short dispute",
+		ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("PlaceLegalHold with expiry: %v", err)
+	}
+
+	deferred, err := st.LegalHolds().DeferErasure(ctx, &store.LegalHoldKey{AccountID: "expiring", Provider: store.ProviderAtlassian})
+	if err != nil || deferred == nil || deferred.ID != expiring.ID || deferred.ErasureDeferredAt == nil {
+		t.Fatalf("DeferErasure = %+v, %v, want hold %s with deferral", deferred, err, expiring.ID)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	if ids := pendingErasureIDs(t, st); !slices.Equal(ids, []string{"expiring"}) {
+		t.Fatalf("pending erasures after expiry = %v, want [expiring]", ids)
+	}
+
+	renewed, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "expiring",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "new dispute",
+	})
+	if err != nil {
+		t.Fatalf("PlaceLegalHold after expiry: %v", err)
+	}
+	if renewed.ID == expiring.ID || renewed.ErasureDeferredAt != nil {
+		t.Fatalf("hold after expiry = %+v, want a new hold", renewed)
+	}
+	if ids := pendingErasureIDs(t, st); len(ids) != 0 {
+		t.Fatalf("pending erasures under new hold = %v, want none", ids)
+	}
+
+	if _, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{AccountID: "x", Provider: store.ProviderAtlassian}); err == nil {
+		t.Fatal("PlaceLegalHold without reason succeeded, want error")
+	}
+	if _, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "x",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "past",
+		ExpiresAt: ago(time.Minute),
+	}); err == nil {
+		t.Fatal("PlaceLegalHold with past expiry succeeded, want error")
+	}
+}
+
+func testListPendingErasures(t *testing.T, newStore Factory) {
+	st, _ := newStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"first", "second"} {
+		key := &store.LegalHoldKey{AccountID: id, Provider: store.ProviderAtlassian}
+		if _, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{AccountID: id, Provider: store.ProviderAtlassian, Reason: "dispute"}); err != nil {
+			t.Fatalf("PlaceLegalHold: %v", err)
+		}
+		if _, err := st.LegalHolds().DeferErasure(ctx, key); err != nil {
+			t.Fatalf("DeferErasure: %v", err)
+		}
+		if _, err := st.LegalHolds().ReleaseLegalHold(ctx, key); err != nil {
+			t.Fatalf("ReleaseLegalHold: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	first, err := st.LegalHolds().ListPendingErasures(ctx, &store.ListPendingErasuresInput{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPendingErasures: %v", err)
+	}
+	if len(first) != 1 || first[0].AccountID != "first" {
+		t.Fatalf("first page = %+v, want the oldest deferral", first)
+	}
+
+	// A pending erasure that failed is not listed again past the cursor.
+	second, err := st.LegalHolds().ListPendingErasures(ctx, &store.ListPendingErasuresInput{Limit: 1, After: &first[0]})
+	if err != nil {
+		t.Fatalf("ListPendingErasures: %v", err)
+	}
+	if len(second) != 1 || second[0].AccountID != "second" {
+		t.Fatalf("second page = %+v, want the newer deferral", second)
+	}
+
+	rest, err := st.LegalHolds().ListPendingErasures(ctx, &store.ListPendingErasuresInput{Limit: 1, After: &second[0]})
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("page after the last = %+v, %v, want none", rest, err)
+	}
+}
+
+func personalData(t *testing.T, st store.Store, accountID string) *store.PersonalDataEntry {
+	t.Helper()
+
+	entry, err := st.Inventory().GetPersonalData(context.Background(), &store.PersonalDataKey{AccountID: accountID, Provider: store.ProviderAtlassian})
+	if err != nil {
+		t.Fatalf("GetPersonalData: %v", err)
+	}
+	return entry
+}
+
+func testPersonalDataInventory(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	fx.CreateProfile(t, Profile{ID: "signed-in", Provider: store.ProviderAtlassian, UpdatedAt: now.Add(-time.Hour)})
+
+	if entry := personalData(t, st, "signed-in"); entry == nil || !slices.Equal(entry.Stores, []string{store.PersonalDataProfiles}) {
+		t.Fatalf("entry of new profile = %+v, want registered by profiles", entry)
+	}
+
+	if err := st.Inventory().RegisterPersonalData(ctx, &store.RegisterPersonalDataInput{
+		Provider:    store.ProviderAtlassian,
+		Store:       store.PersonalDataJiraUsers,
+		AccountIDs:  []string{"cached-only", "signed-in", "cached-only"},
+		RetrievedAt: now,
+	}); err != nil {
+		t.Fatalf("RegisterPersonalData: %v", err)
+	}
+
+	// An older retrieval never moves the reported updatedAt back.
+	if err := st.Inventory().RegisterPersonalData(ctx, &store.RegisterPersonalDataInput{
+		Provider:    store.ProviderAtlassian,
+		Store:       store.PersonalDataWorklogAuthors,
+		AccountIDs:  []string{"signed-in"},
+		RetrievedAt: now.Add(-2 * time.Hour),
+	}); err != nil {
+		t.Fatalf("RegisterPersonalData: %v", err)
+	}
+
+	entry := personalData(t, st, "signed-in")
+	wantStores := []string{store.PersonalDataJiraUsers, store.PersonalDataProfiles, store.PersonalDataWorklogAuthors}
+	if entry == nil || !slices.Equal(entry.Stores, wantStores) || !entry.DataRetrievedAt.Equal(now) {
+		t.Fatalf("entry = %+v, want stores %v retrieved at %v", entry, wantStores, now)
+	}
+
+	_, out := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian})
+	if len(out.Accounts) != 2 {
+		t.Fatalf("accounts = %+v, want cached-only and signed-in", out.Accounts)
+	}
+	for _, acc := range out.Accounts {
+		if !acc.UpdatedAt.Equal(now) {
+			t.Fatalf("account %s updatedAt = %v, want %v", acc.AccountID, acc.UpdatedAt, now)
+		}
+	}
+	if ids := []string{out.Accounts[0].AccountID, out.Accounts[1].AccountID}; !slices.Equal(ids, []string{"cached-only", "signed-in"}) {
+		t.Fatalf("accounts = %v, want [cached-only signed-in]", ids)
+	}
+
+	if _, err := st.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"cached-only", "signed-in"},
+		ReportedAt: now,
+	}); err != nil {
+		t.Fatalf("UpdateLastReported: %v", err)
+	}
+	if ids, _ := accountIDs(t, st, &store.GetAccountsToReportInput{Provider: store.ProviderAtlassian}); len(ids) != 0 {
+		t.Fatalf("accounts after report = %v, want none", ids)
+	}
+	if entry := personalData(t, st, "cached-only"); entry == nil || entry.ReportedAt == nil || !entry.ReportedAt.Equal(now) {
+		t.Fatalf("reported entry = %+v, want reported at %v", entry, now)
+	}
+
+	// Erasing the profile leaves the data held by the caches registered.
+	if _, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: "signed-in"}); err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+	wantStores = []string{store.PersonalDataJiraUsers, store.PersonalDataWorklogAuthors}
+	if entry := personalData(t, st, "signed-in"); entry == nil || !slices.Equal(entry.Stores, wantStores) {
+		t.Fatalf("entry after erasure = %+v, want stores %v", entry, wantStores)
+	}
+
+	unregistered, err := st.Inventory().UnregisterPersonalData(ctx, &store.UnregisterPersonalDataInput{
+		Provider:   store.ProviderAtlassian,
+		Store:      store.PersonalDataJiraUsers,
+		AccountIDs: []string{"cached-only", "signed-in", "missing"},
+	})
+	if err != nil {
+		t.Fatalf("UnregisterPersonalData: %v", err)
+	}
+	if unregistered.Removed != 1 {
+		t.Fatalf("removed = %d, want 1", unregistered.Removed)
+	}
+	if entry := personalData(t, st, "cached-only"); entry != nil {
+		t.Fatalf("entry without stores = %+v, want none", entry)
+	}
+	if entry := personalData(t, st, "signed-in"); entry == nil || !slices.Equal(entry.Stores, []string{store.PersonalDataWorklogAuthors}) {
+		t.Fatalf("entry after unregister = %+v, want worklog authors only", entry)
+	}
+
+	if err := st.Inventory().RegisterPersonalData(ctx, &store.RegisterPersonalDataInput{
+		Provider:    store.ProviderAtlassian,
+		AccountIDs:  []string{"x"},
+		RetrievedAt: now,
+	}); err == nil {
+		t.Fatal("RegisterPersonalData without store succeeded, want error")
+	}
+}
+
+func testAccountTombstones(t *testing.T, newStore Factory) {
+	st, fx := newStore(t)
+	ctx := context.Background()
+
+	fx.CreateProfile(t, Profile{ID: "closed", Provider: store.ProviderAtlassian})
+	fx.CreateProfile(t, Profile{ID: "open", Provider: store.ProviderAtlassian})
+	fx.CreateProfile(t, Profile{ID: "held", Provider: store.ProviderAtlassian})
+
+	if _, err := st.LegalHolds().PlaceLegalHold(ctx, &store.PlaceLegalHoldInput{
+		AccountID: "held",
+		Provider:  store.ProviderAtlassian,
+		Reason:    "dispute",
+	}); err != nil {
+		t.Fatalf("PlaceLegalHold: %v", err)
+	}
+
+	// Erasing twice keeps a single tombstone.
+	for _, id := range []string{"closed", "closed", "held", "never-stored"} {
+		if _, err := st.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{Provider: store.ProviderAtlassian, AccountID: id}); err != nil {
+			t.Fatalf("DeleteUserData(%s): %v", id, err)
+		}
+	}
+
+	for id, want := range map[string]bool{"closed": true, "never-stored": true, "open": false, "held": false} {
+		got, err := st.AccountTombstones().IsTombstoned(ctx, &store.AccountTombstoneKey{AccountID: id, Provider: store.ProviderAtlassian})
+		if err != nil {
+			t.Fatalf("IsTombstoned(%s): %v", id, err)
+		}
+		if got != want {
+			t.Fatalf("IsTombstoned(%s) = %v, want %v", id, got, want)
+		}
+	}
+
+	if got, err := st.AccountTombstones().IsTombstoned(ctx, &store.AccountTombstoneKey{AccountID: "closed", Provider: store.ProviderGitLab}); err != nil || got {
+		t.Fatalf("IsTombstoned for another provider = %v, %v, want false", got, err)
+	}
+
+	tombstoned, err := st.AccountTombstones().FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: []string{"open", "never-stored", "closed", "held", "closed"},
+	})
+	if err != nil {
+		t.Fatalf("FilterTombstoned: %v", err)
+	}
+	if want := []string{"never-stored", "closed"}; !slices.Equal(tombstoned, want) {
+		t.Fatalf("tombstoned = %v, want %v", tombstoned, want)
+	}
+
+	if _, err := st.AccountTombstones().FilterTombstoned(ctx, &store.FilterTombstonedInput{AccountIDs: []string{"closed"}}); err == nil {
+		t.Fatal("FilterTombstoned without provider succeeded, want error")
+	}
+}
@@ -2,10 +2,14 @@ package store
 
 import (
 	"context"
+	"slices"
 	"time"
 )
 
-const ProviderAtlassian = "atlassian"
+const (
+	ProviderAtlassian = "atlassian"
+	ProviderGitLab    = "gitlab"
+)
 
 // Token represents stored OAuth tokens.
 type Token struct {
@@ -22,6 +26,45 @@ type GetTokenInput struct {
 	Provider  string `json:"provider"`
 }
 
+// TokenEventType classifies a token mutation.
+type TokenEventType string
+
+const (
+	TokenEventRefreshed     TokenEventType = "refreshed"
+	TokenEventRotated       TokenEventType = "rotated"
+	TokenEventRevoked       TokenEventType = "revoked"
+	TokenEventDeleted       TokenEventType = "deleted"
+	TokenEventRefreshFailed TokenEventType = "refresh-failed"
+)
+
+// Actors that mutate tokens.
+const (
+	TokenActorWorker = "worker"
+	TokenActorWeb    = "web"
+)
+
+// TokenEvent records a single token mutation. It never carries secret values.
+type TokenEvent struct {
+	ID            string         `json:"id,omitempty"`
+	ProfileID     string         `json:"profileId"`
+	Provider      string         `json:"provider"`
+	Type          TokenEventType `json:"type"`
+	Actor         string         `json:"actor"`
+	OldExpiresAt  *time.Time     `json:"oldExpiresAt,omitempty"`
+	NewExpiresAt  *time.Time     `json:"newExpiresAt,omitempty"`
+	ScopesAdded   []string       `json:"scopesAdded,omitempty"`
+	ScopesRemoved []string       `json:"scopesRemoved,omitempty"`
+	ErrorClass    string         `json:"errorClass,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// GetTokenHistoryInput contains parameters for reading token events.
+type GetTokenHistoryInput struct {
+	ProfileID string `json:"profileId"`
+	Provider  string `json:"provider"`
+	Limit     int    `json:"limit"`
+}
+
 type UpdateTokenInput struct {
 	ProfileID    string     `json:"profileId"`
 	Provider     string     `json:"provider"`
@@ -29,6 +72,89 @@ type UpdateTokenInput struct {
 	RefreshToken string     `json:"refreshToken,omitempty"`
 	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
 	Scopes       []string   `json:"scopes,omitempty"`
+
+	// Actor identifies who performed the update (TokenActorWorker or TokenActorWeb).
+	Actor string `json:"actor"`
+}
+
+// RefreshTokenInput identifies the token to refresh and performs the exchange.
+type RefreshTokenInput struct {
+	ProfileID string
+	Provider  string
+	// Exchange receives the stored token while it is locked and returns the
+	// values to store, or nil to keep the token as it is (e.g. when another
+	// caller refreshed it first). An error aborts the refresh.
+	Exchange func(ctx context.Context, token *Token) (*UpdateTokenInput, error)
+}
+
+// TokenInfo describes a stored token without its secret values.
+type TokenInfo struct {
+	ProfileID        string     `json:"profileId"`
+	Provider         string     `json:"provider"`
+	HasRefreshToken  bool       `json:"hasRefreshToken"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	Scopes           []string   `json:"scopes,omitempty"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	ProfileDeletedAt *time.Time `json:"profileDeletedAt,omitempty"`
+}
+
+// ListTokensInput contains pagination parameters for listing tokens.
+type ListTokensInput struct {
+	// After is a keyset cursor: only tokens ordered after it are returned.
+	After *TokenCursor `json:"after,omitempty"`
+	Limit int          `json:"limit"`
+}
+
+// TokenCursor is the position of a token in ListTokens order, which is by
+// provider and then profile id.
+type TokenCursor struct {
+	Provider  string `json:"provider"`
+	ProfileID string `json:"profileId"`
+}
+
+// ListTokensOutput contains a page of tokens.
+type ListTokensOutput struct {
+	Tokens  []TokenInfo `json:"tokens"`
+	HasMore bool        `json:"hasMore"`
+}
+
+// TokenHealthReport summarizes a token health scan.
+type TokenHealthReport struct {
+	RunID          string    `json:"runId"`
+	Total          int       `json:"total"`
+	Healthy        int       `json:"healthy"`
+	ExpiringSoon   int       `json:"expiringSoon"`
+	Expired        int       `json:"expired"`
+	ScopesDrifted  int       `json:"scopesDrifted"`
+	ProfileDeleted int       `json:"profileDeleted"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// ListDormantTokensInput contains parameters for finding dormant tokens.
+type ListDormantTokensInput struct {
+	// InactiveSince is the cutoff: tokens whose profile had no session activity
+	// after this instant are dormant.
+	InactiveSince time.Time `json:"inactiveSince"`
+	// ExcludeProfileIDs are never returned (e.g. the worker's owner profile).
+	ExcludeProfileIDs []string `json:"excludeProfileIds,omitempty"`
+	// After is a keyset cursor: only tokens ordered after it are returned, so
+	// callers can page past candidates they could not sweep.
+	After *DormantToken `json:"after,omitempty"`
+	Limit int           `json:"limit"`
+}
+
+// DormantToken identifies a token whose profile has been inactive.
+type DormantToken struct {
+	ProfileID    string    `json:"profileId"`
+	Provider     string    `json:"provider"`
+	LastActiveAt time.Time `json:"lastActiveAt"`
+}
+
+// DeleteTokenInput contains parameters for deleting a token.
+type DeleteTokenInput struct {
+	ProfileID string `json:"profileId"`
+	Provider  string `json:"provider"`
+	Actor     string `json:"actor"`
 }
 
 // TokenStore manages OAuth tokens.
@@ -38,6 +164,58 @@ type TokenStore interface {
 	// GetRefreshableToken returns a token that has a refresh token associated with it.
 	GetRefreshableToken(ctx context.Context, input *GetTokenInput) (*Token, error)
 
+	// ListLinkedTokens returns the token of the profile along with the tokens
+	// of the active profiles linked to it through a shared session, such as a
+	// user's GitLab profile next to their Atlassian one. Tokens are ordered by
+	// provider and profile id.
+	ListLinkedTokens(ctx context.Context, input *GetTokenInput) ([]Token, error)
+
 	// UpdateToken replaces token values (access, refresh, expiry, scopes).
+	// The change is recorded as a refreshed or rotated token event.
 	UpdateToken(ctx context.Context, input *UpdateTokenInput) error
+
+	// RefreshToken locks the token of an active profile while Exchange runs
+	// and stores its result as UpdateToken does. Engines shared between
+	// processes hold the lock in the database, so refreshes on other worker
+	// replicas and token writes by the web app wait for the exchange. It
+	// returns the token as stored afterwards, or nil when there is none.
+	RefreshToken(ctx context.Context, input *RefreshTokenInput) (*Token, error)
+
+	// RecordTokenEvent appends an event to the token history.
+	RecordTokenEvent(ctx context.Context, event *TokenEvent) error
+
+	// GetTokenHistory returns the token events of a profile, newest first.
+	GetTokenHistory(ctx context.Context, input *GetTokenHistoryInput) ([]TokenEvent, error)
+
+	// ListTokens returns a page of all stored tokens, including those of
+	// soft-deleted profiles, ordered by provider and profile id.
+	ListTokens(ctx context.Context, input *ListTokensInput) (*ListTokensOutput, error)
+
+	// ListDormantTokens returns tokens of active profiles without session
+	// activity since the cutoff, least recently active first, then by provider
+	// and profile id.
+	ListDormantTokens(ctx context.Context, input *ListDormantTokensInput) ([]DormantToken, error)
+
+	// DeleteToken removes a token and records a deleted token event.
+	// Deleting a missing token is not an error.
+	DeleteToken(ctx context.Context, input *DeleteTokenInput) error
+
+	// CreateTokenHealthReport persists the summary of a token health scan.
+	CreateTokenHealthReport(ctx context.Context, report *TokenHealthReport) error
+}
+
+// DiffScopes returns the scopes present in next but not in prev (added) and
+// those present in prev but not in next (removed).
+func DiffScopes(prev, next []string) (added, removed []string) {
+	for _, scope := range next {
+		if !slices.Contains(prev, scope) {
+			added = append(added, scope)
+		}
+	}
+	for _, scope := range prev {
+		if !slices.Contains(next, scope) {
+			removed = append(removed, scope)
+		}
+	}
+	return added, removed
 }
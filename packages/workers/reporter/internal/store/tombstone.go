@@ -0,0 +1,51 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrTombstonesDisabled is returned by tombstone lookups when the store was
+// created without a tombstone key.
+var ErrTombstonesDisabled = errors.New("account tombstones are disabled: no tombstone key configured")
+
+// MinTombstoneKeyLength is the shortest accepted account tombstone key.
+const MinTombstoneKeyLength = 32
+
+// TombstoneHasher hashes account IDs for account_tombstones and
+// profile_tombstones. The hash is keyed, so a leaked table cannot be matched
+// against known account IDs. The web app hashes with the same key.
+type TombstoneHasher struct {
+	key []byte
+}
+
+// NewTombstoneHasher returns a hasher for the given secret key.
+func NewTombstoneHasher(key string) (*TombstoneHasher, error) {
+	if len(key) < MinTombstoneKeyLength {
+		return nil, fmt.Errorf("tombstone key must be at least %d bytes", MinTombstoneKeyLength)
+	}
+	return &TombstoneHasher{key: []byte(key)}, nil
+}
+
+// Hash returns the hex-encoded HMAC-SHA256 of the provider and account ID.
+func (h *TombstoneHasher) Hash(provider, accountID string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(provider + ":" + accountID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TombstonedAccounts returns the accountIDs whose hash, at the same index in
+// hashes, is among found. Each account is returned once, in input order.
+func TombstonedAccounts(accountIDs, hashes, found []string) []string {
+	tombstoned := make([]string, 0, len(found))
+	for i, id := range accountIDs {
+		if slices.Contains(found, hashes[i]) && !slices.Contains(tombstoned, id) {
+			tombstoned = append(tombstoned, id)
+		}
+	}
+	return tombstoned
+}
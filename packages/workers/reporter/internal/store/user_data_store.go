@@ -9,36 +9,149 @@ import (
 
 // GetAccountsToReportInput contains parameters for fetching accounts to report.
 type GetAccountsToReportInput struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
+	// Provider selects the inventory entries' provider and is required. Its
+	// policy sets the cycle period.
+	Provider string `json:"provider"`
+	Limit    int    `json:"limit"`
+	// Cursor is the NextCursor of the previous page; empty for the first page.
+	Cursor string `json:"cursor,omitempty"`
+	// IncludeCount requests TotalCount. Callers should only set it on the
+	// first page, since counting scans every matching inventory entry.
+	IncludeCount bool `json:"includeCount,omitempty"`
+	// AccountIDs, when non-nil, restricts the result to these accounts.
+	AccountIDs []string `json:"accountIds,omitempty"`
 }
 
 // GetAccountsToReportOutput contains the paginated result.
 type GetAccountsToReportOutput struct {
-	Accounts   []domain.Account `json:"accounts"`
-	TotalCount int              `json:"totalCount"`
-	HasMore    bool             `json:"hasMore"`
+	Accounts []domain.Account `json:"accounts"`
+	// TotalCount is only populated when IncludeCount was set.
+	TotalCount int    `json:"totalCount"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // UpdateLastReportedInput contains parameters for updating report timestamps.
 type UpdateLastReportedInput struct {
+	// Provider is the accounts' provider and is required.
+	Provider   string    `json:"provider"`
 	AccountIDs []string  `json:"accountIds"`
 	ReportedAt time.Time `json:"reportedAt"`
 }
 
+// UpdateLastReportedOutput lists which accounts were marked as reported.
+// Each account appears once, in input order.
+type UpdateLastReportedOutput struct {
+	// Updated are the accounts whose inventory entry was marked.
+	Updated []string `json:"updated"`
+	// Missed are the accounts with no inventory entry, for example because
+	// they were erased since being listed or never existed.
+	Missed []string `json:"missed"`
+}
+
+// NewUpdateLastReportedOutput splits accountIDs into those among updated and
+// those missed.
+func NewUpdateLastReportedOutput(accountIDs, updated []string) *UpdateLastReportedOutput {
+	marked := make(map[string]bool, len(updated))
+	for _, id := range updated {
+		marked[id] = true
+	}
+
+	out := &UpdateLastReportedOutput{Updated: []string{}, Missed: []string{}}
+	for _, id := range DistinctAccountIDs(accountIDs) {
+		if marked[id] {
+			out.Updated = append(out.Updated, id)
+		} else {
+			out.Missed = append(out.Missed, id)
+		}
+	}
+	return out
+}
+
 // DeleteUserDataInput contains parameters for deleting user data.
 type DeleteUserDataInput struct {
+	// Provider is the account's provider and is required.
+	Provider  string `json:"provider"`
 	AccountID string `json:"accountId"`
 }
 
+// ErasedItems itemizes the rows touched by DeleteUserData, per table.
+type ErasedItems struct {
+	// Tokens is the number of deleted tokens rows.
+	Tokens int `json:"tokens"`
+	// SessionLinks is the number of deleted profiles_on_sessions rows.
+	SessionLinks int `json:"sessionLinks"`
+	// Sessions is the number of deleted sessions that only served the profile.
+	Sessions int `json:"sessions"`
+	// SessionsScrubbed is the number of shared sessions whose data had the
+	// profile's provider credentials removed.
+	SessionsScrubbed int `json:"sessionsScrubbed"`
+	// Profiles is the number of tombstoned profiles rows.
+	Profiles int `json:"profiles"`
+	// Untombstoned is true when the store has no tombstone key, so the
+	// account was erased without a tombstone and its token history was
+	// deleted rather than pseudonymized.
+	Untombstoned bool `json:"untombstoned,omitempty"`
+}
+
+// Total returns the number of rows touched across all tables.
+func (e ErasedItems) Total() int {
+	return e.Tokens + e.SessionLinks + e.Sessions + e.SessionsScrubbed + e.Profiles
+}
+
+// Counts returns the non-zero counts keyed by their JSON names, as recorded
+// in the privacy action ledger.
+func (e ErasedItems) Counts() map[string]int {
+	counts := map[string]int{}
+	for name, n := range map[string]int{
+		"tokens":           e.Tokens,
+		"sessionLinks":     e.SessionLinks,
+		"sessions":         e.Sessions,
+		"sessionsScrubbed": e.SessionsScrubbed,
+		"profiles":         e.Profiles,
+	} {
+		if n > 0 {
+			counts[name] = n
+		}
+	}
+	return counts
+}
+
 // DeleteUserDataOutput contains the result of user data deletion.
 type DeleteUserDataOutput struct {
-	DeletedAt    string `json:"deletedAt"`
-	ItemsDeleted int    `json:"itemsDeleted"`
+	DeletedAt    string      `json:"deletedAt,omitempty"`
+	ItemsDeleted int         `json:"itemsDeleted"`
+	Items        ErasedItems `json:"items"`
+	// Deferred is true when an active legal hold kept the data; nothing was
+	// erased, DeletedAt is empty and the erasure is pending until the hold ends.
+	Deferred bool `json:"deferred,omitempty"`
+	// HoldID is the hold that deferred the erasure.
+	HoldID string `json:"holdId,omitempty"`
+}
+
+// DeleteUserDataBatchInput contains parameters for erasing several accounts.
+type DeleteUserDataBatchInput struct {
+	// Provider is the accounts' provider and is required.
+	Provider   string   `json:"provider"`
+	AccountIDs []string `json:"accountIds"`
+}
+
+// DeleteUserDataResult is the erasure of one account of a batch.
+type DeleteUserDataResult struct {
+	AccountID string `json:"accountId"`
+	DeleteUserDataOutput
+}
+
+// DeleteUserDataBatchOutput contains one result per account, in input order.
+type DeleteUserDataBatchOutput struct {
+	Results []DeleteUserDataResult `json:"results"`
 }
 
 // RefreshUserDataInput contains parameters for refreshing user data.
 type RefreshUserDataInput struct {
+	// Provider is the account's provider and is required. Its policy decides
+	// what the refresh does.
+	Provider  string `json:"provider"`
 	AccountID string `json:"accountId"`
 }
 
@@ -48,22 +161,140 @@ type RefreshUserDataOutput struct {
 	ItemsUpdated int    `json:"itemsUpdated"`
 }
 
+// RefreshUserDataBatchInput contains parameters for refreshing several accounts.
+type RefreshUserDataBatchInput struct {
+	// Provider is the accounts' provider and is required.
+	Provider   string   `json:"provider"`
+	AccountIDs []string `json:"accountIds"`
+}
+
+// RefreshUserDataResult is the refresh of one account of a batch.
+type RefreshUserDataResult struct {
+	AccountID string `json:"accountId"`
+	RefreshUserDataOutput
+}
+
+// RefreshUserDataBatchOutput contains one result per account, in input order.
+type RefreshUserDataBatchOutput struct {
+	Results []RefreshUserDataResult `json:"results"`
+}
+
+// DistinctAccountIDs returns accountIDs without duplicates, in input order.
+func DistinctAccountIDs(accountIDs []string) []string {
+	seen := make(map[string]bool, len(accountIDs))
+	distinct := make([]string, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		if !seen[id] {
+			seen[id] = true
+			distinct = append(distinct, id)
+		}
+	}
+	return distinct
+}
+
+// NewDeleteUserDataBatchOutput builds the result of each distinct account,
+// in input order, from the holds that deferred an erasure (account to hold
+// id) and the items erased per account. An account in neither erased
+// nothing, as DeleteUserData does for an empty account id.
+func NewDeleteUserDataBatchOutput(accountIDs []string, deletedAt time.Time, holdIDs map[string]string, items map[string]*ErasedItems) *DeleteUserDataBatchOutput {
+	out := &DeleteUserDataBatchOutput{Results: []DeleteUserDataResult{}}
+	for _, id := range DistinctAccountIDs(accountIDs) {
+		result := DeleteUserDataResult{AccountID: id}
+		if holdID, held := holdIDs[id]; held {
+			result.Deferred = true
+			result.HoldID = holdID
+		} else {
+			result.DeletedAt = deletedAt.Format(time.RFC3339)
+			if erased, ok := items[id]; ok {
+				result.Items = *erased
+				result.ItemsDeleted = erased.Total()
+			}
+		}
+		out.Results = append(out.Results, result)
+	}
+	return out
+}
+
+// NewRefreshUserDataBatchOutput builds the result of each distinct account,
+// in input order, from the accounts whose profile was refreshed.
+func NewRefreshUserDataBatchOutput(accountIDs []string, refreshedAt time.Time, refreshed []string) *RefreshUserDataBatchOutput {
+	updated := make(map[string]int, len(refreshed))
+	for _, id := range refreshed {
+		updated[id]++
+	}
+
+	out := &RefreshUserDataBatchOutput{Results: []RefreshUserDataResult{}}
+	for _, id := range DistinctAccountIDs(accountIDs) {
+		out.Results = append(out.Results, RefreshUserDataResult{
+			AccountID: id,
+			RefreshUserDataOutput: RefreshUserDataOutput{
+				RefreshedAt:  refreshedAt.Format(time.RFC3339),
+				ItemsUpdated: updated[id],
+			},
+		})
+	}
+	return out
+}
+
+// PurgeDeletedProfilesInput contains parameters for hard-deleting profiles.
+type PurgeDeletedProfilesInput struct {
+	// Provider, when set, limits the purge to profiles of that provider.
+	Provider string `json:"provider,omitempty"`
+	// DeletedBefore selects profiles soft-deleted before this time.
+	DeletedBefore time.Time `json:"deletedBefore"`
+	// Limit bounds the number of profiles purged in one call (default: 100).
+	Limit int `json:"limit,omitempty"`
+}
+
+// PurgeDeletedProfilesOutput contains the result of a purge batch.
+type PurgeDeletedProfilesOutput struct {
+	Purged  int  `json:"purged"`
+	HasMore bool `json:"hasMore"`
+}
+
 // UserDataStore manages user data and account registry for privacy compliance.
+// Every method works on the accounts of one provider, named in its input;
+// how each provider's accounts are kept current is set by its ProviderPolicy.
 type UserDataStore interface {
-	// GetAccountsToReport returns accounts that need to be reported.
-	// Accounts are selected based on:
+	// GetAccountsToReport returns the provider's accounts from the personal
+	// data inventory that need to be reported. Accounts are selected based on:
 	// - Never reported before, OR
 	// - Last reported before (now - cycle period)
+	// The cycle period is the one stored for the provider, or its policy's.
 	GetAccountsToReport(ctx context.Context, input *GetAccountsToReportInput) (*GetAccountsToReportOutput, error)
 
-	// UpdateLastReported marks accounts as reported at the given timestamp.
-	UpdateLastReported(ctx context.Context, input *UpdateLastReportedInput) error
+	// UpdateLastReported marks accounts as reported at the given timestamp,
+	// both in the inventory and on their profiles, and reports which accounts
+	// had no inventory entry to mark. IDs are sent in bounded chunks within a
+	// single transaction.
+	UpdateLastReported(ctx context.Context, input *UpdateLastReportedInput) (*UpdateLastReportedOutput, error)
 
-	// DeleteUserData removes all personal data for the given account.
-	// Called when account status is "closed".
+	// DeleteUserData removes all personal data for the given account in a
+	// single transaction: tokens, session links, sessions that only served the
+	// account, and the profile tombstone. On failure nothing is changed, so
+	// callers may retry. Called when account status is "closed".
+	// The profile leaves the personal data inventory; an account with data in
+	// other stores stays in it, and is reported, until those are unregistered.
+	// An account under an active legal hold is not erased; the erasure is
+	// marked as deferred on the hold instead. A completed erasure resolves any
+	// erasure deferred earlier and tombstones the account.
 	DeleteUserData(ctx context.Context, input *DeleteUserDataInput) (*DeleteUserDataOutput, error)
 
-	// RefreshUserData re-fetches and updates user data for the given account.
-	// Called when account status is "updated".
+	// DeleteUserDataBatch erases several accounts as DeleteUserData does, in
+	// a single transaction. Each account gets its own result, held accounts
+	// included. A session shared by several erased accounts counts for each.
+	DeleteUserDataBatch(ctx context.Context, input *DeleteUserDataBatchInput) (*DeleteUserDataBatchOutput, error)
+
+	// RefreshUserData re-fetches and updates user data for the given account,
+	// as the provider's policy says. Called when account status is "updated".
 	RefreshUserData(ctx context.Context, input *RefreshUserDataInput) (*RefreshUserDataOutput, error)
+
+	// RefreshUserDataBatch refreshes several accounts as RefreshUserData
+	// does, in a single transaction.
+	RefreshUserDataBatch(ctx context.Context, input *RefreshUserDataBatchInput) (*RefreshUserDataBatchOutput, error)
+
+	// PurgeDeletedProfiles hard-deletes a batch of soft-deleted profiles,
+	// oldest first. A hashed tombstone is written for each profile and its
+	// token history is removed; other dependants go by ON DELETE CASCADE.
+	PurgeDeletedProfiles(ctx context.Context, input *PurgeDeletedProfilesInput) (*PurgeDeletedProfilesOutput, error)
 }
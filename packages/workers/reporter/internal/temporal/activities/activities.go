@@ -1,46 +1,66 @@
 package activities
 
 import (
+	"time"
+
 	"go.temporal.io/sdk/client"
 
 	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/broker"
+	"hourly/workers/reporter/internal/notify"
 	"hourly/workers/reporter/internal/store"
 )
 
 // Activities contains all activity implementations for privacy compliance.
 type Activities struct {
-	store             store.Store
-	temporal          client.Client
-	atlassian         *atlassian.Client
-	scheduleID        string
-	ownerProfileID    string
-	oauthClientID     string
-	oauthClientSecret string
-	oauthCallbackURL  string
+	store              store.Store
+	temporal           client.Client
+	atlassian          *atlassian.Client
+	scheduleID         string
+	ownerProfileID     string
+	broker             *broker.Broker
+	revokers           map[string]TokenRevoker
+	notifier           notify.Notifier
+	requiredScopes     map[string][]string
+	tokenExpiryWarning time.Duration
+	tombstoneHasher    *store.TombstoneHasher
 }
 
 // CreateActivitiesOptions contains dependencies for creating activities.
 type CreateActivitiesOptions struct {
-	Store             store.Store
-	Temporal          client.Client
-	Atlassian         *atlassian.Client
-	ScheduleID        string
-	OwnerProfileID    string
-	OAuthClientID     string
-	OAuthClientSecret string
-	OAuthCallbackURL  string
+	Store          store.Store
+	Temporal       client.Client
+	Atlassian      *atlassian.Client
+	ScheduleID     string
+	OwnerProfileID string
+	// Broker performs token refreshes; when nil, refresh activities fail as misconfigured.
+	Broker *broker.Broker
+	// Revokers revoke upstream grants before erasure, keyed by provider.
+	Revokers map[string]TokenRevoker
+	// Notifier delivers operator notifications; when nil they are logged.
+	Notifier notify.Notifier
+	// RequiredScopes lists the scopes a healthy token must hold, keyed by provider.
+	RequiredScopes map[string][]string
+	// TokenExpiryWarning is how far ahead a non-refreshable token counts as expiring soon.
+	TokenExpiryWarning time.Duration
+	// TombstoneHasher pseudonymizes profile IDs written to the audit log;
+	// when nil they are left out.
+	TombstoneHasher *store.TombstoneHasher
 }
 
 // New creates a new Activities instance with the given dependencies.
 func New(options *CreateActivitiesOptions) *Activities {
 	return &Activities{
-		store:             options.Store,
-		atlassian:         options.Atlassian,
-		temporal:          options.Temporal,
-		scheduleID:        options.ScheduleID,
-		ownerProfileID:    options.OwnerProfileID,
-		oauthClientID:     options.OAuthClientID,
-		oauthClientSecret: options.OAuthClientSecret,
-		oauthCallbackURL:  options.OAuthCallbackURL,
+		store:              options.Store,
+		atlassian:          options.Atlassian,
+		temporal:           options.Temporal,
+		scheduleID:         options.ScheduleID,
+		ownerProfileID:     options.OwnerProfileID,
+		broker:             options.Broker,
+		revokers:           options.Revokers,
+		notifier:           options.Notifier,
+		requiredScopes:     options.RequiredScopes,
+		tokenExpiryWarning: options.TokenExpiryWarning,
+		tombstoneHasher:    options.TombstoneHasher,
 	}
 }
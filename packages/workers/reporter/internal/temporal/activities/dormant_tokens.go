@@ -0,0 +1,105 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// ListDormantTokensInput contains parameters for finding dormant tokens.
+type ListDormantTokensInput struct {
+	// InactiveFor is how long a profile must have had no session activity.
+	InactiveFor time.Duration `json:"inactiveFor"`
+	// After is the last candidate of the previous page, if any.
+	After *store.DormantToken `json:"after,omitempty"`
+	Limit int                 `json:"limit"`
+}
+
+// ListDormantTokensOutput contains the dormant token candidates.
+type ListDormantTokensOutput struct {
+	Tokens []store.DormantToken `json:"tokens"`
+}
+
+// ListDormantTokens finds tokens whose profile has been inactive for the given period.
+// The owner profile's token is never a candidate.
+func (a *Activities) ListDormantTokens(ctx context.Context, input *ListDormantTokensInput) (*ListDormantTokensOutput, error) {
+	var exclude []string
+	if a.ownerProfileID != "" {
+		exclude = append(exclude, a.ownerProfileID)
+	}
+
+	tokens, err := a.store.Tokens().ListDormantTokens(ctx, &store.ListDormantTokensInput{
+		InactiveSince:     time.Now().UTC().Add(-input.InactiveFor),
+		ExcludeProfileIDs: exclude,
+		After:             input.After,
+		Limit:             input.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListDormantTokensOutput{Tokens: tokens}, nil
+}
+
+// SweepDormantTokenInput identifies the token to sweep.
+type SweepDormantTokenInput struct {
+	ProfileID    string    `json:"profileId"`
+	Provider     string    `json:"provider"`
+	LastActiveAt time.Time `json:"lastActiveAt"`
+}
+
+// SweepDormantTokenOutput contains the sweep result.
+type SweepDormantTokenOutput struct {
+	Revocation TokenRevocation `json:"revocation"`
+}
+
+// SweepDormantToken revokes a dormant token upstream, deletes it and writes an
+// audit entry. A failed revocation does not prevent the deletion. The audit
+// entry identifies the profile by its tombstone hash, or not at all when no
+// tombstone key is configured.
+func (a *Activities) SweepDormantToken(ctx context.Context, input *SweepDormantTokenInput) (*SweepDormantTokenOutput, error) {
+	info := activity.GetInfo(ctx)
+
+	revocation := a.revokeToken(ctx, input.ProfileID, input.Provider)
+
+	if err := a.store.Tokens().DeleteToken(ctx, &store.DeleteTokenInput{
+		ProfileID: input.ProfileID,
+		Provider:  input.Provider,
+		Actor:     store.TokenActorWorker,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := a.store.Audit().RecordAuditLog(ctx, &store.AuditLogEntry{
+		ActionType:         store.AuditActionDataModification,
+		ActionDescription:  "Deleted dormant OAuth token",
+		TargetResourceType: store.AuditTargetToken,
+		TargetResourceID:   a.hashProfileID(input.Provider, input.ProfileID),
+		Outcome:            store.AuditOutcomeSuccess,
+		CorrelationID:      info.WorkflowExecution.ID,
+		RequestID:          info.WorkflowExecution.RunID,
+		RequestPath:        "temporal://" + info.WorkflowType.Name + "/" + info.ActivityType.Name,
+		RequestMethod:      "ACTIVITY",
+		Metadata: map[string]any{
+			"provider":         input.Provider,
+			"lastActiveAt":     input.LastActiveAt,
+			"revocationStatus": revocation.Status,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &SweepDormantTokenOutput{Revocation: revocation}, nil
+}
+
+// hashProfileID returns the tombstone hash of the profile, or "" without a
+// tombstone hasher.
+func (a *Activities) hashProfileID(provider, profileID string) string {
+	if a.tombstoneHasher == nil {
+		return ""
+	}
+	return a.tombstoneHasher.Hash(provider, profileID)
+}
@@ -9,8 +9,11 @@ import (
 
 // GetAccountsToReportInput contains pagination parameters.
 type GetAccountsToReportInput struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
+	Limit        int    `json:"limit"`
+	Cursor       string `json:"cursor,omitempty"`
+	IncludeCount bool   `json:"includeCount,omitempty"`
+	// AccountIDs, when non-nil, restricts the result to these accounts.
+	AccountIDs []string `json:"accountIds,omitempty"`
 }
 
 // GetAccountsToReportOutput contains accounts and pagination info.
@@ -18,13 +21,17 @@ type GetAccountsToReportOutput struct {
 	Accounts   []domain.Account `json:"accounts"`
 	TotalCount int              `json:"totalCount"`
 	HasMore    bool             `json:"hasMore"`
+	NextCursor string           `json:"nextCursor,omitempty"`
 }
 
 // GetAccountsToReport fetches accounts that need to be reported.
 func (a *Activities) GetAccountsToReport(ctx context.Context, input *GetAccountsToReportInput) (*GetAccountsToReportOutput, error) {
 	result, err := a.store.UserData().GetAccountsToReport(ctx, &store.GetAccountsToReportInput{
-		Limit:  input.Limit,
-		Offset: input.Offset,
+		Provider:     store.ProviderAtlassian,
+		Limit:        input.Limit,
+		Cursor:       input.Cursor,
+		IncludeCount: input.IncludeCount,
+		AccountIDs:   input.AccountIDs,
 	})
 	if err != nil {
 		return nil, err
@@ -34,5 +41,6 @@ func (a *Activities) GetAccountsToReport(ctx context.Context, input *GetAccounts
 		Accounts:   result.Accounts,
 		TotalCount: result.TotalCount,
 		HasMore:    result.HasMore,
+		NextCursor: result.NextCursor,
 	}, nil
 }
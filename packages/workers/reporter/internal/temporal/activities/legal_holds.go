@@ -0,0 +1,34 @@
+package activities
+
+import (
+	"context"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// ListPendingErasuresInput contains parameters for listing pending erasures.
+type ListPendingErasuresInput struct {
+	// After is the last erasure of the previous page, if any.
+	After *store.PendingErasure `json:"after,omitempty"`
+	Limit int                   `json:"limit"`
+}
+
+// ListPendingErasuresOutput contains Atlassian accounts whose erasure a legal
+// hold deferred and that are no longer held.
+type ListPendingErasuresOutput struct {
+	Erasures []store.PendingErasure `json:"erasures"`
+}
+
+// ListPendingErasures lists deferred Atlassian erasures that may now run.
+func (a *Activities) ListPendingErasures(ctx context.Context, input *ListPendingErasuresInput) (*ListPendingErasuresOutput, error) {
+	erasures, err := a.store.LegalHolds().ListPendingErasures(ctx, &store.ListPendingErasuresInput{
+		Provider: store.ProviderAtlassian,
+		After:    input.After,
+		Limit:    input.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListPendingErasuresOutput{Erasures: erasures}, nil
+}
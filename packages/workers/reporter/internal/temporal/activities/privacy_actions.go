@@ -0,0 +1,58 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"hourly/workers/reporter/internal/domain"
+	"hourly/workers/reporter/internal/store"
+)
+
+// RecordPrivacyActionInput describes what was done about an account status
+// received from Atlassian.
+type RecordPrivacyActionInput struct {
+	AccountID   string                  `json:"accountId"`
+	Status      domain.AccountStatus    `json:"status"`
+	ReceivedAt  time.Time               `json:"receivedAt"`
+	Action      store.PrivacyActionType `json:"action"`
+	CompletedAt *time.Time              `json:"completedAt,omitempty"`
+	Items       map[string]int          `json:"items,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+}
+
+// RecordPrivacyAction appends the action to the privacy action ledger under
+// the calling workflow's ID and run ID. Retries of the same run are no-ops.
+func (a *Activities) RecordPrivacyAction(ctx context.Context, input *RecordPrivacyActionInput) error {
+	info := activity.GetInfo(ctx)
+
+	return a.store.PrivacyActions().RecordPrivacyAction(ctx, &store.PrivacyAction{
+		AccountID:   input.AccountID,
+		Provider:    store.ProviderAtlassian,
+		Status:      string(input.Status),
+		ReceivedAt:  input.ReceivedAt,
+		WorkflowID:  info.WorkflowExecution.ID,
+		RunID:       info.WorkflowExecution.RunID,
+		Action:      input.Action,
+		CompletedAt: input.CompletedAt,
+		Items:       input.Items,
+		Error:       input.Error,
+	})
+}
+
+// RecordPrivacyActionsInput contains the ledger entries of a batch.
+type RecordPrivacyActionsInput struct {
+	Actions []RecordPrivacyActionInput `json:"actions"`
+}
+
+// RecordPrivacyActions records each action as RecordPrivacyAction does.
+// Entries recorded before a failure are no-ops when the activity is retried.
+func (a *Activities) RecordPrivacyActions(ctx context.Context, input *RecordPrivacyActionsInput) error {
+	for i := range input.Actions {
+		if err := a.RecordPrivacyAction(ctx, &input.Actions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"hourly/workers/reporter/internal/store"
+)
+
+// PurgeDeletedProfilesBatchInput contains parameters for one purge batch.
+type PurgeDeletedProfilesBatchInput struct {
+	// DeletedBefore selects profiles soft-deleted before this time.
+	DeletedBefore time.Time `json:"deletedBefore"`
+	Limit         int       `json:"limit"`
+}
+
+// PurgeDeletedProfilesBatchOutput contains the batch result.
+type PurgeDeletedProfilesBatchOutput struct {
+	Purged  int  `json:"purged"`
+	HasMore bool `json:"hasMore"`
+}
+
+// PurgeDeletedProfilesBatch hard-deletes one bounded batch of soft-deleted
+// profiles, leaving hashed tombstones behind, and audits the batch.
+func (a *Activities) PurgeDeletedProfilesBatch(ctx context.Context, input *PurgeDeletedProfilesBatchInput) (*PurgeDeletedProfilesBatchOutput, error) {
+	info := activity.GetInfo(ctx)
+
+	result, err := a.store.UserData().PurgeDeletedProfiles(ctx, &store.PurgeDeletedProfilesInput{
+		DeletedBefore: input.DeletedBefore,
+		Limit:         input.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Purged > 0 {
+		if err := a.store.Audit().RecordAuditLog(ctx, &store.AuditLogEntry{
+			ActionType:         store.AuditActionDataModification,
+			ActionDescription:  "Purged soft-deleted profiles past retention",
+			TargetResourceType: store.AuditTargetProfile,
+			Outcome:            store.AuditOutcomeSuccess,
+			CorrelationID:      info.WorkflowExecution.ID,
+			RequestID:          info.WorkflowExecution.RunID,
+			RequestPath:        "temporal://" + info.WorkflowType.Name + "/" + info.ActivityType.Name,
+			RequestMethod:      "ACTIVITY",
+			Metadata: map[string]any{
+				"deletedBefore": input.DeletedBefore,
+				"purged":        result.Purged,
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &PurgeDeletedProfilesBatchOutput{
+		Purged:  result.Purged,
+		HasMore: result.HasMore,
+	}, nil
+}
@@ -2,11 +2,12 @@ package activities
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
 
-	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/broker"
 	"hourly/workers/reporter/internal/store"
 )
 
@@ -44,8 +45,9 @@ type RefreshOwnerAccessTokenOutput struct {
 }
 
 // RefreshOwnerAccessToken exchanges the owner's refresh token for a new access token and updates storage.
+// The exchange goes through the token broker so it never races with broker API callers.
 func (a *Activities) RefreshOwnerAccessToken(ctx context.Context) (*RefreshOwnerAccessTokenOutput, error) {
-	if a.oauthClientID == "" || a.oauthClientSecret == "" || a.oauthCallbackURL == "" {
+	if a.broker == nil {
 		return nil, temporal.NewNonRetryableApplicationError(
 			"atlassian oauth client configuration is required",
 			"MissingOAuthConfig",
@@ -53,54 +55,26 @@ func (a *Activities) RefreshOwnerAccessToken(ctx context.Context) (*RefreshOwner
 		)
 	}
 
-	token, err := a.store.Tokens().GetRefreshableToken(ctx, &store.GetTokenInput{
-		ProfileID: a.ownerProfileID,
-		Provider:  store.ProviderAtlassian,
-	})
+	token, err := a.broker.Refresh(ctx, a.ownerProfileID, store.ProviderAtlassian)
 	if err != nil {
-		return nil, err
-	}
-
-	if token == nil || token.RefreshToken == "" {
-		return nil, temporal.NewNonRetryableApplicationError(
-			"atlassian refresh token not found",
-			"MissingRefreshableToken",
-			nil,
-		)
-	}
-
-	result, err := atlassian.RefreshAccessToken(ctx, &atlassian.RefreshAccessTokenInput{
-		ClientID:     a.oauthClientID,
-		ClientSecret: a.oauthClientSecret,
-		RefreshToken: token.RefreshToken,
-		CallbackURL:  a.oauthCallbackURL,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	refreshToken := result.RefreshToken
-	if refreshToken == "" {
-		refreshToken = token.RefreshToken
-	}
-
-	scopes := result.Scopes
-	if len(scopes) == 0 {
-		scopes = token.Scopes
-	}
-
-	if err := a.store.Tokens().UpdateToken(ctx, &store.UpdateTokenInput{
-		ProfileID:    token.ProfileID,
-		Provider:     token.Provider,
-		AccessToken:  result.AccessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    result.ExpiresAt,
-		Scopes:       scopes,
-	}); err != nil {
+		switch {
+		case errors.Is(err, broker.ErrUnsupportedProvider):
+			return nil, temporal.NewNonRetryableApplicationError(
+				"atlassian oauth client configuration is required",
+				"MissingOAuthConfig",
+				nil,
+			)
+		case errors.Is(err, broker.ErrTokenNotFound), errors.Is(err, broker.ErrNotRefreshable):
+			return nil, temporal.NewNonRetryableApplicationError(
+				"atlassian refresh token not found",
+				"MissingRefreshableToken",
+				nil,
+			)
+		}
 		return nil, err
 	}
 
 	return &RefreshOwnerAccessTokenOutput{
-		ExpiresAt: result.ExpiresAt,
+		ExpiresAt: token.ExpiresAt,
 	}, nil
 }
@@ -3,7 +3,9 @@ package activities
 import (
 	"context"
 	"errors"
+	"slices"
 
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
 
 	"hourly/workers/reporter/internal/atlassian"
@@ -21,9 +23,13 @@ type ReportAccountsBatchOutput struct {
 	AccountsToRefresh []string `json:"accountsToRefresh,omitempty"`
 	CyclePeriodDays   int      `json:"cyclePeriodDays,omitempty"`
 	NoActionRequired  bool     `json:"noActionRequired"`
+	// AccountsTombstoned lists reported accounts that were closed and erased
+	// before. They are included in AccountsToClose whatever Atlassian says.
+	AccountsTombstoned []string `json:"accountsTombstoned,omitempty"`
 }
 
 // ReportAccountsBatch reports a batch of accounts (max 90) to Atlassian.
+// Accounts that reappear in the inventory after being closed are closed again.
 func (a *Activities) ReportAccountsBatch(ctx context.Context, input *ReportAccountsBatchInput) (*ReportAccountsBatchOutput, error) {
 	if len(input.Accounts) > atlassian.MaxAccountsPerBatch {
 		return nil, temporal.NewNonRetryableApplicationError(
@@ -33,6 +39,16 @@ func (a *Activities) ReportAccountsBatch(ctx context.Context, input *ReportAccou
 		)
 	}
 
+	ids := make([]string, 0, len(input.Accounts))
+	for _, acc := range input.Accounts {
+		ids = append(ids, acc.AccountID)
+	}
+
+	tombstoned, err := a.filterTombstoned(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
 	result, err := a.atlassian.ReportAccounts(ctx, input.Accounts)
 	if err != nil {
 		// Handle rate limiting - return retryable error
@@ -84,5 +100,20 @@ func (a *Activities) ReportAccountsBatch(ctx context.Context, input *ReportAccou
 		}
 	}
 
+	if len(tombstoned) > 0 {
+		activity.GetLogger(ctx).Warn("Tombstoned accounts reappeared in the inventory", "count", len(tombstoned))
+
+		output.AccountsTombstoned = tombstoned
+		output.AccountsToRefresh = slices.DeleteFunc(output.AccountsToRefresh, func(id string) bool {
+			return slices.Contains(tombstoned, id)
+		})
+		for _, id := range tombstoned {
+			if !slices.Contains(output.AccountsToClose, id) {
+				output.AccountsToClose = append(output.AccountsToClose, id)
+			}
+		}
+		output.NoActionRequired = false
+	}
+
 	return output, nil
 }
@@ -0,0 +1,249 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"hourly/workers/reporter/internal/notify"
+	"hourly/workers/reporter/internal/store"
+)
+
+// TokenHealth classifies a stored token.
+type TokenHealth string
+
+const (
+	TokenHealthHealthy        TokenHealth = "healthy"
+	TokenHealthExpiringSoon   TokenHealth = "expiring-soon"
+	TokenHealthExpired        TokenHealth = "expired"
+	TokenHealthScopesDrifted  TokenHealth = "scopes-drifted"
+	TokenHealthProfileDeleted TokenHealth = "profile-deleted"
+)
+
+// TokenHealthSeverity ranks unhealthy classes, worst first.
+var TokenHealthSeverity = []TokenHealth{
+	TokenHealthProfileDeleted,
+	TokenHealthExpired,
+	TokenHealthScopesDrifted,
+	TokenHealthExpiringSoon,
+}
+
+const defaultTokenExpiryWarning = 24 * time.Hour
+
+// TokenHealthCounts contains the number of tokens per health class.
+type TokenHealthCounts struct {
+	Total          int `json:"total"`
+	Healthy        int `json:"healthy"`
+	ExpiringSoon   int `json:"expiringSoon"`
+	Expired        int `json:"expired"`
+	ScopesDrifted  int `json:"scopesDrifted"`
+	ProfileDeleted int `json:"profileDeleted"`
+}
+
+// Add accumulates other into c.
+func (c *TokenHealthCounts) Add(other TokenHealthCounts) {
+	c.Total += other.Total
+	c.Healthy += other.Healthy
+	c.ExpiringSoon += other.ExpiringSoon
+	c.Expired += other.Expired
+	c.ScopesDrifted += other.ScopesDrifted
+	c.ProfileDeleted += other.ProfileDeleted
+}
+
+func (c *TokenHealthCounts) inc(health TokenHealth) {
+	c.Total++
+	switch health {
+	case TokenHealthHealthy:
+		c.Healthy++
+	case TokenHealthExpiringSoon:
+		c.ExpiringSoon++
+	case TokenHealthExpired:
+		c.Expired++
+	case TokenHealthScopesDrifted:
+		c.ScopesDrifted++
+	case TokenHealthProfileDeleted:
+		c.ProfileDeleted++
+	}
+}
+
+// TokenHealthOffender identifies an unhealthy token.
+type TokenHealthOffender struct {
+	ProfileID     string      `json:"profileId"`
+	Provider      string      `json:"provider"`
+	Health        TokenHealth `json:"health"`
+	ExpiresAt     *time.Time  `json:"expiresAt,omitempty"`
+	MissingScopes []string    `json:"missingScopes,omitempty"`
+}
+
+// ScanTokenHealthPageInput contains pagination parameters.
+type ScanTokenHealthPageInput struct {
+	// After is the Next cursor of the previous page, if any.
+	After *store.TokenCursor `json:"after,omitempty"`
+	Limit int                `json:"limit"`
+}
+
+// ScanTokenHealthPageOutput contains the classification of one page of tokens.
+type ScanTokenHealthPageOutput struct {
+	Counts    TokenHealthCounts     `json:"counts"`
+	Offenders []TokenHealthOffender `json:"offenders,omitempty"`
+	HasMore   bool                  `json:"hasMore"`
+	// Next is the cursor of the following page when HasMore is set.
+	Next *store.TokenCursor `json:"next,omitempty"`
+}
+
+// ScanTokenHealthPage classifies a page of stored tokens.
+func (a *Activities) ScanTokenHealthPage(ctx context.Context, input *ScanTokenHealthPageInput) (*ScanTokenHealthPageOutput, error) {
+	result, err := a.store.Tokens().ListTokens(ctx, &store.ListTokensInput{
+		After: input.After,
+		Limit: input.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	output := &ScanTokenHealthPageOutput{HasMore: result.HasMore}
+	if result.HasMore && len(result.Tokens) > 0 {
+		last := result.Tokens[len(result.Tokens)-1]
+		output.Next = &store.TokenCursor{Provider: last.Provider, ProfileID: last.ProfileID}
+	}
+
+	for _, token := range result.Tokens {
+		health, missing := a.classifyToken(token, now)
+		output.Counts.inc(health)
+
+		if health != TokenHealthHealthy {
+			output.Offenders = append(output.Offenders, TokenHealthOffender{
+				ProfileID:     token.ProfileID,
+				Provider:      token.Provider,
+				Health:        health,
+				ExpiresAt:     token.ExpiresAt,
+				MissingScopes: missing,
+			})
+		}
+	}
+
+	return output, nil
+}
+
+// classifyToken returns the worst applicable health class for the token and,
+// for drifted scopes, the required scopes the token lacks.
+func (a *Activities) classifyToken(token store.TokenInfo, now time.Time) (TokenHealth, []string) {
+	if token.ProfileDeletedAt != nil {
+		return TokenHealthProfileDeleted, nil
+	}
+
+	if !token.HasRefreshToken && token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return TokenHealthExpired, nil
+	}
+
+	var missing []string
+	for _, scope := range a.requiredScopes[token.Provider] {
+		if !slices.Contains(token.Scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return TokenHealthScopesDrifted, missing
+	}
+
+	warning := a.tokenExpiryWarning
+	if warning <= 0 {
+		warning = defaultTokenExpiryWarning
+	}
+
+	if !token.HasRefreshToken && token.ExpiresAt != nil && token.ExpiresAt.Before(now.Add(warning)) {
+		return TokenHealthExpiringSoon, nil
+	}
+
+	return TokenHealthHealthy, nil
+}
+
+// RecordTokenHealthReportInput contains the aggregated scan results.
+type RecordTokenHealthReportInput struct {
+	Counts TokenHealthCounts `json:"counts"`
+}
+
+// RecordTokenHealthReport persists the scan summary and publishes it as metrics.
+func (a *Activities) RecordTokenHealthReport(ctx context.Context, input *RecordTokenHealthReportInput) error {
+	info := activity.GetInfo(ctx)
+
+	if err := a.store.Tokens().CreateTokenHealthReport(ctx, &store.TokenHealthReport{
+		RunID:          info.WorkflowExecution.RunID,
+		Total:          input.Counts.Total,
+		Healthy:        input.Counts.Healthy,
+		ExpiringSoon:   input.Counts.ExpiringSoon,
+		Expired:        input.Counts.Expired,
+		ScopesDrifted:  input.Counts.ScopesDrifted,
+		ProfileDeleted: input.Counts.ProfileDeleted,
+		CreatedAt:      time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	metrics := activity.GetMetricsHandler(ctx)
+	metrics.Gauge("token_health_total").Update(float64(input.Counts.Total))
+
+	for health, count := range map[TokenHealth]int{
+		TokenHealthHealthy:        input.Counts.Healthy,
+		TokenHealthExpiringSoon:   input.Counts.ExpiringSoon,
+		TokenHealthExpired:        input.Counts.Expired,
+		TokenHealthScopesDrifted:  input.Counts.ScopesDrifted,
+		TokenHealthProfileDeleted: input.Counts.ProfileDeleted,
+	} {
+		metrics.WithTags(map[string]string{"health": string(health)}).
+			Gauge("token_health_tokens").
+			Update(float64(count))
+	}
+
+	return nil
+}
+
+// NotifyTokenHealthInput contains the offenders to notify about.
+type NotifyTokenHealthInput struct {
+	Counts    TokenHealthCounts     `json:"counts"`
+	Offenders []TokenHealthOffender `json:"offenders"`
+}
+
+// NotifyTokenHealth notifies operators about the worst unhealthy tokens.
+func (a *Activities) NotifyTokenHealth(ctx context.Context, input *NotifyTokenHealthInput) error {
+	if len(input.Offenders) == 0 {
+		return nil
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d of %d stored tokens are unhealthy.\n",
+		input.Counts.Total-input.Counts.Healthy, input.Counts.Total)
+
+	for _, offender := range input.Offenders {
+		fmt.Fprintf(&text, "- %s/%s: %s", offender.Provider, offender.ProfileID, offender.Health)
+		if len(offender.MissingScopes) > 0 {
+			fmt.Fprintf(&text, " (missing %s)", strings.Join(offender.MissingScopes, ", "))
+		}
+		text.WriteString("\n")
+	}
+
+	message := &notify.Message{
+		Subject: "Token health report",
+		Text:    text.String(),
+		Fields: map[string]any{
+			"total":          input.Counts.Total,
+			"healthy":        input.Counts.Healthy,
+			"expiringSoon":   input.Counts.ExpiringSoon,
+			"expired":        input.Counts.Expired,
+			"scopesDrifted":  input.Counts.ScopesDrifted,
+			"profileDeleted": input.Counts.ProfileDeleted,
+		},
+	}
+
+	if a.notifier == nil {
+		activity.GetLogger(ctx).Warn(message.Subject, "text", message.Text)
+		return nil
+	}
+
+	return a.notifier.Notify(ctx, message)
+}
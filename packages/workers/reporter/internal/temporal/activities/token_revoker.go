@@ -0,0 +1,135 @@
+package activities
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+
+	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/store"
+)
+
+// TokenRevoker revokes an OAuth grant at the provider that issued it.
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, token *store.Token) error
+}
+
+// TokenRevokerFunc adapts a function to the TokenRevoker interface.
+type TokenRevokerFunc func(ctx context.Context, token *store.Token) error
+
+// RevokeToken calls f(ctx, token).
+func (f TokenRevokerFunc) RevokeToken(ctx context.Context, token *store.Token) error {
+	return f(ctx, token)
+}
+
+// Revocation statuses reported in erasure results.
+const (
+	RevocationStatusRevoked     = "revoked"
+	RevocationStatusFailed      = "failed"
+	RevocationStatusUnsupported = "unsupported"
+	RevocationStatusNoToken     = "no-token"
+)
+
+// TokenRevocation describes the outcome of revoking a stored token upstream.
+type TokenRevocation struct {
+	Provider  string `json:"provider"`
+	ProfileID string `json:"profileId,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	// Reason explains an unsupported revocation, so that erasure results
+	// never present a grant left valid upstream as handled.
+	Reason string `json:"reason,omitempty"`
+}
+
+// revocationUnsupported holds, per provider, why its grants cannot be revoked
+// upstream at all. Other providers without a revoker are misconfigured.
+var revocationUnsupported = map[string]string{
+	store.ProviderAtlassian: atlassian.RevocationUnsupported,
+}
+
+// revokeToken revokes the stored token for the profile at its provider.
+// Failures are reported in the result rather than returned, so that callers
+// can proceed with local erasure regardless of the provider's availability.
+func (a *Activities) revokeToken(ctx context.Context, profileID, provider string) TokenRevocation {
+	token, err := a.store.Tokens().GetToken(ctx, &store.GetTokenInput{
+		ProfileID: profileID,
+		Provider:  provider,
+	})
+	if err != nil {
+		return TokenRevocation{Provider: provider, ProfileID: profileID, Status: RevocationStatusFailed, Error: err.Error()}
+	}
+
+	if token == nil {
+		return TokenRevocation{Provider: provider, ProfileID: profileID, Status: RevocationStatusNoToken}
+	}
+
+	return a.revoke(ctx, token)
+}
+
+// revokeLinkedTokens revokes the account's token and the tokens of the
+// profiles linked to it through a shared session, such as the user's GitLab
+// profile, each at its own provider. The owner profile's token is never
+// revoked. It returns one result per token, or a single result for the
+// account when it has no token or the tokens could not be listed.
+func (a *Activities) revokeLinkedTokens(ctx context.Context, accountID, provider string) []TokenRevocation {
+	tokens, err := a.store.Tokens().ListLinkedTokens(ctx, &store.GetTokenInput{
+		ProfileID: accountID,
+		Provider:  provider,
+	})
+	if err != nil {
+		return []TokenRevocation{{Provider: provider, ProfileID: accountID, Status: RevocationStatusFailed, Error: err.Error()}}
+	}
+
+	var revocations []TokenRevocation
+	for i := range tokens {
+		if tokens[i].ProfileID == a.ownerProfileID && tokens[i].ProfileID != accountID {
+			continue
+		}
+		revocations = append(revocations, a.revoke(ctx, &tokens[i]))
+	}
+
+	if len(revocations) == 0 {
+		return []TokenRevocation{{Provider: provider, ProfileID: accountID, Status: RevocationStatusNoToken}}
+	}
+	return revocations
+}
+
+// revoke revokes token with its provider's revoker and records the
+// revocation in the token history.
+func (a *Activities) revoke(ctx context.Context, token *store.Token) TokenRevocation {
+	result := TokenRevocation{Provider: token.Provider, ProfileID: token.ProfileID}
+
+	revoker, ok := a.revokers[token.Provider]
+	if !ok || revoker == nil {
+		result.Status = RevocationStatusUnsupported
+		result.Reason = revocationUnsupported[token.Provider]
+		if result.Reason == "" {
+			result.Reason = "no token revoker configured for provider " + token.Provider
+		}
+		activity.GetLogger(ctx).Warn("Token not revoked upstream",
+			"profileId", token.ProfileID,
+			"provider", token.Provider,
+			"reason", result.Reason)
+		return result
+	}
+
+	if err := revoker.RevokeToken(ctx, token); err != nil {
+		result.Status = RevocationStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = RevocationStatusRevoked
+
+	if err := a.store.Tokens().RecordTokenEvent(ctx, &store.TokenEvent{
+		ProfileID:    token.ProfileID,
+		Provider:     token.Provider,
+		Type:         store.TokenEventRevoked,
+		Actor:        store.TokenActorWorker,
+		OldExpiresAt: token.ExpiresAt,
+	}); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to record token event", "error", err)
+	}
+
+	return result
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"go.temporal.io/sdk/activity"
+
 	"hourly/workers/reporter/internal/store"
 )
 
@@ -12,10 +14,33 @@ type UpdateReportedAccountsInput struct {
 	AccountIDs []string `json:"accountIds"`
 }
 
+// UpdateReportedAccountsOutput lists which accounts were marked as reported.
+type UpdateReportedAccountsOutput struct {
+	Updated []string `json:"updated"`
+	// Missed are reported accounts the registry no longer holds, for example
+	// because they were erased while the report ran.
+	Missed []string `json:"missed"`
+}
+
 // UpdateReportedAccounts marks accounts as reported at the current time.
-func (a *Activities) UpdateReportedAccounts(ctx context.Context, input *UpdateReportedAccountsInput) error {
-	return a.store.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+func (a *Activities) UpdateReportedAccounts(ctx context.Context, input *UpdateReportedAccountsInput) (*UpdateReportedAccountsOutput, error) {
+	result, err := a.store.UserData().UpdateLastReported(ctx, &store.UpdateLastReportedInput{
+		Provider:   store.ProviderAtlassian,
 		AccountIDs: input.AccountIDs,
 		ReportedAt: time.Now().UTC(),
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Missed) > 0 {
+		activity.GetLogger(ctx).Warn("Reported accounts missing from registry",
+			"updated", len(result.Updated),
+			"missed", len(result.Missed))
+	}
+
+	return &UpdateReportedAccountsOutput{
+		Updated: result.Updated,
+		Missed:  result.Missed,
+	}, nil
 }
@@ -2,9 +2,10 @@ package activities
 
 import (
 	"context"
-	"time"
 
 	"go.temporal.io/sdk/client"
+
+	"hourly/workers/reporter/internal/store"
 )
 
 // UpdateScheduleInput contains the new interval for the schedule.
@@ -12,19 +13,28 @@ type UpdateScheduleInput struct {
 	IntervalDays int `json:"intervalDays"`
 }
 
-// UpdateSchedule updates the Temporal schedule interval for privacy compliance.
+// UpdateSchedule persists the cycle period requested by Atlassian, which the
+// account selection cutoff reads, and updates the Temporal schedule interval
+// for privacy compliance to match.
 func (a *Activities) UpdateSchedule(ctx context.Context, input *UpdateScheduleInput) error {
+	if _, err := a.store.Settings().SetCyclePeriod(ctx, &store.SetCyclePeriodInput{
+		Days: input.IntervalDays,
+	}); err != nil {
+		return err
+	}
+
 	if a.temporal == nil || a.scheduleID == "" {
 		return nil // No schedule to update
 	}
 
+	period := &store.CyclePeriod{Days: input.IntervalDays}
 	scheduleHandle := a.temporal.ScheduleClient().GetHandle(ctx, a.scheduleID)
 
 	return scheduleHandle.Update(ctx, client.ScheduleUpdateOptions{
 		DoUpdate: func(in client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
 			schedule := in.Description.Schedule
 			schedule.Spec.Intervals = []client.ScheduleIntervalSpec{{
-				Every: time.Duration(input.IntervalDays) * 24 * time.Hour,
+				Every: period.Duration(),
 			}}
 			return &client.ScheduleUpdate{Schedule: &schedule}, nil
 		},
@@ -2,6 +2,12 @@ package activities
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/log"
 
 	"hourly/workers/reporter/internal/store"
 )
@@ -13,13 +19,46 @@ type DeleteUserDataInput struct {
 
 // DeleteUserDataOutput contains deletion results.
 type DeleteUserDataOutput struct {
-	DeletedAt    string `json:"deletedAt"`
-	ItemsDeleted int    `json:"itemsDeleted"`
+	DeletedAt    string            `json:"deletedAt,omitempty"`
+	ItemsDeleted int               `json:"itemsDeleted"`
+	Items        store.ErasedItems `json:"items"`
+	Revocations  []TokenRevocation `json:"revocations,omitempty"`
+	// Deferred is true when a legal hold kept the account's data. The
+	// erasure stays pending and runs once the hold is released or expires.
+	Deferred bool   `json:"deferred,omitempty"`
+	HoldID   string `json:"holdId,omitempty"`
 }
 
 // DeleteUserData removes all personal data for an account.
+// The account's token and the tokens of profiles linked to it through a
+// shared session are revoked at their providers first; a failed revocation
+// is recorded in the output but never blocks the local erasure. Atlassian
+// grants cannot be revoked upstream, which the revocation records as
+// unsupported with its reason.
+// An account under legal hold is left untouched, tokens included, and the
+// erasure is recorded as deferred on the hold.
 func (a *Activities) DeleteUserData(ctx context.Context, input *DeleteUserDataInput) (*DeleteUserDataOutput, error) {
+	logger := activity.GetLogger(ctx)
+
+	hold, err := a.store.LegalHolds().DeferErasure(ctx, &store.LegalHoldKey{
+		AccountID: input.AccountID,
+		Provider:  store.ProviderAtlassian,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		logger.Info("Erasure deferred by legal hold",
+			"accountId", input.AccountID,
+			"holdId", hold.ID)
+		return &DeleteUserDataOutput{Deferred: true, HoldID: hold.ID}, nil
+	}
+
+	revocations := a.revokeLinkedTokens(ctx, input.AccountID, store.ProviderAtlassian)
+	logFailedRevocations(logger, input.AccountID, revocations)
+
 	result, err := a.store.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{
+		Provider:  store.ProviderAtlassian,
 		AccountID: input.AccountID,
 	})
 	if err != nil {
@@ -28,6 +67,10 @@ func (a *Activities) DeleteUserData(ctx context.Context, input *DeleteUserDataIn
 	return &DeleteUserDataOutput{
 		DeletedAt:    result.DeletedAt,
 		ItemsDeleted: result.ItemsDeleted,
+		Items:        result.Items,
+		Revocations:  revocations,
+		Deferred:     result.Deferred,
+		HoldID:       result.HoldID,
 	}, nil
 }
 
@@ -40,11 +83,25 @@ type RefreshUserDataInput struct {
 type RefreshUserDataOutput struct {
 	RefreshedAt  string `json:"refreshedAt"`
 	ItemsUpdated int    `json:"itemsUpdated"`
+	// Tombstoned is true when the account was closed and erased earlier, so
+	// nothing was refreshed.
+	Tombstoned bool `json:"tombstoned,omitempty"`
 }
 
 // RefreshUserData re-fetches and updates user data for an account.
+// Closed accounts are never refreshed, so their data cannot flow back in.
 func (a *Activities) RefreshUserData(ctx context.Context, input *RefreshUserDataInput) (*RefreshUserDataOutput, error) {
+	tombstoned, err := a.filterTombstoned(ctx, []string{input.AccountID})
+	if err != nil {
+		return nil, err
+	}
+	if len(tombstoned) > 0 {
+		activity.GetLogger(ctx).Warn("Skipping refresh of tombstoned account", "accountId", input.AccountID)
+		return &RefreshUserDataOutput{Tombstoned: true}, nil
+	}
+
 	result, err := a.store.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{
+		Provider:  store.ProviderAtlassian,
 		AccountID: input.AccountID,
 	})
 	if err != nil {
@@ -55,3 +112,233 @@ func (a *Activities) RefreshUserData(ctx context.Context, input *RefreshUserData
 		ItemsUpdated: result.ItemsUpdated,
 	}, nil
 }
+
+// DeleteUserDataBatchInput contains the account IDs to erase.
+type DeleteUserDataBatchInput struct {
+	AccountIDs []string `json:"accountIds"`
+}
+
+// DeleteUserDataResult is the erasure of one account of a batch.
+type DeleteUserDataResult struct {
+	AccountID string `json:"accountId"`
+	DeleteUserDataOutput
+	// Error is set when the account's erasure failed; the other accounts of
+	// the batch are unaffected.
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteUserDataBatchOutput contains one result per distinct account, in
+// input order.
+type DeleteUserDataBatchOutput struct {
+	Results []DeleteUserDataResult `json:"results"`
+}
+
+// DeleteUserDataBatch erases accounts as DeleteUserData does, with a single
+// store transaction for the batch. If the transaction fails, each account is
+// erased on its own so that one bad account only fails itself. The activity
+// fails, and is retried, only when every account failed.
+func (a *Activities) DeleteUserDataBatch(ctx context.Context, input *DeleteUserDataBatchInput) (*DeleteUserDataBatchOutput, error) {
+	logger := activity.GetLogger(ctx)
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+	results := make([]DeleteUserDataResult, len(accountIDs))
+
+	var erase []int
+	for i, id := range accountIDs {
+		results[i].AccountID = id
+
+		hold, err := a.store.LegalHolds().DeferErasure(ctx, &store.LegalHoldKey{
+			AccountID: id,
+			Provider:  store.ProviderAtlassian,
+		})
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if hold != nil {
+			logger.Info("Erasure deferred by legal hold",
+				"accountId", id,
+				"holdId", hold.ID)
+			results[i].Deferred = true
+			results[i].HoldID = hold.ID
+			continue
+		}
+
+		results[i].Revocations = a.revokeLinkedTokens(ctx, id, store.ProviderAtlassian)
+		logFailedRevocations(logger, id, results[i].Revocations)
+		erase = append(erase, i)
+	}
+
+	if len(erase) > 0 {
+		ids := make([]string, len(erase))
+		for j, i := range erase {
+			ids[j] = accountIDs[i]
+		}
+
+		batch, batchErr := a.store.UserData().DeleteUserDataBatch(ctx, &store.DeleteUserDataBatchInput{
+			Provider:   store.ProviderAtlassian,
+			AccountIDs: ids,
+		})
+		if batchErr != nil {
+			logger.Warn("Batch erasure failed, erasing accounts one by one",
+				"accounts", len(ids),
+				"error", batchErr)
+		}
+
+		for j, i := range erase {
+			var erased *store.DeleteUserDataOutput
+			if batchErr == nil {
+				erased = &batch.Results[j].DeleteUserDataOutput
+			} else {
+				var err error
+				erased, err = a.store.UserData().DeleteUserData(ctx, &store.DeleteUserDataInput{
+					Provider:  store.ProviderAtlassian,
+					AccountID: accountIDs[i],
+				})
+				if err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+			}
+			results[i].DeletedAt = erased.DeletedAt
+			results[i].ItemsDeleted = erased.ItemsDeleted
+			results[i].Items = erased.Items
+			results[i].Deferred = erased.Deferred
+			results[i].HoldID = erased.HoldID
+		}
+	}
+
+	if err := allFailed(len(results), func(i int) string { return results[i].Error }); err != nil {
+		return nil, err
+	}
+	return &DeleteUserDataBatchOutput{Results: results}, nil
+}
+
+// RefreshUserDataBatchInput contains the account IDs to refresh.
+type RefreshUserDataBatchInput struct {
+	AccountIDs []string `json:"accountIds"`
+}
+
+// RefreshUserDataResult is the refresh of one account of a batch.
+type RefreshUserDataResult struct {
+	AccountID string `json:"accountId"`
+	RefreshUserDataOutput
+	// Error is set when the account's refresh failed; the other accounts of
+	// the batch are unaffected.
+	Error string `json:"error,omitempty"`
+}
+
+// RefreshUserDataBatchOutput contains one result per distinct account, in
+// input order.
+type RefreshUserDataBatchOutput struct {
+	Results []RefreshUserDataResult `json:"results"`
+}
+
+// RefreshUserDataBatch refreshes accounts as RefreshUserData does, with a
+// single store call for the batch, falling back to one account at a time
+// like DeleteUserDataBatch.
+func (a *Activities) RefreshUserDataBatch(ctx context.Context, input *RefreshUserDataBatchInput) (*RefreshUserDataBatchOutput, error) {
+	logger := activity.GetLogger(ctx)
+
+	accountIDs := store.DistinctAccountIDs(input.AccountIDs)
+	results := make([]RefreshUserDataResult, len(accountIDs))
+
+	tombstoned, err := a.filterTombstoned(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var refresh []int
+	for i, id := range accountIDs {
+		results[i].AccountID = id
+		if slices.Contains(tombstoned, id) {
+			logger.Warn("Skipping refresh of tombstoned account", "accountId", id)
+			results[i].Tombstoned = true
+			continue
+		}
+		refresh = append(refresh, i)
+	}
+
+	if len(refresh) > 0 {
+		ids := make([]string, len(refresh))
+		for j, i := range refresh {
+			ids[j] = accountIDs[i]
+		}
+
+		batch, batchErr := a.store.UserData().RefreshUserDataBatch(ctx, &store.RefreshUserDataBatchInput{
+			Provider:   store.ProviderAtlassian,
+			AccountIDs: ids,
+		})
+		if batchErr != nil {
+			logger.Warn("Batch refresh failed, refreshing accounts one by one",
+				"accounts", len(ids),
+				"error", batchErr)
+		}
+
+		for j, i := range refresh {
+			var refreshed *store.RefreshUserDataOutput
+			if batchErr == nil {
+				refreshed = &batch.Results[j].RefreshUserDataOutput
+			} else {
+				var err error
+				refreshed, err = a.store.UserData().RefreshUserData(ctx, &store.RefreshUserDataInput{
+					Provider:  store.ProviderAtlassian,
+					AccountID: accountIDs[i],
+				})
+				if err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+			}
+			results[i].RefreshedAt = refreshed.RefreshedAt
+			results[i].ItemsUpdated = refreshed.ItemsUpdated
+		}
+	}
+
+	if err := allFailed(len(results), func(i int) string { return results[i].Error }); err != nil {
+		return nil, err
+	}
+	return &RefreshUserDataBatchOutput{Results: results}, nil
+}
+
+// allFailed returns an error when each of n results has an error, so that a
+// batch that achieved nothing is retried as a whole.
+func allFailed(n int, resultError func(i int) string) error {
+	if n == 0 {
+		return nil
+	}
+	for i := range n {
+		if resultError(i) == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("all %d accounts failed: %s", n, resultError(0))
+}
+
+// filterTombstoned returns the given Atlassian accounts that are tombstoned.
+// Without a tombstone key no account counts as tombstoned, so reporting and
+// refreshing keep working.
+func (a *Activities) filterTombstoned(ctx context.Context, accountIDs []string) ([]string, error) {
+	tombstoned, err := a.store.AccountTombstones().FilterTombstoned(ctx, &store.FilterTombstonedInput{
+		Provider:   store.ProviderAtlassian,
+		AccountIDs: accountIDs,
+	})
+	if errors.Is(err, store.ErrTombstonesDisabled) {
+		activity.GetLogger(ctx).Warn("Skipping tombstone check", "error", err)
+		return nil, nil
+	}
+	return tombstoned, err
+}
+
+// logFailedRevocations logs the revocations of an erasure that failed.
+func logFailedRevocations(logger log.Logger, accountID string, revocations []TokenRevocation) {
+	for _, revocation := range revocations {
+		if revocation.Status == RevocationStatusFailed {
+			logger.Warn("Token revocation failed, continuing with erasure",
+				"accountId", accountID,
+				"profileId", revocation.ProfileID,
+				"provider", revocation.Provider,
+				"error", revocation.Error)
+		}
+	}
+}
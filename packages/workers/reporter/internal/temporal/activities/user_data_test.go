@@ -0,0 +1,92 @@
+package activities_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/store/engine/memory"
+	"hourly/workers/reporter/internal/store/storetest"
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+func TestDeleteUserDataRevokesLinkedTokens(t *testing.T) {
+	ctx := context.Background()
+	st, err := memory.New(memory.Options{Policies: storetest.Policies()})
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := st.Open(ctx); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	now := time.Now()
+	st.PutProfile(memory.Profile{ID: "a-atl", Provider: store.ProviderAtlassian, UpdatedAt: now})
+	st.PutProfile(memory.Profile{ID: "a-gl", Provider: store.ProviderGitLab, UpdatedAt: now})
+	for _, token := range []store.Token{
+		{ProfileID: "a-atl", Provider: store.ProviderAtlassian, AccessToken: "atl"},
+		{ProfileID: "a-gl", Provider: store.ProviderGitLab, AccessToken: "gl"},
+	} {
+		if err := st.PutToken(token); err != nil {
+			t.Fatalf("put token: %v", err)
+		}
+	}
+	if err := st.PutSession(memory.Session{
+		ID: "s1",
+		Profiles: []memory.ProfileKey{
+			{ID: "a-atl", Provider: store.ProviderAtlassian},
+			{ID: "a-gl", Provider: store.ProviderGitLab},
+		},
+		UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("put session: %v", err)
+	}
+
+	var revoked []string
+	a := activities.New(&activities.CreateActivitiesOptions{
+		Store: st,
+		Revokers: map[string]activities.TokenRevoker{
+			store.ProviderGitLab: activities.TokenRevokerFunc(func(ctx context.Context, token *store.Token) error {
+				revoked = append(revoked, token.AccessToken)
+				return nil
+			}),
+		},
+	})
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.DeleteUserData)
+
+	value, err := env.ExecuteActivity(a.DeleteUserData, &activities.DeleteUserDataInput{AccountID: "a-atl"})
+	if err != nil {
+		t.Fatalf("delete user data: %v", err)
+	}
+	var output activities.DeleteUserDataOutput
+	if err := value.Get(&output); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+
+	if len(revoked) != 1 || revoked[0] != "gl" {
+		t.Fatalf("revoked tokens = %v, want [gl]", revoked)
+	}
+
+	statuses := map[string]string{}
+	for _, r := range output.Revocations {
+		statuses[r.Provider+":"+r.ProfileID] = r.Status
+	}
+	want := map[string]string{
+		"atlassian:a-atl": activities.RevocationStatusUnsupported,
+		"gitlab:a-gl":     activities.RevocationStatusRevoked,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("revocations = %+v, want %v", output.Revocations, want)
+	}
+	for key, status := range want {
+		if statuses[key] != status {
+			t.Errorf("revocation %s = %q, want %q", key, statuses[key], status)
+		}
+	}
+}
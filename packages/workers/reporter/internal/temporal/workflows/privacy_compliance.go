@@ -1,7 +1,9 @@
 package workflows
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -9,6 +11,7 @@ import (
 
 	"hourly/workers/reporter/internal/atlassian"
 	"hourly/workers/reporter/internal/domain"
+	"hourly/workers/reporter/internal/store"
 	"hourly/workers/reporter/internal/temporal/activities"
 )
 
@@ -16,24 +19,38 @@ import (
 type PrivacyComplianceInput struct {
 	// BatchSize is the number of accounts to fetch per page (default: 1000).
 	BatchSize int `json:"batchSize,omitempty"`
-	// Concurrency is the max parallel account processing operations (default: 10).
+	// Concurrency is the max parallel account batches being processed (default: 10).
 	Concurrency int `json:"concurrency,omitempty"`
+	// ActionBatchSize is the number of accounts erased or refreshed per
+	// activity (default: 100).
+	ActionBatchSize int `json:"actionBatchSize,omitempty"`
+	// AccountIDs limits the run to these accounts, e.g. newly connected
+	// profiles. Accounts that are not due for reporting are skipped.
+	AccountIDs []string `json:"accountIds,omitempty"`
 }
 
+// reportPageByPageChangeID versions the switch from reporting the whole
+// registry at once, with one activity per erased or refreshed account, to
+// reporting, acting on and recording accounts page by page in batches.
+const reportPageByPageChangeID = "report-page-by-page"
+
 // PrivacyComplianceOutput contains workflow results.
 type PrivacyComplianceOutput struct {
 	TotalAccountsReported int `json:"totalAccountsReported"`
 	AccountsClosed        int `json:"accountsClosed"`
 	AccountsRefreshed     int `json:"accountsRefreshed"`
+	AccountsDeferred      int `json:"accountsDeferred"` // erasures deferred by a legal hold
+	AccountsMissed        int `json:"accountsMissed"`   // reported accounts the registry no longer holds, other than ones erased by this run
 	NewCyclePeriodDays    int `json:"newCyclePeriodDays,omitempty"`
 }
 
 // PrivacyCompliance is the main workflow for privacy compliance.
-// It runs on a Temporal schedule (default 7 days) and:
-// 1. Fetches all accounts to report (paginated)
-// 2. Reports accounts to Atlassian in batches of 90
-// 3. Processes accounts requiring action in parallel
-// 4. Updates schedule if Atlassian returns new cycle period
+// It runs on a Temporal schedule (default 7 days) and, for each page of
+// accounts to report:
+// 1. Reports the accounts to Atlassian in batches of 90
+// 2. Processes accounts requiring action in parallel
+// 3. Marks the accounts as reported
+// It then updates the schedule if Atlassian returned a new cycle period.
 func PrivacyCompliance(ctx workflow.Context, input PrivacyComplianceInput) (*PrivacyComplianceOutput, error) {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("PrivacyCompliance workflow started")
@@ -44,6 +61,9 @@ func PrivacyCompliance(ctx workflow.Context, input PrivacyComplianceInput) (*Pri
 	if input.Concurrency <= 0 {
 		input.Concurrency = 10
 	}
+	if input.ActionBatchSize <= 0 {
+		input.ActionBatchSize = 100
+	}
 
 	// Activity options with retry policy
 	activityOpts := workflow.ActivityOptions{
@@ -72,46 +92,95 @@ func PrivacyCompliance(ctx workflow.Context, input PrivacyComplianceInput) (*Pri
 		return nil, fmt.Errorf("access token unavailable: %w", err)
 	}
 
-	// Collect all accounts to report
-	var allAccounts []domain.Account
-	offset := 0
+	// Runs started before the change replay the command sequence they began with.
+	if workflow.GetVersion(ctx, reportPageByPageChangeID, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return privacyComplianceUnpaged(ctx, input, output)
+	}
+
+	// Accounts are reported, acted on and marked as reported page by page,
+	// so the workflow holds at most one page of the registry at a time.
+	fetched := 0
+	cursor := ""
 
 	for {
 		var getResult activities.GetAccountsToReportOutput
 		err := workflow.ExecuteActivity(ctx, "GetAccountsToReport", &activities.GetAccountsToReportInput{
-			Limit:  input.BatchSize,
-			Offset: offset,
+			Limit:        input.BatchSize,
+			Cursor:       cursor,
+			IncludeCount: cursor == "",
+			AccountIDs:   input.AccountIDs,
 		}).Get(ctx, &getResult)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get accounts: %w", err)
 		}
 
-		allAccounts = append(allAccounts, getResult.Accounts...)
-		logger.Info("Fetched accounts page", "count", len(getResult.Accounts), "total", len(allAccounts))
+		if cursor == "" {
+			logger.Info("Accounts to report", "total", getResult.TotalCount)
+		}
+
+		fetched += len(getResult.Accounts)
+		logger.Info("Fetched accounts page", "count", len(getResult.Accounts), "total", fetched)
+
+		if cyclePeriod := reportAccountsPage(ctx, logger, getResult.Accounts, input, output); cyclePeriod > 0 {
+			latestCyclePeriod = cyclePeriod
+		}
 
 		if !getResult.HasMore {
 			break
 		}
-		offset += input.BatchSize
+		cursor = getResult.NextCursor
 	}
 
-	if len(allAccounts) == 0 {
+	if fetched == 0 {
 		logger.Info("No accounts to report")
 		return output, nil
 	}
 
+	// Update schedule if cycle period changed
+	if latestCyclePeriod > 0 {
+		output.NewCyclePeriodDays = latestCyclePeriod
+		err := workflow.ExecuteActivity(ctx, "UpdateSchedule", &activities.UpdateScheduleInput{
+			IntervalDays: latestCyclePeriod,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to update schedule", "error", err)
+		}
+	}
+
+	logger.Info("PrivacyCompliance workflow completed",
+		"totalReported", output.TotalAccountsReported,
+		"closed", output.AccountsClosed,
+		"refreshed", output.AccountsRefreshed,
+		"deferred", output.AccountsDeferred,
+		"missed", output.AccountsMissed)
+
+	return output, nil
+}
+
+// reportAccountsPage reports a page of accounts to Atlassian in batches of
+// 90, erases or refreshes the accounts Atlassian asks for, and marks the
+// page's accounts as reported, adding the outcomes to output. It returns the
+// latest cycle period returned by Atlassian, or 0.
+func reportAccountsPage(
+	ctx workflow.Context,
+	logger interface{ Error(string, ...interface{}) },
+	accounts []domain.Account,
+	input PrivacyComplianceInput,
+	output *PrivacyComplianceOutput,
+) (cyclePeriodDays int) {
 	// Collect accounts requiring action
 	var accountsToClose []string
 	var accountsToRefresh []string
 	var reportedAccountIDs []string
+	receivedAt := map[string]time.Time{}
 
 	// Process accounts in batches of 90
-	for i := 0; i < len(allAccounts); i += atlassian.MaxAccountsPerBatch {
+	for i := 0; i < len(accounts); i += atlassian.MaxAccountsPerBatch {
 		end := i + atlassian.MaxAccountsPerBatch
-		if end > len(allAccounts) {
-			end = len(allAccounts)
+		if end > len(accounts) {
+			end = len(accounts)
 		}
-		batch := allAccounts[i:end]
+		batch := accounts[i:end]
 
 		var reportResult activities.ReportAccountsBatchOutput
 		err := workflow.ExecuteActivity(ctx, "ReportAccountsBatch", &activities.ReportAccountsBatchInput{
@@ -130,136 +199,313 @@ func PrivacyCompliance(ctx workflow.Context, input PrivacyComplianceInput) (*Pri
 
 		// Update cycle period if returned
 		if reportResult.CyclePeriodDays > 0 {
-			latestCyclePeriod = reportResult.CyclePeriodDays
+			cyclePeriodDays = reportResult.CyclePeriodDays
 		}
 
 		// Collect accounts requiring action
+		now := workflow.Now(ctx)
+		for _, id := range append(reportResult.AccountsToClose, reportResult.AccountsToRefresh...) {
+			receivedAt[id] = now
+		}
 		accountsToClose = append(accountsToClose, reportResult.AccountsToClose...)
 		accountsToRefresh = append(accountsToRefresh, reportResult.AccountsToRefresh...)
 	}
 
 	// Process accounts in parallel with concurrency limit
 	if len(accountsToClose) > 0 || len(accountsToRefresh) > 0 {
-		closedCount, refreshedCount := processAccountsParallel(
-			ctx, logger, accountsToClose, accountsToRefresh, input.Concurrency,
+		outcomes := processAccountsParallel(
+			ctx, logger, accountsToClose, accountsToRefresh, receivedAt, input.ActionBatchSize, input.Concurrency,
 		)
-		output.AccountsClosed = closedCount
-		output.AccountsRefreshed = refreshedCount
+		output.AccountsClosed += len(outcomes.erased)
+		output.AccountsRefreshed += outcomes.refreshed
+		output.AccountsDeferred += outcomes.deferred
+
+		// Erasure removed these accounts from the registry, so marking them
+		// would only count them as missed.
+		erased := make(map[string]bool, len(outcomes.erased))
+		for _, id := range outcomes.erased {
+			erased[id] = true
+		}
+		reportedAccountIDs = slices.DeleteFunc(reportedAccountIDs, func(id string) bool {
+			return erased[id]
+		})
 	}
 
 	// Update reported accounts in registry
 	if len(reportedAccountIDs) > 0 {
+		var updateResult activities.UpdateReportedAccountsOutput
 		err := workflow.ExecuteActivity(ctx, "UpdateReportedAccounts", &activities.UpdateReportedAccountsInput{
 			AccountIDs: reportedAccountIDs,
-		}).Get(ctx, nil)
+		}).Get(ctx, &updateResult)
 		if err != nil {
 			logger.Error("Failed to update reported accounts", "error", err)
 		}
+		output.AccountsMissed += len(updateResult.Missed)
 	}
 
-	// Update schedule if cycle period changed
-	if latestCyclePeriod > 0 {
-		output.NewCyclePeriodDays = latestCyclePeriod
-		err := workflow.ExecuteActivity(ctx, "UpdateSchedule", &activities.UpdateScheduleInput{
-			IntervalDays: latestCyclePeriod,
-		}).Get(ctx, nil)
-		if err != nil {
-			logger.Error("Failed to update schedule", "error", err)
-		}
-	}
-
-	logger.Info("PrivacyCompliance workflow completed",
-		"totalReported", output.TotalAccountsReported,
-		"closed", output.AccountsClosed,
-		"refreshed", output.AccountsRefreshed)
-
-	return output, nil
+	return cyclePeriodDays
 }
 
 // accountTask represents a task to process an account.
 type accountTask struct {
-	accountID string
-	isClose   bool // true = delete, false = refresh
+	accountID  string
+	isClose    bool // true = delete, false = refresh
+	receivedAt time.Time
 }
 
-// processAccountsParallel processes accounts with a concurrency limit using semaphore pattern.
+// accountOutcomes summarizes the tasks run by processAccountsParallel.
+type accountOutcomes struct {
+	erased    []string // accounts whose data was erased
+	refreshed int
+	deferred  int // erasures deferred by a legal hold
+}
+
+// processAccountsParallel erases and refreshes accounts in batches of batchSize,
+// with a concurrency limit on batches using semaphore pattern.
+// The outcome of every task is recorded in the privacy action ledger.
 func processAccountsParallel(
 	ctx workflow.Context,
 	logger interface{ Error(string, ...interface{}) },
 	toClose, toRefresh []string,
-	concurrency int,
-) (closedCount, refreshedCount int) {
+	receivedAt map[string]time.Time,
+	batchSize, concurrency int,
+) accountOutcomes {
+	var outcomes accountOutcomes
+
 	totalTasks := len(toClose) + len(toRefresh)
 	if totalTasks == 0 {
-		return 0, 0
+		return outcomes
+	}
+
+	// Build batches; each holds only erasures or only refreshes
+	var batches [][]accountTask
+	for chunk := range slices.Chunk(toClose, batchSize) {
+		batches = append(batches, newAccountTasks(chunk, true, receivedAt))
+	}
+	for chunk := range slices.Chunk(toRefresh, batchSize) {
+		batches = append(batches, newAccountTasks(chunk, false, receivedAt))
 	}
 
 	// Create semaphore and result channels
 	sem := workflow.NewBufferedChannel(ctx, concurrency)
-	resultCh := workflow.NewBufferedChannel(ctx, totalTasks)
+	resultCh := workflow.NewBufferedChannel(ctx, len(batches))
 
 	// Fill semaphore with tokens
 	for range concurrency {
 		sem.Send(ctx, struct{}{})
 	}
 
-	// Build task list
-	var tasks []accountTask
-	for _, id := range toClose {
-		tasks = append(tasks, accountTask{accountID: id, isClose: true})
-	}
-	for _, id := range toRefresh {
-		tasks = append(tasks, accountTask{accountID: id, isClose: false})
-	}
-
-	// Launch goroutines for each task
-	for _, task := range tasks {
-		task := task // capture for closure
+	// Launch goroutines for each batch
+	for _, batch := range batches {
+		batch := batch // capture for closure
 		workflow.Go(ctx, func(gCtx workflow.Context) {
 			// Acquire semaphore
 			var token struct{}
 			sem.Receive(gCtx, &token)
 			defer sem.Send(gCtx, token) // Release
 
-			var err error
-			if task.isClose {
-				err = workflow.ExecuteActivity(gCtx, "DeleteUserData", &activities.DeleteUserDataInput{
-					AccountID: task.accountID,
-				}).Get(gCtx, nil)
-			} else {
-				err = workflow.ExecuteActivity(gCtx, "RefreshUserData", &activities.RefreshUserDataInput{
-					AccountID: task.accountID,
-				}).Get(gCtx, nil)
-			}
-
-			// Send result: nil for success, error for failure
-			resultCh.Send(gCtx, accountTaskResult{task: task, err: err})
+			// Send the outcomes, including any failures
+			resultCh.Send(gCtx, runAccountBatch(gCtx, logger, batch))
 		})
 	}
 
 	// Wait for all results
-	for range totalTasks {
-		var result accountTaskResult
-		resultCh.Receive(ctx, &result)
-		if result.err != nil {
-			logger.Error("Account processing failed",
-				"accountId", result.task.accountID,
-				"isClose", result.task.isClose,
-				"error", result.err)
-		} else {
-			if result.task.isClose {
-				closedCount++
-			} else {
-				refreshedCount++
+	for range batches {
+		var results []accountTaskResult
+		resultCh.Receive(ctx, &results)
+		for _, result := range results {
+			switch {
+			case result.err != nil:
+				logger.Error("Account processing failed",
+					"accountId", result.task.accountID,
+					"isClose", result.task.isClose,
+					"error", result.err)
+			case result.deferred:
+				outcomes.deferred++
+			case result.task.isClose:
+				outcomes.erased = append(outcomes.erased, result.task.accountID)
+			default:
+				outcomes.refreshed++
 			}
 		}
 	}
 
-	return closedCount, refreshedCount
+	return outcomes
+}
+
+// newAccountTasks returns a task per account.
+func newAccountTasks(accountIDs []string, isClose bool, receivedAt map[string]time.Time) []accountTask {
+	tasks := make([]accountTask, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		tasks = append(tasks, accountTask{accountID: id, isClose: isClose, receivedAt: receivedAt[id]})
+	}
+	return tasks
 }
 
 // accountTaskResult holds the result of processing an account.
 type accountTaskResult struct {
-	task accountTask
-	err  error
+	task     accountTask
+	deferred bool // erasure deferred by a legal hold
+	err      error
+}
+
+// runAccountTask erases or refreshes an account and records the outcome in
+// the privacy action ledger. A failure to record is logged, not returned, so
+// that it never masks the outcome of the action itself.
+func runAccountTask(ctx workflow.Context, logger interface{ Error(string, ...interface{}) }, task accountTask) accountTaskResult {
+	var result accountTaskResult
+	var entry *activities.RecordPrivacyActionInput
+
+	if task.isClose {
+		var deleted activities.DeleteUserDataOutput
+		err := workflow.ExecuteActivity(ctx, "DeleteUserData", &activities.DeleteUserDataInput{
+			AccountID: task.accountID,
+		}).Get(ctx, &deleted)
+		result, entry = closeOutcome(ctx, task, deleted, err)
+	} else {
+		var refreshed activities.RefreshUserDataOutput
+		err := workflow.ExecuteActivity(ctx, "RefreshUserData", &activities.RefreshUserDataInput{
+			AccountID: task.accountID,
+		}).Get(ctx, &refreshed)
+		result, entry = refreshOutcome(ctx, task, refreshed, err)
+	}
+
+	if err := workflow.ExecuteActivity(ctx, "RecordPrivacyAction", entry).Get(ctx, nil); err != nil {
+		logger.Error("Failed to record privacy action",
+			"accountId", task.accountID,
+			"action", entry.Action,
+			"error", err)
+	}
+
+	return result
+}
+
+// runAccountBatch erases or refreshes a batch of accounts and records their
+// outcomes in the privacy action ledger, as runAccountTask does for one.
+// Each account succeeds or fails on its own; a failed batch activity fails
+// every account of the batch.
+func runAccountBatch(ctx workflow.Context, logger interface{ Error(string, ...interface{}) }, batch []accountTask) []accountTaskResult {
+	accountIDs := make([]string, len(batch))
+	for i, task := range batch {
+		accountIDs[i] = task.accountID
+	}
+
+	results := make([]accountTaskResult, len(batch))
+	entries := make([]activities.RecordPrivacyActionInput, len(batch))
+
+	if batch[0].isClose {
+		var deleted activities.DeleteUserDataBatchOutput
+		err := workflow.ExecuteActivity(ctx, "DeleteUserDataBatch", &activities.DeleteUserDataBatchInput{
+			AccountIDs: accountIDs,
+		}).Get(ctx, &deleted)
+
+		byAccount := make(map[string]activities.DeleteUserDataResult, len(deleted.Results))
+		for _, r := range deleted.Results {
+			byAccount[r.AccountID] = r
+		}
+		for i, task := range batch {
+			r := byAccount[task.accountID]
+			result, entry := closeOutcome(ctx, task, r.DeleteUserDataOutput, accountError(err, r.Error))
+			results[i], entries[i] = result, *entry
+		}
+	} else {
+		var refreshed activities.RefreshUserDataBatchOutput
+		err := workflow.ExecuteActivity(ctx, "RefreshUserDataBatch", &activities.RefreshUserDataBatchInput{
+			AccountIDs: accountIDs,
+		}).Get(ctx, &refreshed)
+
+		byAccount := make(map[string]activities.RefreshUserDataResult, len(refreshed.Results))
+		for _, r := range refreshed.Results {
+			byAccount[r.AccountID] = r
+		}
+		for i, task := range batch {
+			r := byAccount[task.accountID]
+			result, entry := refreshOutcome(ctx, task, r.RefreshUserDataOutput, accountError(err, r.Error))
+			results[i], entries[i] = result, *entry
+		}
+	}
+
+	if err := workflow.ExecuteActivity(ctx, "RecordPrivacyActions", &activities.RecordPrivacyActionsInput{
+		Actions: entries,
+	}).Get(ctx, nil); err != nil {
+		logger.Error("Failed to record privacy actions",
+			"accounts", len(entries),
+			"error", err)
+	}
+
+	return results
+}
+
+// accountError returns the error of one account of a batch: the batch
+// activity's error, or else the account's own.
+func accountError(batchErr error, accountErr string) error {
+	if batchErr != nil {
+		return batchErr
+	}
+	if accountErr != "" {
+		return errors.New(accountErr)
+	}
+	return nil
+}
+
+// untombstonedErasure is recorded on erasures completed without a tombstone.
+const untombstonedErasure = "erased without a tombstone: no tombstone key configured"
+
+// closeOutcome returns the result and ledger entry of an erasure.
+func closeOutcome(ctx workflow.Context, task accountTask, deleted activities.DeleteUserDataOutput, err error) (accountTaskResult, *activities.RecordPrivacyActionInput) {
+	result := accountTaskResult{task: task, err: err}
+	entry := &activities.RecordPrivacyActionInput{
+		AccountID:  task.accountID,
+		Status:     domain.AccountStatusClosed,
+		ReceivedAt: task.receivedAt,
+		Action:     store.PrivacyActionErased,
+		Items:      deleted.Items.Counts(),
+	}
+
+	if err == nil && deleted.Deferred {
+		// The erasure stays pending on the hold and resumes once the
+		// hold is released or expires.
+		result.deferred = true
+		entry.Action = store.PrivacyActionDeferred
+		entry.Items = nil
+		entry.Error = "legal hold " + deleted.HoldID
+	}
+
+	if err == nil && deleted.Items.Untombstoned {
+		entry.Error = untombstonedErasure
+	}
+
+	completeEntry(ctx, entry, result)
+	return result, entry
+}
+
+// refreshOutcome returns the result and ledger entry of a refresh.
+func refreshOutcome(ctx workflow.Context, task accountTask, refreshed activities.RefreshUserDataOutput, err error) (accountTaskResult, *activities.RecordPrivacyActionInput) {
+	result := accountTaskResult{task: task, err: err}
+	entry := &activities.RecordPrivacyActionInput{
+		AccountID:  task.accountID,
+		Status:     domain.AccountStatusUpdated,
+		ReceivedAt: task.receivedAt,
+		Action:     store.PrivacyActionRefreshed,
+		Items:      map[string]int{"profiles": refreshed.ItemsUpdated},
+	}
+
+	completeEntry(ctx, entry, result)
+	return result, entry
+}
+
+// completeEntry records a failed action as deferred, and stamps the
+// completion time of one that neither failed nor was deferred by a hold.
+func completeEntry(ctx workflow.Context, entry *activities.RecordPrivacyActionInput, result accountTaskResult) {
+	switch {
+	case result.err != nil:
+		// The account is reported again next cycle, which receives the
+		// status again and retries the action.
+		entry.Action = store.PrivacyActionDeferred
+		entry.Items = nil
+		entry.Error = result.err.Error()
+	case !result.deferred:
+		completedAt := workflow.Now(ctx)
+		entry.CompletedAt = &completedAt
+	}
 }
@@ -0,0 +1,193 @@
+package workflows
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	"hourly/workers/reporter/internal/atlassian"
+	"hourly/workers/reporter/internal/domain"
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+// privacyComplianceUnpaged continues a PrivacyCompliance run started before
+// reportPageByPageChangeID. It keeps that version's command sequence: it
+// collects every account to report, reports them in batches of 90, erases or
+// refreshes accounts one activity at a time, marks all reported accounts,
+// then updates the schedule. New runs never take this path.
+func privacyComplianceUnpaged(ctx workflow.Context, input PrivacyComplianceInput, output *PrivacyComplianceOutput) (*PrivacyComplianceOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	var latestCyclePeriod int
+
+	// Collect all accounts to report
+	var allAccounts []domain.Account
+	cursor := ""
+
+	for {
+		var getResult activities.GetAccountsToReportOutput
+		err := workflow.ExecuteActivity(ctx, "GetAccountsToReport", &activities.GetAccountsToReportInput{
+			Limit:  input.BatchSize,
+			Cursor: cursor,
+		}).Get(ctx, &getResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accounts: %w", err)
+		}
+
+		allAccounts = append(allAccounts, getResult.Accounts...)
+		logger.Info("Fetched accounts page", "count", len(getResult.Accounts), "total", len(allAccounts))
+
+		if !getResult.HasMore || len(getResult.Accounts) == 0 {
+			break
+		}
+
+		// Pages recorded before the change carry no cursor; the next page
+		// starts after the last account of this one.
+		cursor = getResult.NextCursor
+		if cursor == "" {
+			last := getResult.Accounts[len(getResult.Accounts)-1]
+			cursor = store.AccountsCursor{UpdatedAt: last.UpdatedAt, AccountID: last.AccountID}.Encode()
+		}
+	}
+
+	if len(allAccounts) == 0 {
+		logger.Info("No accounts to report")
+		return output, nil
+	}
+
+	// Collect accounts requiring action
+	var accountsToClose []string
+	var accountsToRefresh []string
+	var reportedAccountIDs []string
+
+	// Process accounts in batches of 90
+	for i := 0; i < len(allAccounts); i += atlassian.MaxAccountsPerBatch {
+		end := min(i+atlassian.MaxAccountsPerBatch, len(allAccounts))
+		batch := allAccounts[i:end]
+
+		var reportResult activities.ReportAccountsBatchOutput
+		err := workflow.ExecuteActivity(ctx, "ReportAccountsBatch", &activities.ReportAccountsBatchInput{
+			Accounts: batch,
+		}).Get(ctx, &reportResult)
+		if err != nil {
+			logger.Error("Failed to report batch", "error", err, "batchStart", i)
+			continue // Continue with other batches
+		}
+
+		for _, acc := range batch {
+			reportedAccountIDs = append(reportedAccountIDs, acc.AccountID)
+		}
+		output.TotalAccountsReported += len(batch)
+
+		if reportResult.CyclePeriodDays > 0 {
+			latestCyclePeriod = reportResult.CyclePeriodDays
+		}
+
+		accountsToClose = append(accountsToClose, reportResult.AccountsToClose...)
+		accountsToRefresh = append(accountsToRefresh, reportResult.AccountsToRefresh...)
+	}
+
+	// Process accounts in parallel with concurrency limit
+	if len(accountsToClose) > 0 || len(accountsToRefresh) > 0 {
+		output.AccountsClosed, output.AccountsRefreshed = processAccountsUnpaged(
+			ctx, logger, accountsToClose, accountsToRefresh, input.Concurrency,
+		)
+	}
+
+	// Update reported accounts in registry
+	if len(reportedAccountIDs) > 0 {
+		err := workflow.ExecuteActivity(ctx, "UpdateReportedAccounts", &activities.UpdateReportedAccountsInput{
+			AccountIDs: reportedAccountIDs,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to update reported accounts", "error", err)
+		}
+	}
+
+	// Update schedule if cycle period changed
+	if latestCyclePeriod > 0 {
+		output.NewCyclePeriodDays = latestCyclePeriod
+		err := workflow.ExecuteActivity(ctx, "UpdateSchedule", &activities.UpdateScheduleInput{
+			IntervalDays: latestCyclePeriod,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to update schedule", "error", err)
+		}
+	}
+
+	logger.Info("PrivacyCompliance workflow completed",
+		"totalReported", output.TotalAccountsReported,
+		"closed", output.AccountsClosed,
+		"refreshed", output.AccountsRefreshed)
+
+	return output, nil
+}
+
+// processAccountsUnpaged erases and refreshes accounts one activity each, with
+// a concurrency limit using semaphore pattern. Outcomes are not recorded in
+// the privacy action ledger, which runs of this version predate.
+func processAccountsUnpaged(
+	ctx workflow.Context,
+	logger interface{ Error(string, ...interface{}) },
+	toClose, toRefresh []string,
+	concurrency int,
+) (closedCount, refreshedCount int) {
+	var tasks []accountTask
+	for _, id := range toClose {
+		tasks = append(tasks, accountTask{accountID: id, isClose: true})
+	}
+	for _, id := range toRefresh {
+		tasks = append(tasks, accountTask{accountID: id, isClose: false})
+	}
+
+	// Create semaphore and result channels
+	sem := workflow.NewBufferedChannel(ctx, concurrency)
+	resultCh := workflow.NewBufferedChannel(ctx, len(tasks))
+
+	// Fill semaphore with tokens
+	for range concurrency {
+		sem.Send(ctx, struct{}{})
+	}
+
+	// Launch goroutines for each task
+	for _, task := range tasks {
+		workflow.Go(ctx, func(gCtx workflow.Context) {
+			// Acquire semaphore
+			var token struct{}
+			sem.Receive(gCtx, &token)
+			defer sem.Send(gCtx, token) // Release
+
+			var err error
+			if task.isClose {
+				err = workflow.ExecuteActivity(gCtx, "DeleteUserData", &activities.DeleteUserDataInput{
+					AccountID: task.accountID,
+				}).Get(gCtx, nil)
+			} else {
+				err = workflow.ExecuteActivity(gCtx, "RefreshUserData", &activities.RefreshUserDataInput{
+					AccountID: task.accountID,
+				}).Get(gCtx, nil)
+			}
+
+			resultCh.Send(gCtx, accountTaskResult{task: task, err: err})
+		})
+	}
+
+	// Wait for all results
+	for range tasks {
+		var result accountTaskResult
+		resultCh.Receive(ctx, &result)
+		switch {
+		case result.err != nil:
+			logger.Error("Account processing failed",
+				"accountId", result.task.accountID,
+				"isClose", result.task.isClose,
+				"error", result.err)
+		case result.task.isClose:
+			closedCount++
+		default:
+			refreshedCount++
+		}
+	}
+
+	return closedCount, refreshedCount
+}
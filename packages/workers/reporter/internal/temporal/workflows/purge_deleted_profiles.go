@@ -0,0 +1,88 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+// PurgeDeletedProfilesInput contains workflow parameters.
+type PurgeDeletedProfilesInput struct {
+	// Retention is how long a soft-deleted profile is kept before it is purged (default: 30 days).
+	Retention time.Duration `json:"retention,omitempty"`
+	// BatchSize is the number of profiles purged per activity (default: 100).
+	BatchSize int `json:"batchSize,omitempty"`
+	// MaxBatches bounds a single run; the next scheduled run picks up the rest (default: 100).
+	MaxBatches int `json:"maxBatches,omitempty"`
+}
+
+// PurgeDeletedProfilesOutput contains workflow results.
+type PurgeDeletedProfilesOutput struct {
+	DeletedBefore time.Time `json:"deletedBefore"`
+	Purged        int       `json:"purged"`
+	Batches       int       `json:"batches"`
+	Complete      bool      `json:"complete"`
+}
+
+// PurgeDeletedProfiles hard-deletes profiles whose soft deletion is older than
+// the retention period, in bounded batches. Each purged profile leaves a hashed
+// tombstone so that re-creation can still be detected.
+func PurgeDeletedProfiles(ctx workflow.Context, input PurgeDeletedProfilesInput) (*PurgeDeletedProfilesOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("PurgeDeletedProfiles workflow started")
+
+	if input.Retention <= 0 {
+		input.Retention = 30 * 24 * time.Hour
+	}
+	if input.BatchSize <= 0 {
+		input.BatchSize = 100
+	}
+	if input.MaxBatches <= 0 {
+		input.MaxBatches = 100
+	}
+
+	activityOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOpts)
+
+	output := &PurgeDeletedProfilesOutput{
+		DeletedBefore: workflow.Now(ctx).UTC().Add(-input.Retention),
+	}
+
+	for output.Batches < input.MaxBatches {
+		var batch activities.PurgeDeletedProfilesBatchOutput
+		err := workflow.ExecuteActivity(ctx, "PurgeDeletedProfilesBatch", &activities.PurgeDeletedProfilesBatchInput{
+			DeletedBefore: output.DeletedBefore,
+			Limit:         input.BatchSize,
+		}).Get(ctx, &batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge deleted profiles: %w", err)
+		}
+
+		output.Batches++
+		output.Purged += batch.Purged
+
+		if !batch.HasMore {
+			output.Complete = true
+			break
+		}
+	}
+
+	logger.Info("PurgeDeletedProfiles workflow completed",
+		"purged", output.Purged,
+		"batches", output.Batches,
+		"complete", output.Complete)
+
+	return output, nil
+}
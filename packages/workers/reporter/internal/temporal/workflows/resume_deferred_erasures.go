@@ -0,0 +1,109 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+// ResumeDeferredErasuresInput contains workflow parameters.
+type ResumeDeferredErasuresInput struct {
+	// BatchSize is the number of pending erasures listed per page (default: 100).
+	BatchSize int `json:"batchSize,omitempty"`
+	// MaxBatches bounds a single run; the next scheduled run picks up the rest (default: 10).
+	MaxBatches int `json:"maxBatches,omitempty"`
+}
+
+// ResumeDeferredErasuresOutput contains workflow results.
+type ResumeDeferredErasuresOutput struct {
+	Erased   int  `json:"erased"`
+	Deferred int  `json:"deferred"`
+	Failed   int  `json:"failed"`
+	Batches  int  `json:"batches"`
+	Complete bool `json:"complete"`
+}
+
+// ResumeDeferredErasures runs the erasures that a legal hold deferred once the
+// hold is released or has expired. Each outcome is recorded in the privacy
+// action ledger. An account placed under a new hold is deferred again, and a
+// failed erasure stays pending for the next run, so no erasure is dropped.
+// Pages are read with a keyset cursor past the previous page, so a failed
+// erasure does not hold back the erasures deferred after it.
+func ResumeDeferredErasures(ctx workflow.Context, input ResumeDeferredErasuresInput) (*ResumeDeferredErasuresOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("ResumeDeferredErasures workflow started")
+
+	if input.BatchSize <= 0 {
+		input.BatchSize = 100
+	}
+	if input.MaxBatches <= 0 {
+		input.MaxBatches = 10
+	}
+
+	activityOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOpts)
+
+	output := &ResumeDeferredErasuresOutput{}
+	var after *store.PendingErasure
+
+	for output.Batches < input.MaxBatches {
+		var page activities.ListPendingErasuresOutput
+		err := workflow.ExecuteActivity(ctx, "ListPendingErasures", &activities.ListPendingErasuresInput{
+			After: after,
+			Limit: input.BatchSize,
+		}).Get(ctx, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pending erasures: %w", err)
+		}
+
+		output.Batches++
+
+		for _, pending := range page.Erasures {
+			result := runAccountTask(ctx, logger, accountTask{
+				accountID:  pending.AccountID,
+				isClose:    true,
+				receivedAt: pending.DeferredAt,
+			})
+
+			switch {
+			case result.err != nil:
+				logger.Error("Deferred erasure failed",
+					"accountId", pending.AccountID,
+					"error", result.err)
+				output.Failed++
+			case result.deferred:
+				output.Deferred++
+			default:
+				output.Erased++
+			}
+		}
+
+		if len(page.Erasures) < input.BatchSize {
+			// Failed erasures stay pending for the next run.
+			output.Complete = output.Failed == 0
+			break
+		}
+		after = &page.Erasures[len(page.Erasures)-1]
+	}
+
+	logger.Info("ResumeDeferredErasures workflow completed",
+		"erased", output.Erased,
+		"deferred", output.Deferred,
+		"failed", output.Failed,
+		"complete", output.Complete)
+
+	return output, nil
+}
@@ -0,0 +1,134 @@
+package workflows
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+// dormantCandidateSampleSize caps the candidates a dry run returns, so that
+// its result stays well within Temporal's payload limits.
+const dormantCandidateSampleSize = 100
+
+// SweepDormantTokensInput contains workflow parameters.
+type SweepDormantTokensInput struct {
+	// InactiveFor is how long a profile must have been without session activity (default: 90 days).
+	InactiveFor time.Duration `json:"inactiveFor,omitempty"`
+	// DryRun counts the candidates and returns a sample of them without
+	// revoking or deleting anything.
+	DryRun bool `json:"dryRun,omitempty"`
+	// BatchSize is the number of candidates to fetch per page (default: 100).
+	BatchSize int `json:"batchSize,omitempty"`
+}
+
+// SweepDormantTokensOutput contains workflow results.
+type SweepDormantTokensOutput struct {
+	DryRun bool `json:"dryRun"`
+	// CandidateCount is the number of candidates found by a dry run.
+	CandidateCount int `json:"candidateCount,omitempty"`
+	// Candidates holds the first candidates found by a dry run, at most
+	// dormantCandidateSampleSize of them.
+	Candidates []store.DormantToken `json:"candidates,omitempty"`
+	Swept      int                  `json:"swept"`
+	Revoked    int                  `json:"revoked"`
+	Failed     int                  `json:"failed"`
+}
+
+// SweepDormantTokens deletes tokens of profiles that have had no session activity
+// for a configurable period, revoking them upstream first.
+// In dry-run mode it counts the candidates and returns a sample instead.
+func SweepDormantTokens(ctx workflow.Context, input SweepDormantTokensInput) (*SweepDormantTokensOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("SweepDormantTokens workflow started", "dryRun", input.DryRun)
+
+	if input.InactiveFor <= 0 {
+		input.InactiveFor = 90 * 24 * time.Hour
+	}
+	if input.BatchSize <= 0 {
+		input.BatchSize = 100
+	}
+
+	activityOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOpts)
+
+	output := &SweepDormantTokensOutput{DryRun: input.DryRun}
+
+	// Pages are read with a keyset cursor past the previous page, so each
+	// candidate is visited once: a token that failed to sweep is not retried
+	// or counted again within this run.
+	var after *store.DormantToken
+	for {
+		var page activities.ListDormantTokensOutput
+		err := workflow.ExecuteActivity(ctx, "ListDormantTokens", &activities.ListDormantTokensInput{
+			InactiveFor: input.InactiveFor,
+			After:       after,
+			Limit:       input.BatchSize,
+		}).Get(ctx, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dormant tokens: %w", err)
+		}
+
+		if len(page.Tokens) == 0 {
+			break
+		}
+		after = &page.Tokens[len(page.Tokens)-1]
+
+		if input.DryRun {
+			output.CandidateCount += len(page.Tokens)
+			if room := dormantCandidateSampleSize - len(output.Candidates); room > 0 {
+				output.Candidates = append(output.Candidates, page.Tokens[:min(room, len(page.Tokens))]...)
+			}
+		} else {
+			for _, token := range page.Tokens {
+				var result activities.SweepDormantTokenOutput
+				err := workflow.ExecuteActivity(ctx, "SweepDormantToken", &activities.SweepDormantTokenInput{
+					ProfileID:    token.ProfileID,
+					Provider:     token.Provider,
+					LastActiveAt: token.LastActiveAt,
+				}).Get(ctx, &result)
+				if err != nil {
+					logger.Error("Failed to sweep dormant token",
+						"profileId", token.ProfileID,
+						"provider", token.Provider,
+						"error", err)
+					output.Failed++
+					continue
+				}
+
+				output.Swept++
+				if result.Revocation.Status == activities.RevocationStatusRevoked {
+					output.Revoked++
+				}
+			}
+		}
+
+		if len(page.Tokens) < input.BatchSize {
+			break
+		}
+	}
+
+	if input.DryRun {
+		logger.Info("Dormant token candidates", "count", output.CandidateCount)
+		return output, nil
+	}
+
+	logger.Info("SweepDormantTokens workflow completed",
+		"swept", output.Swept,
+		"revoked", output.Revoked,
+		"failed", output.Failed)
+
+	return output, nil
+}
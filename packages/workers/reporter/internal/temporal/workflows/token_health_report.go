@@ -0,0 +1,129 @@
+package workflows
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"hourly/workers/reporter/internal/store"
+	"hourly/workers/reporter/internal/temporal/activities"
+)
+
+// tokenHealthPagesPerRun bounds the pages scanned by one run, which then
+// continues as new so that its history stays small.
+const tokenHealthPagesPerRun = 100
+
+// TokenHealthReportInput contains workflow parameters.
+type TokenHealthReportInput struct {
+	// PageSize is the number of tokens to classify per activity (default: 500).
+	PageSize int `json:"pageSize,omitempty"`
+	// MaxOffenders caps how many unhealthy tokens are included in the notification (default: 25).
+	MaxOffenders int `json:"maxOffenders,omitempty"`
+
+	// After, Counts and Offenders carry a scan over to the run it continued
+	// as new; they are unset when the scan starts.
+	After     *store.TokenCursor               `json:"after,omitempty"`
+	Counts    activities.TokenHealthCounts     `json:"counts"`
+	Offenders []activities.TokenHealthOffender `json:"offenders,omitempty"`
+}
+
+// TokenHealthReportOutput contains workflow results.
+type TokenHealthReportOutput struct {
+	Counts    activities.TokenHealthCounts     `json:"counts"`
+	Offenders []activities.TokenHealthOffender `json:"offenders,omitempty"`
+}
+
+// TokenHealthReport scans every stored token and classifies its health.
+// It runs on a Temporal schedule and:
+// 1. Classifies all tokens page by page, continuing as new every
+// tokenHealthPagesPerRun pages
+// 2. Records the summary as a report row and as metrics
+// 3. Notifies operators about the worst offenders
+func TokenHealthReport(ctx workflow.Context, input TokenHealthReportInput) (*TokenHealthReportOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("TokenHealthReport workflow started")
+
+	if input.PageSize <= 0 {
+		input.PageSize = 500
+	}
+	if input.MaxOffenders <= 0 {
+		input.MaxOffenders = 25
+	}
+
+	activityOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 2 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOpts)
+
+	output := &TokenHealthReportOutput{Counts: input.Counts, Offenders: input.Offenders}
+	after := input.After
+
+	for pages := 0; ; pages++ {
+		if pages == tokenHealthPagesPerRun {
+			input.After, input.Counts, input.Offenders = after, output.Counts, output.Offenders
+			return nil, workflow.NewContinueAsNewError(ctx, TokenHealthReport, input)
+		}
+
+		var page activities.ScanTokenHealthPageOutput
+		err := workflow.ExecuteActivity(ctx, "ScanTokenHealthPage", &activities.ScanTokenHealthPageInput{
+			After: after,
+			Limit: input.PageSize,
+		}).Get(ctx, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tokens: %w", err)
+		}
+
+		output.Counts.Add(page.Counts)
+		output.Offenders = worstOffenders(append(output.Offenders, page.Offenders...), input.MaxOffenders)
+
+		if !page.HasMore || page.Next == nil {
+			break
+		}
+		after = page.Next
+	}
+
+	if err := workflow.ExecuteActivity(ctx, "RecordTokenHealthReport", &activities.RecordTokenHealthReportInput{
+		Counts: output.Counts,
+	}).Get(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to record token health report: %w", err)
+	}
+
+	if len(output.Offenders) > 0 {
+		err := workflow.ExecuteActivity(ctx, "NotifyTokenHealth", &activities.NotifyTokenHealthInput{
+			Counts:    output.Counts,
+			Offenders: output.Offenders,
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to notify about token health", "error", err)
+		}
+	}
+
+	logger.Info("TokenHealthReport workflow completed",
+		"total", output.Counts.Total,
+		"healthy", output.Counts.Healthy,
+		"unhealthy", output.Counts.Total-output.Counts.Healthy)
+
+	return output, nil
+}
+
+// worstOffenders orders offenders by severity and keeps at most limit of them.
+func worstOffenders(offenders []activities.TokenHealthOffender, limit int) []activities.TokenHealthOffender {
+	slices.SortStableFunc(offenders, func(a, b activities.TokenHealthOffender) int {
+		return slices.Index(activities.TokenHealthSeverity, a.Health) - slices.Index(activities.TokenHealthSeverity, b.Health)
+	})
+
+	if len(offenders) > limit {
+		offenders = offenders[:limit]
+	}
+
+	return offenders
+}